@@ -10,7 +10,6 @@ import (
 
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
-	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
 	"github.com/simone-viozzi/bosun/internal/ports"
 	"github.com/simone-viozzi/bosun/internal/testutil"
@@ -47,11 +46,8 @@ func Test_Integration_DockerLabels_VolumeAndNetworkDiscovery(t *testing.T) {
 		_ = cli.NetworkRemove(context.Background(), netResp.ID)
 	})
 
-	// Create a DockerLabelSource
-	source, err := dockerlabels.NewFromEnv()
-	if err != nil {
-		t.Fatalf("failed to create DockerLabelSource: %v", err)
-	}
+	// Create a DockerLabelSource, recording its fixture when BOSUN_RECORD_DIR is set
+	source := newDockerLabelSource(t, "discovery")
 
 	// Take a snapshot with bosun. prefix filter
 	sel := ports.Selector{
@@ -146,5 +142,20 @@ func Test_Integration_DockerLabels_VolumeAndNetworkDiscovery(t *testing.T) {
 		}
 	}
 
+	// Inspect details, recorded separately since they take extra API calls
+	full, err := newDockerLabelSource(t, "discovery-full").Snapshot(ctx, ports.Selector{
+		Prefixes:       []string{dlabels.DefaultLabelPrefix},
+		IncludeStopped: true,
+		Detail:         ports.DetailFull,
+	})
+	if err != nil {
+		t.Fatalf("Snapshot with full detail failed: %v", err)
+	}
+	for _, entity := range full.Entities {
+		if entity.Kind == dlabels.KindContainer && entity.Meta[dlabels.MetaRestarts] == "" {
+			t.Errorf("Container %s missing '%s' in Meta", entity.Name, dlabels.MetaRestarts)
+		}
+	}
+
 	t.Logf("Integration test completed successfully with project: %s", stack.Project)
 }
@@ -0,0 +1,55 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
+	"github.com/simone-viozzi/bosun/internal/testutil/dockerreplay"
+)
+
+// recordDirEnv names the directory fixtures are recorded into, usually
+// internal/adapters/dockerlabels/testdata/replay. Unset, nothing is recorded.
+const recordDirEnv = "BOSUN_RECORD_DIR"
+
+// newDockerLabelSource connects to the daemon from the environment. With
+// BOSUN_RECORD_DIR set, the Docker API exchanges of the source are saved to
+// $BOSUN_RECORD_DIR/<api version>/<name>.json when the test passes, for the
+// replay tests in internal/adapters/dockerlabels.
+func newDockerLabelSource(t *testing.T, name string) *dockerlabels.DockerLabelSource {
+	t.Helper()
+	dir := os.Getenv(recordDirEnv)
+	if dir == "" {
+		source, err := dockerlabels.NewFromEnv()
+		if err != nil {
+			t.Fatalf("failed to create DockerLabelSource: %v", err)
+		}
+		return source
+	}
+
+	cli, rec, err := dockerreplay.NewRecordingClient(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("failed to create recording Docker client: %v", err)
+	}
+	source, err := dockerlabels.NewFromClient(cli)
+	if err != nil {
+		t.Fatalf("failed to create DockerLabelSource: %v", err)
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			return
+		}
+		path := filepath.Join(dir, rec.APIVersion(), name+".json")
+		if err := rec.Fixture().Save(path); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+			return
+		}
+		t.Logf("recorded %s", path)
+	})
+	return source
+}
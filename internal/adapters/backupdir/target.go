@@ -0,0 +1,219 @@
+// Package backupdir implements ports.BackupTarget on a local directory,
+// storing each backup as a zstd-compressed tar file.
+package backupdir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	dbackup "github.com/simone-viozzi/bosun/internal/domain/backup"
+)
+
+const (
+	// timeLayout is the timestamp in backup file names, always UTC.
+	timeLayout = "20060102T150405.000Z"
+	// parseLayout also reads the whole-second names of older backups.
+	parseLayout = "20060102T150405Z"
+	extension   = ".tar.zst"
+)
+
+// Target stores backups as <Dir>/<volume>/<volume>-<timestamp>.tar.zst.
+type Target struct {
+	Dir string
+}
+
+// New creates a Target rooted at dir.
+func New(dir string) *Target {
+	return &Target{Dir: dir}
+}
+
+// Save implements the BackupTarget interface. The archive is written to a
+// temporary file and renamed into place, so partial backups are never listed.
+// It never replaces an existing backup taken at the same millisecond.
+func (t *Target) Save(ctx context.Context, volume string, at time.Time, r io.Reader) (dbackup.Backup, error) {
+	if volume == "" || strings.ContainsAny(volume, `/\`) || volume == "." || volume == ".." {
+		return dbackup.Backup{}, fmt.Errorf("invalid volume name %q", volume)
+	}
+	dir := filepath.Join(t.Dir, volume)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return dbackup.Backup{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	at = at.UTC().Truncate(time.Millisecond)
+	name := volume + "-" + at.Format(timeLayout) + extension
+	if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+		return dbackup.Backup{}, fmt.Errorf("backup %s/%s already exists", volume, name)
+	}
+	tmp, err := os.CreateTemp(dir, ".partial-*")
+	if err != nil {
+		return dbackup.Backup{}, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	size, err := compress(ctx, tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return dbackup.Backup{}, fmt.Errorf("failed to write backup of %s: %w", volume, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return dbackup.Backup{}, fmt.Errorf("failed to store backup of %s: %w", volume, err)
+	}
+
+	return dbackup.Backup{
+		ID:      volume + "/" + name,
+		Volume:  volume,
+		TakenAt: at,
+		Size:    size,
+	}, nil
+}
+
+// compress copies r into f through a zstd encoder and returns the compressed size.
+func compress(ctx context.Context, f *os.File, r io.Reader) (int64, error) {
+	enc, err := zstd.NewWriter(f)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(enc, readerWithContext(ctx, r)); err != nil {
+		enc.Close()
+		return 0, err
+	}
+	if err := enc.Close(); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
+
+// List implements the BackupTarget interface.
+func (t *Target) List(ctx context.Context, volume string) ([]dbackup.Backup, error) {
+	volumes := []string{volume}
+	if volume == "" {
+		entries, err := os.ReadDir(t.Dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list backups: %w", err)
+		}
+		volumes = volumes[:0]
+		for _, e := range entries {
+			if e.IsDir() {
+				volumes = append(volumes, e.Name())
+			}
+		}
+	}
+
+	var out []dbackup.Backup
+	for _, v := range volumes {
+		bs, err := t.listVolume(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bs...)
+	}
+	slices.SortStableFunc(out, func(a, b dbackup.Backup) int {
+		if c := a.TakenAt.Compare(b.TakenAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Volume, b.Volume)
+	})
+	return out, nil
+}
+
+// Open returns a reader of the uncompressed tar stream of the backup with the given ID.
+func (t *Target) Open(id string) (io.ReadCloser, error) {
+	path := filepath.Join(t.Dir, filepath.FromSlash(id))
+	if !strings.HasPrefix(path, filepath.Clean(t.Dir)+string(filepath.Separator)) {
+		return nil, fmt.Errorf("invalid backup id %q", id)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &decoder{Decoder: dec, f: f}, nil
+}
+
+// listVolume returns the backups in the directory of one volume.
+func (t *Target) listVolume(volume string) ([]dbackup.Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(t.Dir, volume))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups of %s: %w", volume, err)
+	}
+
+	var out []dbackup.Backup
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), volume+"-")
+		if !ok || e.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, extension)
+		if !ok {
+			continue
+		}
+		at, err := time.Parse(parseLayout, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dbackup.Backup{
+			ID:      volume + "/" + e.Name(),
+			Volume:  volume,
+			TakenAt: at,
+			Size:    info.Size(),
+		})
+	}
+	return out, nil
+}
+
+// decoder closes both the zstd decoder and the underlying file.
+type decoder struct {
+	*zstd.Decoder
+	f *os.File
+}
+
+func (d *decoder) Close() error {
+	d.Decoder.Close()
+	return d.f.Close()
+}
+
+// readerWithContext stops reading once ctx is done.
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return r.Read(p)
+	})
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,126 @@
+package backupdir
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTarget_SaveListOpen(t *testing.T) {
+	ctx := context.Background()
+	target := New(t.TempDir())
+	t1 := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+
+	b1, err := target.Save(ctx, "app_data", t1, strings.NewReader("first archive"))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if b1.ID != "app_data/app_data-20250310T120000.000Z.tar.zst" || b1.Size == 0 {
+		t.Errorf("unexpected backup: %+v", b1)
+	}
+	if _, err := target.Save(ctx, "app_data", t2, strings.NewReader("second archive")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := target.Save(ctx, "db", t1.Add(time.Hour), strings.NewReader("db archive")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Stray files are ignored
+	if err := os.WriteFile(filepath.Join(target.Dir, "app_data", "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := target.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var got []string
+	for _, b := range all {
+		got = append(got, b.Volume+"@"+b.TakenAt.Format(time.RFC3339))
+	}
+	want := []string{"app_data@2025-03-10T12:00:00Z", "db@2025-03-10T13:00:00Z", "app_data@2025-03-11T12:00:00Z"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+
+	one, err := target.List(ctx, "db")
+	if err != nil || len(one) != 1 {
+		t.Fatalf("List(db) = %v, %v", one, err)
+	}
+
+	rc, err := target.Open(b1.ID)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil || string(content) != "first archive" {
+		t.Errorf("Open() content = %q, %v", content, err)
+	}
+}
+
+func TestTarget_SameSecond(t *testing.T) {
+	ctx := context.Background()
+	target := New(t.TempDir())
+	at := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// Backups named before millisecond timestamps are still listed
+	if err := os.MkdirAll(filepath.Join(target.Dir, "data"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target.Dir, "data", "data-20250310T115959Z.tar.zst"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, offset := range []time.Duration{0, 250 * time.Millisecond} {
+		if _, err := target.Save(ctx, "data", at.Add(offset), strings.NewReader("archive")); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if _, err := target.Save(ctx, "data", at, strings.NewReader("again")); err == nil {
+		t.Error("expected error when a backup with the same timestamp exists")
+	}
+
+	bs, err := target.List(ctx, "data")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var got []string
+	for _, b := range bs {
+		got = append(got, b.TakenAt.Format(time.RFC3339Nano))
+	}
+	want := []string{"2025-03-10T11:59:59Z", "2025-03-10T12:00:00Z", "2025-03-10T12:00:00.25Z"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+}
+
+func TestTarget_Errors(t *testing.T) {
+	ctx := context.Background()
+	target := New(t.TempDir())
+
+	if _, err := target.Save(ctx, "../escape", time.Now(), strings.NewReader("")); err == nil {
+		t.Error("expected error for a volume name with a path separator")
+	}
+	if _, err := target.Open("../../etc/passwd"); err == nil {
+		t.Error("expected error for a backup id outside the directory")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := target.Save(cancelled, "data", time.Now(), strings.NewReader("x")); err == nil {
+		t.Error("expected error for a cancelled context")
+	}
+	if bs, _ := target.List(ctx, "data"); len(bs) != 0 {
+		t.Errorf("failed save left a backup behind: %v", bs)
+	}
+
+	missing := New(filepath.Join(t.TempDir(), "missing"))
+	if bs, err := missing.List(ctx, ""); err != nil || len(bs) != 0 {
+		t.Errorf("List on a missing directory = %v, %v", bs, err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package composelabels implements ports.LabelSource over docker-compose files on disk,
+// so labels can be inspected and validated without a Docker daemon.
+package composelabels
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/cli"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// ComposeLabelSource produces the snapshot DockerLabelSource would return after
+// `docker compose up` of the given files. Entities have no daemon IDs yet, so
+// the ID of every entity is the name Docker Compose would give it.
+type ComposeLabelSource struct {
+	Files       []string
+	ProjectName string   // optional override, like `docker compose -p`
+	Profiles    []string // optional profiles to enable, like `docker compose --profile`
+}
+
+// New creates a ComposeLabelSource for the given compose files, merged in order.
+func New(files ...string) *ComposeLabelSource {
+	return &ComposeLabelSource{Files: files}
+}
+
+// load parses and normalizes the compose files the way the compose CLI does,
+// including .env files and variable interpolation from the environment.
+func (s *ComposeLabelSource) load(ctx context.Context) (*types.Project, error) {
+	opts := []cli.ProjectOptionsFn{cli.WithOsEnv, cli.WithDotEnv}
+	if s.ProjectName != "" {
+		opts = append(opts, cli.WithName(s.ProjectName))
+	}
+	if len(s.Profiles) > 0 {
+		opts = append(opts, cli.WithProfiles(s.Profiles))
+	}
+	po, err := cli.NewProjectOptions(s.Files, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return po.LoadProject(ctx)
+}
+
+// Snapshot implements the LabelSource interface
+func (s *ComposeLabelSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	project, err := s.load(ctx)
+	if err != nil {
+		return dlabels.Snapshot{}, fmt.Errorf("failed to load compose files: %w", err)
+	}
+
+	snap := dlabels.Snapshot{TakenAt: time.Now()}
+	if len(sel.ProjectFilter) > 0 && !slices.Contains(sel.ProjectFilter, project.Name) {
+		return snap, nil
+	}
+
+	if sel.WantsKind(dlabels.KindContainer) {
+		snap.Entities = append(snap.Entities, containers(project, sel)...)
+	}
+	if sel.WantsKind(dlabels.KindVolume) {
+		snap.Entities = append(snap.Entities, volumes(project, sel)...)
+	}
+	if sel.WantsKind(dlabels.KindNetwork) {
+		snap.Entities = append(snap.Entities, networks(project, sel)...)
+	}
+	snap.Entities = slices.DeleteFunc(snap.Entities, func(e dlabels.LabeledEntity) bool {
+		return !sel.LabelSelector.Matches(e)
+	})
+	dlabels.SortEntities(snap.Entities)
+	return snap, nil
+}
+
+// containers returns one entity per replica of every enabled service.
+// Compose names replicas <project>-<service>-<n> unless container_name is set.
+func containers(project *types.Project, sel ports.Selector) []dlabels.LabeledEntity {
+	var out []dlabels.LabeledEntity
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		svc := project.Services[name]
+		fl := dockerlabels.FilterByPrefixes(svc.Labels, sel.Prefixes)
+		if len(fl) == 0 {
+			continue
+		}
+
+		image := svc.Image
+		if image == "" {
+			image = project.Name + "-" + svc.Name
+		}
+
+		for i := 1; i <= svc.GetScale(); i++ {
+			ctrName := fmt.Sprintf("%s-%s-%d", project.Name, svc.Name, i)
+			if svc.ContainerName != "" {
+				ctrName = svc.ContainerName
+			}
+			ent := dlabels.LabeledEntity{
+				Kind:   dlabels.KindContainer,
+				ID:     ctrName,
+				Name:   ctrName,
+				Labels: maps.Clone(fl),
+				Meta: map[string]string{
+					dlabels.MetaComposeProject: project.Name,
+					dlabels.MetaComposeService: svc.Name,
+					dlabels.MetaImage:          image,
+				},
+			}
+			if instance := svc.Labels[dlabels.LabelInstance]; instance != "" {
+				ent.Meta[dlabels.MetaInstance] = instance
+			}
+			out = append(out, ent)
+		}
+	}
+	return out
+}
+
+// volumes returns the volumes Compose would create. External volumes are not
+// created by Compose and cannot declare labels, so they are skipped.
+func volumes(project *types.Project, sel ports.Selector) []dlabels.LabeledEntity {
+	var out []dlabels.LabeledEntity
+	for _, key := range slices.Sorted(maps.Keys(project.Volumes)) {
+		v := project.Volumes[key]
+		if bool(v.External) {
+			continue
+		}
+		fl := dockerlabels.FilterByPrefixes(v.Labels, sel.Prefixes)
+		if len(fl) == 0 {
+			continue
+		}
+		driver := v.Driver
+		if driver == "" {
+			driver = "local"
+		}
+		ent := dlabels.LabeledEntity{
+			Kind:   dlabels.KindVolume,
+			ID:     v.Name,
+			Name:   v.Name,
+			Labels: fl,
+			Meta: map[string]string{
+				dlabels.MetaComposeProject: project.Name,
+				dlabels.MetaDriver:         driver,
+			},
+		}
+		if instance := v.Labels[dlabels.LabelInstance]; instance != "" {
+			ent.Meta[dlabels.MetaInstance] = instance
+		}
+		out = append(out, ent)
+	}
+	return out
+}
+
+// networks returns the networks Compose would create, including the implicit
+// default network. External networks are skipped like external volumes.
+func networks(project *types.Project, sel ports.Selector) []dlabels.LabeledEntity {
+	var out []dlabels.LabeledEntity
+	for _, key := range slices.Sorted(maps.Keys(project.Networks)) {
+		n := project.Networks[key]
+		if bool(n.External) {
+			continue
+		}
+		fl := dockerlabels.FilterByPrefixes(n.Labels, sel.Prefixes)
+		if len(fl) == 0 {
+			continue
+		}
+		driver := n.Driver
+		if driver == "" {
+			driver = "bridge"
+		}
+		ent := dlabels.LabeledEntity{
+			Kind:   dlabels.KindNetwork,
+			ID:     n.Name,
+			Name:   n.Name,
+			Labels: fl,
+			Meta: map[string]string{
+				dlabels.MetaComposeProject: project.Name,
+				dlabels.MetaDriver:         driver,
+				dlabels.MetaScope:          "local",
+			},
+		}
+		if instance := n.Labels[dlabels.LabelInstance]; instance != "" {
+			ent.Meta[dlabels.MetaInstance] = instance
+		}
+		out = append(out, ent)
+	}
+	return out
+}
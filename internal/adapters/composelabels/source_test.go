@@ -0,0 +1,135 @@
+package composelabels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+const testCompose = `
+name: shop
+services:
+  web:
+    image: nginx:${NGINX_TAG:-alpine}
+    labels:
+      bosun.role: "webserver"
+      bosun.instance: "prod-01"
+    deploy:
+      replicas: 2
+    volumes:
+      - data:/data
+  db:
+    build: ./db
+    container_name: shop-database
+    labels:
+      bosun.role: "database"
+    networks:
+      - back
+  cache:
+    image: redis
+    labels:
+      other.label: "x"
+
+volumes:
+  data:
+    labels:
+      bosun.backup: "daily"
+  legacy:
+    external: true
+
+networks:
+  default:
+    labels:
+      bosun.zone: "front"
+  back:
+    driver: overlay
+    labels:
+      bosun.zone: "back"
+`
+
+func writeCompose(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSnapshot(t *testing.T) {
+	source := New(writeCompose(t, testCompose))
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}}
+
+	snap, err := source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	type row struct {
+		kind dlabels.Kind
+		name string
+	}
+	want := []row{
+		{dlabels.KindContainer, "shop-database"},
+		{dlabels.KindContainer, "shop-web-1"},
+		{dlabels.KindContainer, "shop-web-2"},
+		{dlabels.KindVolume, "shop_data"},
+		{dlabels.KindNetwork, "shop_back"},
+		{dlabels.KindNetwork, "shop_default"},
+	}
+	if len(snap.Entities) != len(want) {
+		t.Fatalf("expected %d entities, got %d: %+v", len(want), len(snap.Entities), snap.Entities)
+	}
+	for i, w := range want {
+		e := snap.Entities[i]
+		if e.Kind != w.kind || e.Name != w.name || e.ID != w.name {
+			t.Errorf("entity[%d] = %s %s (%s), want %s %s", i, e.Kind, e.Name, e.ID, w.kind, w.name)
+		}
+	}
+
+	db := snap.Entities[0]
+	if db.Meta["compose.project"] != "shop" || db.Meta["compose.service"] != "db" || db.Meta["image"] != "shop-db" {
+		t.Errorf("unexpected db meta: %v", db.Meta)
+	}
+	web := snap.Entities[1]
+	if web.Meta["image"] != "nginx:alpine" || web.Meta["instance"] != "prod-01" {
+		t.Errorf("unexpected web meta: %v", web.Meta)
+	}
+	if snap.Entities[3].Meta["driver"] != "local" || snap.Entities[4].Meta["driver"] != "overlay" {
+		t.Errorf("unexpected drivers: %v, %v", snap.Entities[3].Meta, snap.Entities[4].Meta)
+	}
+}
+
+func TestSnapshot_ProjectNameAndFilter(t *testing.T) {
+	path := writeCompose(t, testCompose)
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}, ProjectFilter: []string{"other"}}
+
+	snap, err := New(path).Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(snap.Entities) != 0 {
+		t.Errorf("expected no entities for a non-matching project, got %d", len(snap.Entities))
+	}
+
+	source := &ComposeLabelSource{Files: []string{path}, ProjectName: "other"}
+	snap, err = source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(snap.Entities) == 0 || snap.Entities[0].Meta["compose.project"] != "other" {
+		t.Errorf("expected entities of project other, got %+v", snap.Entities)
+	}
+}
+
+func TestSnapshot_InvalidFile(t *testing.T) {
+	path := writeCompose(t, "services:\n  web:\n    image: [unterminated\n")
+	if _, err := New(path).Snapshot(context.Background(), ports.Selector{}); err == nil {
+		t.Error("expected an error for an invalid compose file")
+	}
+}
@@ -0,0 +1,78 @@
+// Package configfile loads the desired state of container groups from YAML or JSON files.
+package configfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/simone-viozzi/bosun/internal/domain/desired"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the config file read when none is given.
+const DefaultPath = "bosun.yaml"
+
+// file is the on-disk layout of a config. JSON documents are valid YAML, so
+// both formats decode through the same structs.
+//
+//	groups:
+//	  - name: workers
+//	    selector: bosun.role=worker
+//	    state: running
+//	    scale: 3
+//	    restartUnhealthy: true
+type file struct {
+	Groups []group `yaml:"groups"`
+}
+
+type group struct {
+	Name             string `yaml:"name"`
+	Selector         string `yaml:"selector"`
+	State            string `yaml:"state"`
+	Scale            int    `yaml:"scale"`
+	RestartUnhealthy bool   `yaml:"restartUnhealthy"`
+}
+
+// Load reads and validates the config at path.
+func Load(path string) (desired.Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return desired.Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	c, err := Parse(b)
+	if err != nil {
+		return desired.Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse decodes and validates a YAML or JSON config document.
+// Unknown fields are rejected so typos in the config itself are caught.
+func Parse(b []byte) (desired.Config, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return desired.Config{}, err
+	}
+
+	var c desired.Config
+	for _, g := range f.Groups {
+		sel, err := dselector.Parse(g.Selector)
+		if err != nil {
+			return desired.Config{}, fmt.Errorf("group %s: %w", g.Name, err)
+		}
+		c.Groups = append(c.Groups, desired.Group{
+			Name:             g.Name,
+			Selector:         sel,
+			State:            desired.State(g.State),
+			Scale:            g.Scale,
+			RestartUnhealthy: g.RestartUnhealthy,
+		})
+	}
+	return c.Normalize()
+}
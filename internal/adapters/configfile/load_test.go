@@ -0,0 +1,72 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simone-viozzi/bosun/internal/domain/desired"
+)
+
+func TestLoad_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bosun.yaml")
+	doc := `
+groups:
+  - name: workers
+    selector: bosun.role=worker
+    scale: 3
+    restartUnhealthy: true
+  - name: maintenance
+    selector: bosun.role in (maintenance, migrate)
+    state: stopped
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(c.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(c.Groups))
+	}
+	workers := c.Groups[0]
+	if workers.Name != "workers" || workers.State != desired.StateRunning || workers.Scale != 3 || !workers.RestartUnhealthy {
+		t.Errorf("unexpected workers group: %+v", workers)
+	}
+	if got := workers.Selector.String(); got != "bosun.role=worker" {
+		t.Errorf("workers selector = %q", got)
+	}
+	if maint := c.Groups[1]; maint.State != desired.StateStopped || len(maint.Selector) != 1 {
+		t.Errorf("unexpected maintenance group: %+v", maint)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	c, err := Parse([]byte(`{"groups": [{"name": "api", "selector": "bosun.role=api"}]}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(c.Groups) != 1 || c.Groups[0].Name != "api" {
+		t.Errorf("unexpected config: %+v", c)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":   "groups:\n  - name: a\n    selector: bosun.x\n    replicas: 2\n",
+		"bad selector":    "groups:\n  - name: a\n    selector: bosun.role like web\n",
+		"missing name":    "groups:\n  - selector: bosun.x\n",
+		"scale + stopped": "groups:\n  - name: a\n    selector: bosun.x\n    state: stopped\n    scale: 1\n",
+	}
+	for name, doc := range tests {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read config") {
+		t.Errorf("Load(missing) error = %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package dockerconn builds Docker API clients from explicit connection
+// settings, DOCKER_* environment variables or Docker CLI contexts.
+package dockerconn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/cli/cli/connhelper"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+// Config selects the daemon to connect to. The zero value behaves like the
+// docker CLI without flags.
+type Config struct {
+	// Host is an endpoint like DOCKER_HOST: unix:///path, tcp://addr:port or
+	// ssh://[user@]host. It takes precedence over Context.
+	Host string
+	// Context is the name of a Docker CLI context.
+	Context string
+	// TLSCertDir holds ca.pem, cert.pem and key.pem for a tcp Host, like
+	// DOCKER_CERT_PATH. Without it a tcp Host uses DOCKER_CERT_PATH and
+	// DOCKER_TLS_VERIFY.
+	TLSCertDir string
+	// APIVersion pins the API version, e.g. "1.44"; empty negotiates with the daemon.
+	APIVersion string
+	// Timeout bounds dialing the daemon and the TLS handshake. The API calls
+	// themselves are not bounded, as some legitimately take long, e.g. a stop
+	// waiting for the stop timeout of a container. Zero means no limit.
+	Timeout time.Duration
+	// ConfigDir is the Docker CLI configuration directory; empty means
+	// $DOCKER_CONFIG or ~/.docker.
+	ConfigDir string
+}
+
+// Endpoint is a resolved daemon address with its TLS settings.
+type Endpoint struct {
+	Host          string
+	Context       string // context the endpoint was read from, empty if none
+	CAFile        string
+	CertFile      string
+	KeyFile       string
+	SkipTLSVerify bool
+}
+
+// tls reports whether the endpoint needs a TLS configuration.
+func (e Endpoint) tls() bool {
+	return e.CAFile != "" || e.CertFile != "" || e.KeyFile != "" || e.SkipTLSVerify
+}
+
+// Resolve picks the endpoint with the precedence of the docker CLI: Host,
+// Context, DOCKER_HOST, DOCKER_CONTEXT, the current context of config.json,
+// and finally the default socket. The context named "default" stands for the
+// environment.
+func (c Config) Resolve() (Endpoint, error) {
+	if c.Host != "" {
+		ep := Endpoint{Host: c.Host}
+		switch {
+		case c.TLSCertDir != "":
+			if err := ep.certDir(c.TLSCertDir, true); err != nil {
+				return Endpoint{}, err
+			}
+		case strings.HasPrefix(c.Host, "tcp://"):
+			if err := ep.envTLS(); err != nil {
+				return Endpoint{}, err
+			}
+		}
+		return ep, nil
+	}
+
+	name := c.Context
+	if name == "" && os.Getenv(client.EnvOverrideHost) == "" {
+		name = os.Getenv("DOCKER_CONTEXT")
+		if name == "" {
+			var err error
+			if name, err = currentContext(c.configDir()); err != nil {
+				return Endpoint{}, err
+			}
+		}
+	}
+	if name != "" && name != DefaultContext {
+		return loadContext(c.configDir(), name)
+	}
+	return envEndpoint()
+}
+
+// envEndpoint reads DOCKER_HOST, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY.
+func envEndpoint() (Endpoint, error) {
+	ep := Endpoint{Host: os.Getenv(client.EnvOverrideHost)}
+	if ep.Host == "" {
+		ep.Host = client.DefaultDockerHost
+	}
+	if err := ep.envTLS(); err != nil {
+		return Endpoint{}, err
+	}
+	return ep, nil
+}
+
+// envTLS sets the TLS files found in DOCKER_CERT_PATH, if set. As with the
+// docker CLI, certificates are only verified with DOCKER_TLS_VERIFY.
+func (e *Endpoint) envTLS() error {
+	dir := os.Getenv(client.EnvOverrideCertPath)
+	if dir == "" {
+		return nil
+	}
+	if err := e.certDir(dir, false); err != nil {
+		return err
+	}
+	e.SkipTLSVerify = os.Getenv(client.EnvTLSVerify) == ""
+	return nil
+}
+
+// certDir sets the TLS files found in dir. With required, dir must exist.
+func (e *Endpoint) certDir(dir string, required bool) error {
+	if _, err := os.Stat(dir); err != nil {
+		if required || !os.IsNotExist(err) {
+			return fmt.Errorf("invalid TLS certificate directory: %w", err)
+		}
+		return nil
+	}
+	for name, dst := range map[string]*string{"ca.pem": &e.CAFile, "cert.pem": &e.CertFile, "key.pem": &e.KeyFile} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			*dst = path
+		}
+	}
+	return nil
+}
+
+// NewClient returns a Docker API client for the endpoint cfg resolves to.
+func NewClient(cfg Config) (*client.Client, error) {
+	ep, err := cfg.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	// Same idle connection limits as the client's default transport
+	transport := &http.Transport{
+		MaxIdleConns:        6,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: cfg.Timeout,
+	}
+	if ep.tls() {
+		transport.TLSClientConfig, err = tlsconfig.Client(tlsconfig.Options{
+			CAFile:             ep.CAFile,
+			CertFile:           ep.CertFile,
+			KeyFile:            ep.KeyFile,
+			InsecureSkipVerify: ep.SkipTLSVerify,
+			ExclusiveRootPools: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS configuration: %w", err)
+		}
+	}
+
+	opts := []client.Opt{client.WithHTTPClient(&http.Client{Transport: transport})}
+	helper, err := connhelper.GetConnectionHelper(ep.Host)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case helper != nil:
+		// ssh:// runs `docker system dial-stdio` on the remote host
+		opts = append(opts, client.WithHost(helper.Host), client.WithDialContext(helper.Dialer))
+	case cfg.Timeout > 0:
+		opts = append(opts, client.WithHost(ep.Host), withDialTimeout(ep.Host, cfg.Timeout))
+	default:
+		opts = append(opts, client.WithHost(ep.Host))
+	}
+
+	version := cfg.APIVersion
+	if version == "" {
+		version = os.Getenv(client.EnvOverrideAPIVersion)
+	}
+	if version != "" {
+		opts = append(opts, client.WithVersion(version))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cli, nil
+}
+
+// withDialTimeout bounds dialing host. The dialer ignores the address the HTTP
+// client asks for, like the one the client configures for its host.
+func withDialTimeout(host string, timeout time.Duration) client.Opt {
+	return func(c *client.Client) error {
+		u, err := client.ParseHostURL(host)
+		if err != nil {
+			return err
+		}
+		d := &net.Dialer{Timeout: timeout}
+		return client.WithDialContext(func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, u.Scheme, u.Host)
+		})(c)
+	}
+}
@@ -0,0 +1,257 @@
+package dockerconn
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// writeContext stores a context the way the docker CLI does
+func writeContext(t *testing.T, configDir, name, host string, tlsFiles ...string) {
+	t.Helper()
+	meta := filepath.Join(configDir, "contexts", "meta", contextID(name))
+	if err := os.MkdirAll(meta, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	if err := os.WriteFile(filepath.Join(meta, "meta.json"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tlsDir := filepath.Join(configDir, "contexts", "tls", contextID(name), "docker")
+	for _, f := range tlsFiles {
+		if err := os.MkdirAll(tlsDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tlsDir, f), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// clearEnv unsets the variables Resolve reads
+func clearEnv(t *testing.T) {
+	for _, k := range []string{client.EnvOverrideHost, client.EnvOverrideCertPath, client.EnvTLSVerify, client.EnvOverrideAPIVersion, "DOCKER_CONTEXT", "DOCKER_CONFIG"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestConfig_Resolve(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	writeContext(t, dir, "rootless", "unix:///run/user/1000/docker.sock")
+	writeContext(t, dir, "prod", "tcp://10.0.0.5:2376", "ca.pem", "cert.pem", "key.pem")
+	writeContext(t, dir, "current", "ssh://admin@web1")
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext":"current"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tlsPath := filepath.Join(dir, "contexts", "tls", contextID("prod"), "docker")
+
+	tests := []struct {
+		name string
+		cfg  Config
+		env  map[string]string
+		want Endpoint
+	}{
+		{
+			name: "explicit host wins",
+			cfg:  Config{Host: "tcp://1.2.3.4:2375", Context: "prod"},
+			want: Endpoint{Host: "tcp://1.2.3.4:2375"},
+		},
+		{
+			name: "named context",
+			cfg:  Config{Context: "rootless"},
+			want: Endpoint{Host: "unix:///run/user/1000/docker.sock", Context: "rootless"},
+		},
+		{
+			name: "context TLS files",
+			cfg:  Config{Context: "prod"},
+			want: Endpoint{
+				Host: "tcp://10.0.0.5:2376", Context: "prod",
+				CAFile: filepath.Join(tlsPath, "ca.pem"), CertFile: filepath.Join(tlsPath, "cert.pem"), KeyFile: filepath.Join(tlsPath, "key.pem"),
+			},
+		},
+		{
+			name: "DOCKER_HOST beats the current context",
+			env:  map[string]string{client.EnvOverrideHost: "tcp://5.6.7.8:2375"},
+			want: Endpoint{Host: "tcp://5.6.7.8:2375"},
+		},
+		{
+			name: "DOCKER_CONTEXT beats config.json",
+			env:  map[string]string{"DOCKER_CONTEXT": "rootless"},
+			want: Endpoint{Host: "unix:///run/user/1000/docker.sock", Context: "rootless"},
+		},
+		{
+			name: "current context from config.json",
+			want: Endpoint{Host: "ssh://admin@web1", Context: "current"},
+		},
+		{
+			name: "default context means the environment",
+			cfg:  Config{Context: DefaultContext},
+			want: Endpoint{Host: client.DefaultDockerHost},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			tt.cfg.ConfigDir = dir
+			got, err := tt.cfg.Resolve()
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveErrors(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+
+	if _, err := (Config{Context: "missing", ConfigDir: dir}).Resolve(); err == nil {
+		t.Error("expected error for an unknown context")
+	}
+	if _, err := (Config{Host: "tcp://1.2.3.4:2376", TLSCertDir: filepath.Join(dir, "nope")}).Resolve(); err == nil {
+		t.Error("expected error for a missing TLS directory")
+	}
+}
+
+func TestConfig_ResolveEnvTLS(t *testing.T) {
+	clearEnv(t)
+	certs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(certs, "ca.pem"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(client.EnvOverrideHost, "tcp://1.2.3.4:2376")
+	t.Setenv(client.EnvOverrideCertPath, certs)
+
+	ep, err := (Config{ConfigDir: t.TempDir()}).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if ep.CAFile != filepath.Join(certs, "ca.pem") || ep.CertFile != "" || !ep.SkipTLSVerify {
+		t.Errorf("Resolve() = %+v, expected the CA file without verification", ep)
+	}
+
+	t.Setenv(client.EnvTLSVerify, "1")
+	if ep, _ := (Config{ConfigDir: t.TempDir()}).Resolve(); ep.SkipTLSVerify {
+		t.Error("DOCKER_TLS_VERIFY should enable verification")
+	}
+
+	// An explicit tcp host without a certificate directory uses the environment too
+	ep, err = (Config{Host: "tcp://5.6.7.8:2376"}).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if want := (Endpoint{Host: "tcp://5.6.7.8:2376", CAFile: filepath.Join(certs, "ca.pem")}); !reflect.DeepEqual(ep, want) {
+		t.Errorf("Resolve() = %+v, want %+v", ep, want)
+	}
+	if ep, _ := (Config{Host: "unix:///run/docker.sock"}).Resolve(); ep.tls() {
+		t.Errorf("Resolve() = %+v, want no TLS for a unix socket", ep)
+	}
+	other := t.TempDir()
+	if ep, _ := (Config{Host: "tcp://5.6.7.8:2376", TLSCertDir: other}).Resolve(); ep.CAFile != "" {
+		t.Errorf("Resolve() = %+v, want --tls-cert-dir to replace DOCKER_CERT_PATH", ep)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	writeContext(t, dir, "rootless", "unix:///run/user/1000/docker.sock")
+
+	cli, err := NewClient(Config{Context: "rootless", ConfigDir: dir, APIVersion: "1.44", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if cli.DaemonHost() != "unix:///run/user/1000/docker.sock" {
+		t.Errorf("DaemonHost() = %s", cli.DaemonHost())
+	}
+	if cli.ClientVersion() != "1.44" {
+		t.Errorf("ClientVersion() = %s, want 1.44", cli.ClientVersion())
+	}
+
+	ssh, err := NewClient(Config{Host: "ssh://admin@web1", ConfigDir: dir})
+	if err != nil {
+		t.Fatalf("NewClient(ssh) failed: %v", err)
+	}
+	if ssh.DaemonHost() == "" {
+		t.Error("expected a daemon host for ssh")
+	}
+}
+
+func TestContexts(t *testing.T) {
+	dir := t.TempDir()
+	writeContext(t, dir, "prod", "tcp://10.0.0.5:2376")
+	writeContext(t, dir, "dev", "unix:///var/run/docker.sock")
+
+	names, err := Contexts(Config{ConfigDir: dir})
+	if err != nil {
+		t.Fatalf("Contexts failed: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"dev", "prod"}) {
+		t.Errorf("Contexts() = %v", names)
+	}
+}
+
+func TestNewClient_TimeoutDialsHost(t *testing.T) {
+	clearEnv(t)
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.44")
+		w.WriteHeader(http.StatusOK)
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	cli, err := NewClient(Config{Host: "unix://" + sock, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	ping, err := cli.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if ping.APIVersion != "1.44" {
+		t.Errorf("APIVersion = %s, want 1.44", ping.APIVersion)
+	}
+}
+
+func TestNewClient_TimeoutSparesSlowCalls(t *testing.T) {
+	clearEnv(t)
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like a stop waiting for the stop timeout of the container
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	cli, err := NewClient(Config{Host: "unix://" + sock, APIVersion: "1.44", Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := cli.ContainerStop(context.Background(), "web", container.StopOptions{}); err != nil {
+		t.Errorf("ContainerStop failed: %v", err)
+	}
+}
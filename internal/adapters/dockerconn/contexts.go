@@ -0,0 +1,116 @@
+package dockerconn
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultContext is the built-in context that stands for the environment.
+const DefaultContext = "default"
+
+// configDir returns the Docker CLI configuration directory.
+func (c Config) configDir() string {
+	if c.ConfigDir != "" {
+		return c.ConfigDir
+	}
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// currentContext returns the currentContext of config.json, or "" if unset.
+func currentContext(configDir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", filepath.Join(configDir, "config.json"), err)
+	}
+	return cfg.CurrentContext, nil
+}
+
+// contextMeta is the meta.json the docker CLI writes for each context.
+type contextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host          string `json:"Host"`
+		SkipTLSVerify bool   `json:"SkipTLSVerify"`
+	} `json:"Endpoints"`
+}
+
+// contextID is the directory name the docker CLI stores a context under.
+func contextID(name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+}
+
+// loadContext reads the docker endpoint of a context from
+// <configDir>/contexts/meta/<id>/meta.json and its TLS files from
+// <configDir>/contexts/tls/<id>/docker.
+func loadContext(configDir, name string) (Endpoint, error) {
+	id := contextID(name)
+	b, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Endpoint{}, fmt.Errorf("docker context %q not found", name)
+	}
+	if err != nil {
+		return Endpoint{}, err
+	}
+	var meta contextMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return Endpoint{}, fmt.Errorf("failed to parse docker context %q: %w", name, err)
+	}
+	docker := meta.Endpoints["docker"]
+	if docker.Host == "" {
+		return Endpoint{}, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+
+	ep := Endpoint{Host: docker.Host, Context: name, SkipTLSVerify: docker.SkipTLSVerify}
+	if err := ep.certDir(filepath.Join(configDir, "contexts", "tls", id, "docker"), false); err != nil {
+		return Endpoint{}, err
+	}
+	return ep, nil
+}
+
+// Contexts lists the names of the Docker CLI contexts in cfg's configuration
+// directory, sorted, without the built-in default context.
+func Contexts(cfg Config) ([]string, error) {
+	dir := filepath.Join(cfg.configDir(), "contexts", "meta")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name(), "meta.json"))
+		if err != nil {
+			continue
+		}
+		var meta contextMeta
+		if json.Unmarshal(b, &meta) == nil && meta.Name != "" {
+			names = append(names, meta.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,110 @@
+// Package dockercontainers starts, stops, inspects and recreates Docker containers.
+package dockercontainers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerconn"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+)
+
+// dockerClient defines the subset of Docker client methods we use
+type dockerClient interface {
+	ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerRemove(ctx context.Context, containerID string, opts container.RemoveOptions) error
+	ContainerStart(ctx context.Context, containerID string, opts container.StartOptions) error
+	ContainerStop(ctx context.Context, containerID string, opts container.StopOptions) error
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerRename(ctx context.Context, containerID, newName string) error
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error
+}
+
+// DockerContainers implements ports.ContainerController,
+// ports.ContainerInspector and ports.ContainerRecreator.
+type DockerContainers struct {
+	CLI dockerClient
+	// ReadyTimeout bounds how long Recreate waits for a started replacement
+	// to become ready; defaults to DefaultReadyTimeout
+	ReadyTimeout time.Duration
+	// MinUptime is how long a started replacement must stay ready before the
+	// original is removed; defaults to DefaultMinUptime
+	MinUptime time.Duration
+	// PollInterval is how often a started replacement is checked; defaults
+	// to DefaultPollInterval
+	PollInterval time.Duration
+}
+
+func NewFromEnv() (*DockerContainers, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	return &DockerContainers{CLI: cli}, nil
+}
+
+// New connects to the Docker daemon selected by cfg.
+func New(cfg dockerconn.Config) (*DockerContainers, error) {
+	cli, err := dockerconn.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerContainers{CLI: cli}, nil
+}
+
+// ContainersUsingVolume implements the ContainerController interface.
+func (d *DockerContainers) ContainersUsingVolume(ctx context.Context, volume string) ([]string, error) {
+	ctrs, err := d.CLI.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("volume", volume)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(ctrs))
+	for _, c := range ctrs {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// Stop implements the ContainerController interface.
+func (d *DockerContainers) Stop(ctx context.Context, id string) error {
+	return d.CLI.ContainerStop(ctx, id, container.StopOptions{})
+}
+
+// StopTimeout implements the ContainerController interface. Docker takes the
+// timeout in whole seconds, so it is rounded up.
+func (d *DockerContainers) StopTimeout(ctx context.Context, id string, timeout time.Duration) error {
+	secs := int((timeout + time.Second - 1) / time.Second)
+	return d.CLI.ContainerStop(ctx, id, container.StopOptions{Timeout: &secs})
+}
+
+// Status implements the ContainerInspector interface.
+func (d *DockerContainers) Status(ctx context.Context, id string) (drollout.Status, error) {
+	info, err := d.CLI.ContainerInspect(ctx, id)
+	if err != nil {
+		return drollout.Status{}, err
+	}
+	if info.ContainerJSONBase == nil || info.State == nil {
+		return drollout.Status{}, fmt.Errorf("container %s has no state", id)
+	}
+	s := drollout.Status{State: string(info.State.Status), ExitCode: info.State.ExitCode}
+	if h := info.State.Health; h != nil && h.Status != container.NoHealthcheck {
+		s.Health = string(h.Status)
+	}
+	return s, nil
+}
+
+// Start implements the ContainerController interface.
+func (d *DockerContainers) Start(ctx context.Context, id string) error {
+	return d.CLI.ContainerStart(ctx, id, container.StartOptions{})
+}
@@ -0,0 +1,109 @@
+package dockercontainers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+)
+
+// mockDockerClient records the calls made by DockerContainers; the methods
+// it does not override panic through the nil embedded client
+type mockDockerClient struct {
+	dockerClient
+	listOpts container.ListOptions
+	stopOpts container.StopOptions
+	state    *container.State
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+func (notFoundError) NotFound()     {}
+
+func (m *mockDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	m.listOpts = opts
+	return []container.Summary{{ID: "c1"}, {ID: "c2"}}, nil
+}
+
+func (m *mockDockerClient) ContainerStop(ctx context.Context, containerID string, opts container.StopOptions) error {
+	m.stopOpts = opts
+	return nil
+}
+
+func (m *mockDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	if m.state == nil {
+		return container.InspectResponse{}, notFoundError{}
+	}
+	return container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{ID: containerID, State: m.state}}, nil
+}
+
+func (m *mockDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	return image.InspectResponse{}, notFoundError{}
+}
+
+func TestContainersUsingVolume(t *testing.T) {
+	cli := &mockDockerClient{}
+	d := &DockerContainers{CLI: cli}
+
+	ids, err := d.ContainersUsingVolume(context.Background(), "app_data")
+	if err != nil {
+		t.Fatalf("ContainersUsingVolume failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "c1,c2" {
+		t.Errorf("ids = %v, want c1,c2", ids)
+	}
+	if got := cli.listOpts.Filters.Get("volume"); len(got) != 1 || got[0] != "app_data" {
+		t.Errorf("volume filter = %v, want app_data", got)
+	}
+	if cli.listOpts.All {
+		t.Error("expected only running containers to be listed")
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	cli := &mockDockerClient{}
+	d := &DockerContainers{CLI: cli}
+
+	if err := d.StopTimeout(context.Background(), "c1", 1500*time.Millisecond); err != nil {
+		t.Fatalf("StopTimeout failed: %v", err)
+	}
+	if cli.stopOpts.Timeout == nil || *cli.stopOpts.Timeout != 2 {
+		t.Errorf("timeout = %v, want 2 seconds", cli.stopOpts.Timeout)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *container.State
+		want  drollout.Status
+	}{
+		{"no healthcheck", &container.State{Status: container.StateRunning}, drollout.Status{State: "running"}},
+		{"healthcheck disabled", &container.State{Status: container.StateRunning, Health: &container.Health{Status: container.NoHealthcheck}}, drollout.Status{State: "running"}},
+		{"starting", &container.State{Status: container.StateRunning, Health: &container.Health{Status: container.Starting}}, drollout.Status{State: "running", Health: "starting"}},
+		{"exited", &container.State{Status: container.StateExited, ExitCode: 1}, drollout.Status{State: "exited", ExitCode: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DockerContainers{CLI: &mockDockerClient{state: tt.state}}
+			got, err := d.Status(context.Background(), "c1")
+			if err != nil {
+				t.Fatalf("Status failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Status = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	d := &DockerContainers{CLI: &mockDockerClient{}}
+	if _, err := d.Status(context.Background(), "gone"); !client.IsErrNotFound(err) {
+		t.Errorf("Status of a missing container: got %v, want not found", err)
+	}
+}
@@ -0,0 +1,367 @@
+package dockercontainers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+)
+
+// Suffixes of the temporary names used while swapping a container with its replacement.
+const (
+	newSuffix = "-bosun-new"
+	oldSuffix = "-bosun-old"
+)
+
+// Defaults of the readiness settings of DockerContainers.
+const (
+	DefaultReadyTimeout = 2 * time.Minute
+	DefaultMinUptime    = 3 * time.Second
+	DefaultPollInterval = 500 * time.Millisecond
+)
+
+// createSpec is what a container is created from; Recreate diffs the spec of
+// a container against the spec of its replacement.
+type createSpec struct {
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	Networks   map[string]*network.EndpointSettings
+}
+
+// Recreate implements ports.ContainerRecreator. The replacement gets the
+// config, host config, mounts and network attachments of the container, with
+// the edited labels. The swap creates it under a temporary name, stops the
+// container and renames both; if any step fails, including the start of a
+// replacement for a running container or its readiness, the steps done so
+// far are undone. The replaced container is removed, its volumes are kept.
+//
+// Docker adds the labels of the image to a new container for every key it
+// does not set, so removing a label the image defines is refused.
+func (d *DockerContainers) Recreate(ctx context.Context, ref string, edit drecreate.Edit, dryRun bool) (drecreate.Result, error) {
+	old, err := d.CLI.ContainerInspect(ctx, ref)
+	if err != nil {
+		return drecreate.Result{}, fmt.Errorf("failed to inspect container %s: %w", ref, err)
+	}
+	if old.Config == nil || old.HostConfig == nil || old.State == nil {
+		return drecreate.Result{}, fmt.Errorf("container %s: incomplete inspect response", ref)
+	}
+	if old.HostConfig.AutoRemove {
+		return drecreate.Result{}, fmt.Errorf("container %s is removed when it stops (--rm), so it cannot be replaced safely", ref)
+	}
+	name := strings.TrimPrefix(old.Name, "/")
+	res := drecreate.Result{Container: name, OldID: old.ID}
+
+	labels := edit.Apply(old.Config.Labels)
+	if maps.Equal(labels, old.Config.Labels) {
+		return res, nil
+	}
+	if err := d.checkUnset(ctx, old, name, labels); err != nil {
+		return drecreate.Result{}, err
+	}
+	current := specOf(old)
+	spec, notes := d.replacementSpec(ctx, old, labels)
+	res.Notes = notes
+	if res.Changes, err = drecreate.Diff(current, spec); err != nil {
+		return drecreate.Result{}, err
+	}
+	if dryRun {
+		return res, nil
+	}
+
+	res.NewID, err = d.swap(ctx, old, name, spec)
+	if err != nil {
+		return drecreate.Result{}, err
+	}
+	if err := d.CLI.ContainerRemove(ctx, old.ID, container.RemoveOptions{}); err != nil {
+		res.Notes = append(res.Notes, fmt.Sprintf("failed to remove the replaced container %s%s: %v", name, oldSuffix, err))
+	}
+	return res, nil
+}
+
+// checkUnset returns an error if labels lacks a label of container old that
+// its image defines, as the replacement would get it back from the image.
+func (d *DockerContainers) checkUnset(ctx context.Context, old container.InspectResponse, name string, labels map[string]string) error {
+	var removed []string
+	for key := range old.Config.Labels {
+		if _, ok := labels[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	img, err := d.CLI.ImageInspect(ctx, old.Image)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the image of container %s: %w", name, err)
+	}
+	if img.Config == nil {
+		return nil
+	}
+	removed = slices.DeleteFunc(removed, func(key string) bool {
+		_, ok := img.Config.Labels[key]
+		return !ok
+	})
+	if len(removed) > 0 {
+		slices.Sort(removed)
+		return fmt.Errorf("cannot unset %s on container %s: the image defines it and Docker would add it back to the replacement; set another value instead",
+			strings.Join(removed, ", "), name)
+	}
+	return nil
+}
+
+// specOf returns the spec container old was created from, as far as inspect
+// tells: its network attachments without the addresses assigned at runtime.
+func specOf(old container.InspectResponse) createSpec {
+	spec := createSpec{Config: old.Config, HostConfig: old.HostConfig}
+	if old.NetworkSettings == nil || !attachable(old.HostConfig.NetworkMode) {
+		return spec
+	}
+	spec.Networks = make(map[string]*network.EndpointSettings, len(old.NetworkSettings.Networks))
+	for name, ep := range old.NetworkSettings.Networks {
+		if ep == nil {
+			ep = &network.EndpointSettings{}
+		}
+		spec.Networks[name] = &network.EndpointSettings{
+			IPAMConfig: ep.IPAMConfig,
+			Links:      ep.Links,
+			// Before API 1.45 the aliases include the short container ID,
+			// which the replacement gets on its own
+			Aliases:    slices.DeleteFunc(slices.Clone(ep.Aliases), func(a string) bool { return a == shortID(old.ID) }),
+			MacAddress: ep.MacAddress,
+			DriverOpts: ep.DriverOpts,
+			GwPriority: ep.GwPriority,
+		}
+	}
+	return spec
+}
+
+// replacementSpec returns the spec of the replacement of old with labels and
+// notes explaining the adjustments it needs beyond the labels.
+func (d *DockerContainers) replacementSpec(ctx context.Context, old container.InspectResponse, labels map[string]string) (createSpec, []string) {
+	spec := specOf(old)
+	cfg := *old.Config
+	cfg.Labels = labels
+	hc := *old.HostConfig
+	spec.Config, spec.HostConfig = &cfg, &hc
+	var notes []string
+
+	// Docker defaults the hostname to the short container ID
+	if cfg.Hostname == shortID(old.ID) {
+		cfg.Hostname = ""
+	}
+	// Keep the image the container runs, even if its tag moved since
+	if img, err := d.CLI.ImageInspect(ctx, cfg.Image); err != nil || img.ID != old.Image {
+		notes = append(notes, fmt.Sprintf("%s no longer refers to the image the container runs, using %s", cfg.Image, old.Image))
+		cfg.Image = old.Image
+	}
+	// Volumes mounted without a bind or mount spec, i.e. anonymous volumes and
+	// those declared by the image, would be created afresh
+	declared := make(map[string]bool)
+	for _, b := range hc.Binds {
+		if parts := strings.Split(b, ":"); len(parts) > 1 {
+			declared[parts[1]] = true
+		}
+	}
+	for _, m := range hc.Mounts {
+		declared[m.Target] = true
+	}
+	hc.Mounts = slices.Clone(hc.Mounts)
+	for _, m := range old.Mounts {
+		if m.Type != mount.TypeVolume || declared[m.Destination] {
+			continue
+		}
+		hc.Mounts = append(hc.Mounts, mount.Mount{Type: mount.TypeVolume, Source: m.Name, Target: m.Destination, ReadOnly: !m.RW})
+		notes = append(notes, fmt.Sprintf("volume %s stays mounted at %s", m.Name, m.Destination))
+	}
+	return spec, notes
+}
+
+// attachable reports whether containers in network mode join networks,
+// unlike host, none and container:ID.
+func attachable(mode container.NetworkMode) bool {
+	return !mode.IsHost() && !mode.IsNone() && !mode.IsContainer()
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// swap replaces container old named name by a container created from spec,
+// started if old was running, and returns its ID. On failure it undoes the
+// steps done so far, leaving old as it was.
+func (d *DockerContainers) swap(ctx context.Context, old container.InspectResponse, name string, spec createSpec) (_ string, err error) {
+	var undo []func(context.Context) error
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Roll back even when ctx was canceled
+		ctx := context.WithoutCancel(ctx)
+		var errs []error
+		for _, fn := range slices.Backward(undo) {
+			if uerr := fn(ctx); uerr != nil {
+				errs = append(errs, uerr)
+			}
+		}
+		if len(errs) > 0 {
+			err = fmt.Errorf("%w; rollback failed, check containers %s and %s%s: %w", err, name, name, oldSuffix, errors.Join(errs...))
+		}
+	}()
+
+	// Create the replacement attached to its primary network; older API
+	// versions take a single network on create, the others are connected
+	primary, rest := splitNetworks(spec.HostConfig.NetworkMode, spec.Networks)
+	var netCfg *network.NetworkingConfig
+	if primary != "" {
+		netCfg = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{primary: spec.Networks[primary]}}
+	}
+	created, err := d.CLI.ContainerCreate(ctx, spec.Config, spec.HostConfig, netCfg, nil, name+newSuffix)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the replacement of %s: %w", name, err)
+	}
+	id := created.ID
+	undo = append(undo, func(ctx context.Context) error {
+		return d.CLI.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+	})
+	if err := d.checkLabels(ctx, id, name, spec.Config.Labels); err != nil {
+		return "", err
+	}
+	for _, n := range rest {
+		if err := d.CLI.NetworkConnect(ctx, n, id, spec.Networks[n]); err != nil {
+			return "", fmt.Errorf("failed to connect the replacement of %s to network %s: %w", name, n, err)
+		}
+	}
+
+	running := old.State.Running
+	if running {
+		if err := d.CLI.ContainerStop(ctx, old.ID, container.StopOptions{}); err != nil {
+			return "", fmt.Errorf("failed to stop container %s: %w", name, err)
+		}
+		undo = append(undo, func(ctx context.Context) error {
+			return d.CLI.ContainerStart(ctx, old.ID, container.StartOptions{})
+		})
+	}
+	if err := d.CLI.ContainerRename(ctx, old.ID, name+oldSuffix); err != nil {
+		return "", fmt.Errorf("failed to rename container %s: %w", name, err)
+	}
+	undo = append(undo, func(ctx context.Context) error {
+		return d.CLI.ContainerRename(ctx, old.ID, name)
+	})
+	if err := d.CLI.ContainerRename(ctx, id, name); err != nil {
+		return "", fmt.Errorf("failed to rename the replacement of %s: %w", name, err)
+	}
+	undo = append(undo, func(ctx context.Context) error {
+		return d.CLI.ContainerRename(ctx, id, name+newSuffix)
+	})
+	if !running {
+		return id, nil
+	}
+
+	if err := d.CLI.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("failed to start the replacement of %s: %w", name, err)
+	}
+	if err := d.waitReady(ctx, id); err != nil {
+		return "", fmt.Errorf("the replacement of %s did not become ready: %w", name, err)
+	}
+	return id, nil
+}
+
+// checkLabels returns an error if the created container id does not have
+// exactly the labels want, e.g. because Docker added labels of its image.
+func (d *DockerContainers) checkLabels(ctx context.Context, id, name string, want map[string]string) error {
+	inspect, err := d.CLI.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect the replacement of %s: %w", name, err)
+	}
+	if inspect.Config == nil {
+		return fmt.Errorf("the replacement of %s: incomplete inspect response", name)
+	}
+	got := inspect.Config.Labels
+	var differ []string
+	for key, v := range got {
+		if w, ok := want[key]; !ok || w != v {
+			differ = append(differ, key)
+		}
+	}
+	for key := range want {
+		if _, ok := got[key]; !ok {
+			differ = append(differ, key)
+		}
+	}
+	if len(differ) > 0 {
+		slices.Sort(differ)
+		return fmt.Errorf("the replacement of %s was created with other values for labels %s", name, strings.Join(differ, ", "))
+	}
+	return nil
+}
+
+// waitReady polls the started container id until drollout.Check reports it
+// ready and it has stayed so for MinUptime, so a replacement crashing right
+// after its start is still rolled back.
+func (d *DockerContainers) waitReady(ctx context.Context, id string) error {
+	timeout := cmp.Or(d.ReadyTimeout, DefaultReadyTimeout)
+	minUptime := cmp.Or(d.MinUptime, DefaultMinUptime)
+	ticker := time.NewTicker(cmp.Or(d.PollInterval, DefaultPollInterval))
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var readySince time.Time
+	for {
+		st, err := d.Status(ctx, id)
+		if err != nil {
+			return err
+		}
+		ready, err := drollout.Check(st)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !ready:
+			readySince = time.Time{}
+		case readySince.IsZero():
+			readySince = time.Now()
+		}
+		if !readySince.IsZero() && time.Since(readySince) >= minUptime {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("still %s after %s", st, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// splitNetworks returns the network to create a container on, the one named
+// by mode if attached, and the other networks sorted by name.
+func splitNetworks(mode container.NetworkMode, networks map[string]*network.EndpointSettings) (primary string, rest []string) {
+	names := slices.Sorted(maps.Keys(networks))
+	if len(names) == 0 {
+		return "", nil
+	}
+	primary = names[0]
+	want := string(mode)
+	if mode.IsDefault() {
+		want = network.NetworkBridge
+	}
+	if _, ok := networks[want]; ok {
+		primary = want
+	}
+	return primary, slices.DeleteFunc(names, func(n string) bool { return n == primary })
+}
@@ -0,0 +1,344 @@
+package dockercontainers
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+// fakeClient runs DockerContainers against the fake engine, failing to start
+// the containers failStart returns an error for. Like Docker, it adds
+// imageLabels to created containers for the keys they do not set, and it
+// calls onInspect before each container inspect
+type fakeClient struct {
+	*fakedocker.Engine
+	failStart   func(id string) error
+	imageLabels map[string]string
+	onInspect   func(id string)
+}
+
+func (f *fakeClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if f.imageLabels != nil {
+		cfg := *config
+		cfg.Labels = maps.Clone(cfg.Labels)
+		if cfg.Labels == nil {
+			cfg.Labels = make(map[string]string)
+		}
+		for k, v := range f.imageLabels {
+			if _, ok := cfg.Labels[k]; !ok {
+				cfg.Labels[k] = v
+			}
+		}
+		config = &cfg
+	}
+	return f.Engine.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func (f *fakeClient) ContainerInspect(ctx context.Context, id string) (container.InspectResponse, error) {
+	if f.onInspect != nil {
+		f.onInspect(id)
+	}
+	return f.Engine.ContainerInspect(ctx, id)
+}
+
+func (f *fakeClient) ContainerStart(ctx context.Context, id string, opts container.StartOptions) error {
+	if f.failStart != nil {
+		if err := f.failStart(id); err != nil {
+			return err
+		}
+	}
+	return f.Engine.ContainerStart(ctx, id, opts)
+}
+
+// webEngine has a running container "web" on the shop and bridge networks
+// with a data volume
+func webEngine(t *testing.T) (*fakeClient, string) {
+	t.Helper()
+	engine := fakedocker.New()
+	engine.SetImageLabels("nginx:1", nil)
+	id, err := engine.AddContainer(fakedocker.ContainerSpec{
+		Name:     "web",
+		Image:    "nginx:1",
+		Labels:   map[string]string{"bosun.role": "api", "bosun.tier": "back", "keep": "1"},
+		Running:  true,
+		Mounts:   []string{"data:/data"},
+		Networks: []string{"shop", "bridge"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeClient{Engine: engine}, id
+}
+
+// newRecreator returns a DockerContainers on cli that waits only briefly for
+// replacements to stay ready
+func newRecreator(cli *fakeClient) *DockerContainers {
+	return &DockerContainers{CLI: cli, MinUptime: 20 * time.Millisecond, PollInterval: time.Millisecond}
+}
+
+func containerNames(t *testing.T, cli *fakeClient) []string {
+	t.Helper()
+	list, err := cli.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range list {
+		names = append(names, strings.TrimPrefix(c.Names[0], "/"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+func relabelEdit() drecreate.Edit {
+	return drecreate.Edit{Set: map[string]string{"bosun.role": "web"}, Unset: []string{"bosun.tier"}}
+}
+
+func TestRecreate(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	d := newRecreator(cli)
+
+	res, err := d.Recreate(ctx, "web", relabelEdit(), false)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+	if res.Container != "web" || res.OldID != oldID || res.NewID == "" || res.NewID == oldID {
+		t.Errorf("result = %+v", res)
+	}
+	var changes []string
+	for _, c := range res.Changes {
+		changes = append(changes, c.String())
+	}
+	want := []string{`~ Config.Labels["bosun.role"]: "api" -> "web"`, `- Config.Labels["bosun.tier"]: "back"`}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+
+	got, err := cli.ContainerInspect(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != res.NewID || !got.State.Running {
+		t.Errorf("web is %s, %s; want the running replacement %s", got.ID, got.State.Status, res.NewID)
+	}
+	if want := map[string]string{"bosun.role": "web", "keep": "1"}; !reflect.DeepEqual(got.Config.Labels, want) {
+		t.Errorf("labels = %v, want %v", got.Config.Labels, want)
+	}
+	if got.Config.Image != "nginx:1" || len(got.Mounts) != 1 || got.Mounts[0].Name != "data" || got.Mounts[0].Destination != "/data" {
+		t.Errorf("image %s, mounts %+v; want nginx:1 with data at /data", got.Config.Image, got.Mounts)
+	}
+	if nets := slices.Sorted(maps.Keys(got.NetworkSettings.Networks)); !reflect.DeepEqual(nets, []string{"bridge", "shop"}) {
+		t.Errorf("networks = %v, want bridge and shop", nets)
+	}
+	if names := containerNames(t, cli); !reflect.DeepEqual(names, []string{"web"}) {
+		t.Errorf("containers = %v, want only web", names)
+	}
+}
+
+func TestRecreate_DryRun(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	d := newRecreator(cli)
+
+	res, err := d.Recreate(ctx, "web", relabelEdit(), true)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+	if res.NewID != "" || len(res.Changes) != 2 {
+		t.Errorf("dry run result = %+v", res)
+	}
+	got, _ := cli.ContainerInspect(ctx, "web")
+	if got.ID != oldID || got.Config.Labels["bosun.role"] != "api" {
+		t.Errorf("dry run changed web: %s %v", got.ID, got.Config.Labels)
+	}
+}
+
+func TestRecreate_Unchanged(t *testing.T) {
+	cli, oldID := webEngine(t)
+	d := newRecreator(cli)
+
+	edit := drecreate.Edit{Set: map[string]string{"bosun.role": "api"}, Unset: []string{"missing"}}
+	res, err := d.Recreate(context.Background(), "web", edit, false)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+	if res.NewID != "" || len(res.Changes) != 0 || res.OldID != oldID {
+		t.Errorf("result = %+v, want no changes", res)
+	}
+}
+
+func TestRecreate_Stopped(t *testing.T) {
+	ctx := context.Background()
+	cli, _ := webEngine(t)
+	if err := cli.ContainerStop(ctx, "web", container.StopOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	d := newRecreator(cli)
+
+	res, err := d.Recreate(ctx, "web", relabelEdit(), false)
+	if err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+	got, _ := cli.ContainerInspect(ctx, "web")
+	if got.ID != res.NewID || got.State.Status != container.StateCreated {
+		t.Errorf("web is %s, %s; want the replacement, not started", got.ID, got.State.Status)
+	}
+}
+
+func TestRecreate_RollsBackWhenStartFails(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	cli.failStart = func(id string) error {
+		if id != oldID {
+			return errors.New("port is already allocated")
+		}
+		return nil
+	}
+	d := newRecreator(cli)
+
+	_, err := d.Recreate(ctx, "web", relabelEdit(), false)
+	if err == nil || !strings.Contains(err.Error(), "failed to start the replacement of web: port is already allocated") {
+		t.Fatalf("Recreate = %v, want a start failure", err)
+	}
+	got, err := cli.ContainerInspect(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != oldID || !got.State.Running || got.Config.Labels["bosun.role"] != "api" {
+		t.Errorf("web is %s, %s, %v; want the original running again", got.ID, got.State.Status, got.Config.Labels)
+	}
+	if names := containerNames(t, cli); !reflect.DeepEqual(names, []string{"web"}) {
+		t.Errorf("containers = %v, want only web", names)
+	}
+}
+
+func TestRecreate_RefusesUnsettingImageLabels(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	cli.SetImageLabels("nginx:1", map[string]string{"bosun.tier": "back"})
+	d := newRecreator(cli)
+
+	for _, dryRun := range []bool{true, false} {
+		_, err := d.Recreate(ctx, "web", relabelEdit(), dryRun)
+		if err == nil || !strings.Contains(err.Error(), "cannot unset bosun.tier on container web: the image defines it") {
+			t.Errorf("Recreate(dryRun=%v) = %v, want a refusal to unset bosun.tier", dryRun, err)
+		}
+	}
+	got, _ := cli.ContainerInspect(ctx, "web")
+	if got.ID != oldID || got.Config.Labels["bosun.tier"] != "back" {
+		t.Errorf("web is %s with %v; want the original", got.ID, got.Config.Labels)
+	}
+
+	// Changing the value of a label of the image works
+	edit := drecreate.Edit{Set: map[string]string{"bosun.tier": "front"}}
+	if _, err := d.Recreate(ctx, "web", edit, false); err != nil {
+		t.Fatalf("Recreate failed: %v", err)
+	}
+}
+
+func TestRecreate_RollsBackWhenLabelsDiffer(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	cli.imageLabels = map[string]string{"vendor": "acme"}
+	d := newRecreator(cli)
+
+	_, err := d.Recreate(ctx, "web", relabelEdit(), false)
+	if err == nil || !strings.Contains(err.Error(), "the replacement of web was created with other values for labels vendor") {
+		t.Fatalf("Recreate = %v, want a label mismatch", err)
+	}
+	got, _ := cli.ContainerInspect(ctx, "web")
+	if got.ID != oldID || !got.State.Running {
+		t.Errorf("web is %s, %s; want the original running", got.ID, got.State.Status)
+	}
+	if names := containerNames(t, cli); !reflect.DeepEqual(names, []string{"web"}) {
+		t.Errorf("containers = %v, want only web", names)
+	}
+}
+
+func TestRecreate_RollsBackWhenReplacementCrashes(t *testing.T) {
+	ctx := context.Background()
+	cli, oldID := webEngine(t)
+	// The replacement exits on the second check after it started
+	var checks int
+	cli.onInspect = func(id string) {
+		if id == oldID || id == "web" {
+			return
+		}
+		if c, err := cli.Engine.ContainerInspect(ctx, id); err == nil && c.State.Running {
+			if checks++; checks == 2 {
+				_ = cli.Exit(id, 1)
+			}
+		}
+	}
+	d := newRecreator(cli)
+
+	_, err := d.Recreate(ctx, "web", relabelEdit(), false)
+	if err == nil || !strings.Contains(err.Error(), "the replacement of web did not become ready: exited with code 1") {
+		t.Fatalf("Recreate = %v, want the crash of the replacement", err)
+	}
+	got, _ := cli.ContainerInspect(ctx, "web")
+	if got.ID != oldID || !got.State.Running || got.Config.Labels["bosun.role"] != "api" {
+		t.Errorf("web is %s, %s, %v; want the original running again", got.ID, got.State.Status, got.Config.Labels)
+	}
+	if names := containerNames(t, cli); !reflect.DeepEqual(names, []string{"web"}) {
+		t.Errorf("containers = %v, want only web", names)
+	}
+}
+
+func TestReplacementSpec(t *testing.T) {
+	const id = "0123456789abcdef0123"
+	old := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:         id,
+			Image:      "sha256:old",
+			HostConfig: &container.HostConfig{Binds: []string{"data:/data"}, NetworkMode: "shop"},
+			State:      &container.State{Running: true},
+		},
+		Config: &container.Config{Image: "nginx:1", Hostname: "0123456789ab", Labels: map[string]string{"a": "1"}},
+		Mounts: []container.MountPoint{
+			{Type: mount.TypeVolume, Name: "data", Destination: "/data", RW: true},
+			{Type: mount.TypeVolume, Name: "f00d", Destination: "/cache"},
+			{Type: mount.TypeBind, Source: "/etc/app", Destination: "/etc/app"},
+		},
+		NetworkSettings: &container.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"shop": {Aliases: []string{"web", "0123456789ab"}, IPAddress: "172.18.0.2", NetworkID: "n1"},
+		}},
+	}
+	// The mock finds no image, as if nginx:1 had been removed
+	d := &DockerContainers{CLI: &mockDockerClient{}}
+
+	spec, notes := d.replacementSpec(context.Background(), old, map[string]string{"a": "2"})
+	if spec.Config.Hostname != "" || spec.Config.Image != "sha256:old" || spec.Config.Labels["a"] != "2" {
+		t.Errorf("config = %+v", spec.Config)
+	}
+	if want := []mount.Mount{{Type: mount.TypeVolume, Source: "f00d", Target: "/cache", ReadOnly: true}}; !reflect.DeepEqual(spec.HostConfig.Mounts, want) {
+		t.Errorf("mounts = %+v, want %+v", spec.HostConfig.Mounts, want)
+	}
+	if want := (&network.EndpointSettings{Aliases: []string{"web"}}); !reflect.DeepEqual(spec.Networks["shop"], want) {
+		t.Errorf("shop endpoint = %+v, want %+v", spec.Networks["shop"], want)
+	}
+	if len(notes) != 2 {
+		t.Errorf("notes = %q, want the image and the /cache volume", notes)
+	}
+	if old.Config.Labels["a"] != "1" || old.Config.Hostname == "" || len(old.HostConfig.Mounts) != 0 {
+		t.Error("replacementSpec modified the inspected container")
+	}
+
+	old.HostConfig.NetworkMode = "host"
+	if spec, _ := d.replacementSpec(context.Background(), old, nil); spec.Networks != nil {
+		t.Errorf("host network mode got endpoints %v", spec.Networks)
+	}
+}
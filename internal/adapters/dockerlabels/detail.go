@@ -0,0 +1,99 @@
+package dockerlabels
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// addContainerDetail adds the metadata of the container list entry c to meta.
+func addContainerDetail(meta map[string]string, c container.Summary) {
+	meta[dlabels.MetaState] = string(c.State)
+	meta[dlabels.MetaStatus] = c.Status
+	meta[dlabels.MetaCreated] = time.Unix(c.Created, 0).UTC().Format(time.RFC3339)
+	if p := formatPorts(c.Ports); p != "" {
+		meta[dlabels.MetaPorts] = p
+	}
+	if m := formatMounts(c.Mounts); m != "" {
+		meta[dlabels.MetaMounts] = m
+	}
+	if c.NetworkSettings != nil && len(c.NetworkSettings.Networks) > 0 {
+		names := make([]string, 0, len(c.NetworkSettings.Networks))
+		for name := range c.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		meta[dlabels.MetaNetworks] = strings.Join(names, ",")
+	}
+}
+
+// addInspectDetail adds the health status and restart count of container id to meta.
+// Containers without a healthcheck get no health entry. It reports false when
+// the container was removed since it was listed.
+func (s *DockerLabelSource) addInspectDetail(ctx context.Context, meta map[string]string, id string) (bool, error) {
+	info, err := s.CLI.ContainerInspect(ctx, id)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect container %s: %w", id, err)
+	}
+	if info.ContainerJSONBase == nil {
+		return true, nil
+	}
+	meta[dlabels.MetaRestarts] = strconv.Itoa(info.RestartCount)
+	if info.State != nil && info.State.Health != nil && info.State.Health.Status != container.NoHealthcheck {
+		meta[dlabels.MetaHealth] = string(info.State.Health.Status)
+	}
+	return true, nil
+}
+
+// addVolumeDetail adds the mountpoint and scope of v to meta.
+func addVolumeDetail(meta map[string]string, v *volume.Volume) {
+	meta[dlabels.MetaMountpoint] = v.Mountpoint
+	meta[dlabels.MetaScope] = v.Scope
+}
+
+// formatPorts renders ports like docker ps, e.g. "0.0.0.0:8080->80/tcp,443/tcp",
+// sorted and without duplicates.
+func formatPorts(ports []container.Port) string {
+	out := make([]string, 0, len(ports))
+	for _, p := range ports {
+		s := fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)
+		if p.PublicPort != 0 {
+			s = fmt.Sprintf("%s->%s", net.JoinHostPort(p.IP, strconv.Itoa(int(p.PublicPort))), s)
+		}
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return strings.Join(slices.Compact(out), ",")
+}
+
+// formatMounts renders mounts as a MetaMounts value, sorted by destination.
+// tmpfs mounts have no source and are rendered as their destination alone.
+func formatMounts(mounts []container.MountPoint) string {
+	sorted := slices.Clone(mounts)
+	slices.SortFunc(sorted, func(a, b container.MountPoint) int { return strings.Compare(a.Destination, b.Destination) })
+	out := make([]dlabels.Mount, 0, len(sorted))
+	for _, m := range sorted {
+		dm := dlabels.Mount{Destination: m.Destination, ReadOnly: !m.RW}
+		if m.Type == mount.TypeVolume {
+			dm.Volume = m.Name
+		} else {
+			dm.Source = m.Source
+		}
+		out = append(out, dm)
+	}
+	return dlabels.FormatMounts(out)
+}
@@ -0,0 +1,150 @@
+package dockerlabels
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// detailDockerClient serves one running container with ports, mounts and
+// networks, and answers inspections with a health status and restart count
+type detailDockerClient struct {
+	mockDockerClient
+	inspects []string
+}
+
+func (m *detailDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	return []container.Summary{
+		{
+			ID:      "c1",
+			Names:   []string{"/shop-db-1"},
+			Image:   "postgres:16",
+			Labels:  map[string]string{"bosun.backup": "daily"},
+			Created: 1741703400,
+			State:   container.StateRunning,
+			Status:  "Up 2 hours (healthy)",
+			Ports: []container.Port{
+				{PrivatePort: 5432, Type: "tcp"},
+				{IP: "0.0.0.0", PrivatePort: 5432, PublicPort: 15432, Type: "tcp"},
+				{IP: "::", PrivatePort: 5432, PublicPort: 15432, Type: "tcp"},
+			},
+			Mounts: []container.MountPoint{
+				{Type: mount.TypeVolume, Name: "shop_db-data", Source: "/var/lib/docker/volumes/shop_db-data/_data", Destination: "/var/lib/postgresql/data", RW: true},
+				{Type: mount.TypeBind, Source: "/srv/shop/init.sql", Destination: "/docker-entrypoint-initdb.d/init.sql"},
+				{Type: mount.TypeTmpfs, Destination: "/run", RW: true},
+			},
+			NetworkSettings: &container.NetworkSettingsSummary{
+				Networks: map[string]*network.EndpointSettings{"shop_default": {}, "shop_backend": {}},
+			},
+		},
+		{
+			ID:     "c2",
+			Names:  []string{"/gone"},
+			Labels: map[string]string{"bosun.role": "gone"},
+			State:  container.StateExited,
+		},
+	}, nil
+}
+
+func (m *detailDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	m.inspects = append(m.inspects, containerID)
+	if containerID != "c1" {
+		return container.InspectResponse{}, notFoundError{}
+	}
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:           containerID,
+			RestartCount: 3,
+			State:        &container.State{Health: &container.Health{Status: container.Healthy}},
+		},
+	}, nil
+}
+
+func (m *detailDockerClient) VolumeList(ctx context.Context, opts volume.ListOptions) (volume.ListResponse, error) {
+	return volume.ListResponse{Volumes: []*volume.Volume{{
+		Name:       "shop_db-data",
+		Driver:     "local",
+		Mountpoint: "/var/lib/docker/volumes/shop_db-data/_data",
+		Scope:      "local",
+		Labels:     map[string]string{"bosun.backup": "daily"},
+	}}}, nil
+}
+
+func TestSnapshot_DetailLevels(t *testing.T) {
+	base := map[string]string{"compose.project": "", "compose.service": "", "image": "postgres:16"}
+	extended := map[string]string{
+		"compose.project":    "",
+		"compose.service":    "",
+		"image":              "postgres:16",
+		dlabels.MetaState:    "running",
+		dlabels.MetaStatus:   "Up 2 hours (healthy)",
+		dlabels.MetaCreated:  "2025-03-11T14:30:00Z",
+		dlabels.MetaPorts:    "0.0.0.0:15432->5432/tcp,5432/tcp,[::]:15432->5432/tcp",
+		dlabels.MetaMounts:   "/srv/shop/init.sql:/docker-entrypoint-initdb.d/init.sql:ro,/run,shop_db-data:/var/lib/postgresql/data",
+		dlabels.MetaNetworks: "shop_backend,shop_default",
+	}
+	full := map[string]string{dlabels.MetaHealth: "healthy", dlabels.MetaRestarts: "3"}
+	for k, v := range extended {
+		full[k] = v
+	}
+
+	tests := []struct {
+		detail   ports.Detail
+		want     map[string]string
+		inspects int
+	}{
+		{ports.DetailBasic, base, 0},
+		{ports.DetailExtended, extended, 0},
+		{ports.DetailFull, full, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detail.String(), func(t *testing.T) {
+			cli := &detailDockerClient{}
+			source := &DockerLabelSource{CLI: cli}
+			sel := ports.Selector{Prefixes: []string{"bosun."}, Detail: tt.detail, IncludeStopped: true}
+
+			snap, err := source.Snapshot(context.Background(), sel)
+			if err != nil {
+				t.Fatalf("Snapshot failed: %v", err)
+			}
+			var ctrs []dlabels.LabeledEntity
+			var db, vol dlabels.LabeledEntity
+			for _, e := range snap.Entities {
+				switch e.Kind {
+				case dlabels.KindContainer:
+					ctrs = append(ctrs, e)
+					if e.ID == "c1" {
+						db = e
+					}
+				case dlabels.KindVolume:
+					vol = e
+				}
+			}
+			// c2 disappears between list and inspect at DetailFull
+			if wantCtrs := 2 - tt.inspects/2; len(ctrs) != wantCtrs {
+				t.Fatalf("containers = %d, want %d", len(ctrs), wantCtrs)
+			}
+			if !reflect.DeepEqual(db.Meta, tt.want) {
+				t.Errorf("Meta = %v, want %v", db.Meta, tt.want)
+			}
+			if len(cli.inspects) != tt.inspects {
+				t.Errorf("inspects = %v, want %d", cli.inspects, tt.inspects)
+			}
+
+			_, hasMountpoint := vol.Meta[dlabels.MetaMountpoint]
+			if hasMountpoint != (tt.detail >= ports.DetailExtended) {
+				t.Errorf("volume Meta = %v at detail %s", vol.Meta, tt.detail)
+			}
+			if hasMountpoint && vol.Meta[dlabels.MetaScope] != "local" {
+				t.Errorf("volume scope = %q, want local", vol.Meta[dlabels.MetaScope])
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package dockerlabels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+var _ dockerClient = (*fakedocker.Engine)(nil)
+
+// TestNewFromEnv_FakeEngine runs the source against the fake engine over a
+// unix socket, through the real Docker client
+func TestNewFromEnv_FakeEngine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	e := fakedocker.New()
+	web, err := e.AddContainer(fakedocker.ContainerSpec{
+		Name:     "web",
+		Running:  true,
+		Labels:   map[string]string{"bosun.role": "web", "com.docker.compose.project": "shop"},
+		Mounts:   []string{"data:/data"},
+		Networks: []string{"backend"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddContainer(fakedocker.ContainerSpec{Name: "job", Labels: map[string]string{"bosun.role": "job"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetLabels("data", map[string]string{"bosun.backup": "daily"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DOCKER_HOST", e.ServeUnix(t))
+	t.Setenv("DOCKER_API_VERSION", "")
+	src, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := src.Snapshot(ctx, ports.Selector{Prefixes: []string{"bosun."}, Detail: ports.DetailExtended})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]dlabels.LabeledEntity{}
+	for _, ent := range snap.Entities {
+		got[string(ent.Kind)+"/"+ent.Name] = ent
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected the running container and the volume, got %v", snap.Entities)
+	}
+	if c := got["container/web"]; c.ID != web || c.Meta["compose.project"] != "shop" || c.Meta[dlabels.MetaState] != "running" {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	if v := got["volume/data"]; v.Labels["bosun.backup"] != "daily" {
+		t.Errorf("unexpected volume: %+v", v)
+	}
+
+	changes, err := src.Watch(ctx, ports.Selector{Prefixes: []string{"bosun."}, Kinds: []dlabels.Kind{dlabels.KindContainer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// waitFor skips changes until one of type typ for id arrives
+	waitFor := func(typ dlabels.ChangeType, id string) dlabels.Change {
+		t.Helper()
+		for {
+			select {
+			case c, ok := <-changes:
+				if !ok {
+					t.Fatal("watch closed")
+				}
+				if c.Type == typ && c.Entity.ID == id {
+					return c
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s of %s", typ, id)
+			}
+		}
+	}
+
+	waitFor(dlabels.ChangeAdded, web)
+	api, err := e.AddContainer(fakedocker.ContainerSpec{Name: "api", Running: true, Labels: map[string]string{"bosun.role": "api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := waitFor(dlabels.ChangeAdded, api); c.Entity.Labels["bosun.role"] != "api" {
+		t.Errorf("unexpected labels: %v", c.Entity.Labels)
+	}
+	if err := e.Exit(web, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(dlabels.ChangeRemoved, web)
+}
@@ -1,6 +1,12 @@
 package dockerlabels
 
-import "strings"
+import (
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+)
 
 // FilterByPrefixes filters a map of labels by allowed prefixes and drops empty values.
 // It returns a new map containing only labels whose keys start with any of the provided prefixes,
@@ -25,3 +31,54 @@ func FilterByPrefixes(in map[string]string, prefixes []string) map[string]string
 	}
 	return out
 }
+
+// ProjectFilters builds the Docker API filters for a compose project selection.
+// Docker ANDs repeated label filters, so it returns one filters.Args per project
+// and callers issue one list call per element. Duplicate and empty project names
+// are dropped. With no projects it returns a single empty filters.Args.
+func ProjectFilters(projects []string) []filters.Args {
+	return projectLabelFilters(LabelComposeProject, projects)
+}
+
+// projectLabelFilters is ProjectFilters for the project label key.
+func projectLabelFilters(key string, projects []string) []filters.Args {
+	var names []string
+	for _, p := range projects {
+		if p = strings.TrimSpace(p); p != "" {
+			names = append(names, p)
+		}
+	}
+	if len(names) == 0 {
+		return []filters.Args{filters.NewArgs()}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	out := make([]filters.Args, 0, len(names))
+	for _, p := range names {
+		out = append(out, filters.NewArgs(filters.Arg("label", key+"="+p)))
+	}
+	return out
+}
+
+// SelectorFilters translates the requirements of sel that Docker can evaluate
+// into label filters: equality with a non-empty value and existence. Docker ANDs
+// them like the selector does. The remaining requirements and Meta keys are
+// still evaluated client-side, so the result only narrows the list calls.
+func SelectorFilters(sel dselector.Selector) []filters.KeyValuePair {
+	var out []filters.KeyValuePair
+	for _, r := range sel {
+		if r.IsMeta() {
+			continue
+		}
+		switch {
+		case r.Op == dselector.Exists:
+			out = append(out, filters.Arg("label", r.Key))
+		case r.Op == dselector.Equals && r.Values[0] != "":
+			out = append(out, filters.Arg("label", r.Key+"="+r.Values[0]))
+		case r.Op == dselector.In && len(r.Values) == 1:
+			out = append(out, filters.Arg("label", r.Key+"="+r.Values[0]))
+		}
+	}
+	return out
+}
@@ -2,9 +2,12 @@ package dockerlabels
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 
+	"github.com/docker/docker/api/types/filters"
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
 )
 
 func TestFilterByPrefixes(t *testing.T) {
@@ -73,3 +76,39 @@ func TestFilterByPrefixes(t *testing.T) {
 		})
 	}
 }
+
+func TestProjectFilters(t *testing.T) {
+	t.Run("no projects yields a single empty filter", func(t *testing.T) {
+		got := ProjectFilters(nil)
+		if len(got) != 1 || got[0].Len() != 0 {
+			t.Fatalf("ProjectFilters(nil) = %v, expected one empty Args", got)
+		}
+	})
+
+	t.Run("one filter per distinct project", func(t *testing.T) {
+		got := ProjectFilters([]string{"web", "db", "web", " "})
+		if len(got) != 2 {
+			t.Fatalf("expected 2 filters, got %d", len(got))
+		}
+		want := []string{LabelComposeProject + "=db", LabelComposeProject + "=web"}
+		for i, f := range got {
+			if !reflect.DeepEqual(f.Get("label"), []string{want[i]}) {
+				t.Errorf("filter[%d] label = %v, expected %v", i, f.Get("label"), want[i])
+			}
+		}
+	})
+}
+
+func TestSelectorFilters(t *testing.T) {
+	sel, err := dselector.Parse("bosun.role=web,bosun.env!=dev,bosun.backup,bosun.tier in (db),bosun.x in (a,b),!bosun.ignore,meta:image=nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := filters.NewArgs(SelectorFilters(sel)...)
+	got := args.Get("label")
+	slices.Sort(got)
+	want := []string{"bosun.backup", "bosun.role=web", "bosun.tier=db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectorFilters() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package dockerlabels
+
+import (
+	"context"
+	"sync"
+
+	"github.com/docker/docker/client"
+)
+
+// MetaOriginPrefix prefixes the Meta keys that record where a container label
+// came from when image labels are inherited, e.g. "origin.bosun.role": "image".
+const MetaOriginPrefix = "origin."
+
+// Label origins recorded under MetaOriginPrefix.
+const (
+	OriginImage     = "image"
+	OriginContainer = "container"
+)
+
+// imageLabelCache caches image labels by image ID. Image IDs are content
+// addressed, so the labels of an ID never change and entries never expire.
+type imageLabelCache struct {
+	mu     sync.Mutex
+	labels map[string]map[string]string
+}
+
+func (c *imageLabelCache) get(id string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.labels[id]
+	return l, ok
+}
+
+func (c *imageLabelCache) put(id string, labels map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.labels == nil {
+		c.labels = make(map[string]map[string]string)
+	}
+	c.labels[id] = labels
+}
+
+// imageLabels returns the labels of the image with the given ID.
+// A missing image, e.g. removed after the container was created, has no labels.
+func (s *DockerLabelSource) imageLabels(ctx context.Context, id string) (map[string]string, error) {
+	if id == "" {
+		return nil, nil
+	}
+	if l, ok := s.images.get(id); ok {
+		return l, nil
+	}
+
+	resp, err := s.CLI.ImageInspect(ctx, id)
+	if err != nil && !client.IsErrNotFound(err) {
+		return nil, err
+	}
+	var labels map[string]string
+	if resp.Config != nil {
+		labels = resp.Config.Labels
+	}
+	s.images.put(id, labels)
+	return labels, nil
+}
+
+// mergeImageLabels layers container labels over image labels, container labels winning.
+// It returns the merged labels and the origin of each key. Docker copies image labels
+// into the container config on creation, so a container value equal to the image value
+// is reported as coming from the image.
+func mergeImageLabels(image, ctr map[string]string) (map[string]string, map[string]string) {
+	merged := make(map[string]string, len(image)+len(ctr))
+	origins := make(map[string]string, len(image)+len(ctr))
+	for k, v := range image {
+		merged[k] = v
+		origins[k] = OriginImage
+	}
+	for k, v := range ctr {
+		if iv, ok := image[k]; ok && iv == v {
+			continue
+		}
+		merged[k] = v
+		origins[k] = OriginContainer
+	}
+	return merged, origins
+}
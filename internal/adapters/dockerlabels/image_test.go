@@ -0,0 +1,133 @@
+package dockerlabels
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// imageDockerClient serves containers sharing one vendor image and counts image inspections
+type imageDockerClient struct {
+	mockDockerClient
+	inspects atomic.Int32
+}
+
+func (m *imageDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	return []container.Summary{
+		{
+			ID:      "c1",
+			Names:   []string{"/vendor-1"},
+			ImageID: "sha256:vendor",
+			Labels:  map[string]string{"bosun.backup": "daily", "bosun.role": "db"},
+		},
+		{
+			ID:      "c2",
+			Names:   []string{"/vendor-2"},
+			ImageID: "sha256:vendor",
+			Labels:  map[string]string{"bosun.role": "replica"},
+		},
+		{
+			ID:      "c3",
+			Names:   []string{"/orphan"},
+			ImageID: "sha256:gone",
+			Labels:  map[string]string{"bosun.role": "orphan"},
+		},
+	}, nil
+}
+
+func (m *imageDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	m.inspects.Add(1)
+	if imageID != "sha256:vendor" {
+		return image.InspectResponse{}, notFoundError{}
+	}
+	return image.InspectResponse{
+		ID: imageID,
+		Config: &dockerspec.DockerOCIImageConfig{
+			ImageConfig: ocispec.ImageConfig{
+				Labels: map[string]string{"bosun.backup": "daily", "bosun.role": "db", "vendor.name": "acme"},
+			},
+		},
+	}, nil
+}
+
+// notFoundError satisfies the errdefs not-found interface used by client.IsErrNotFound
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "no such image" }
+func (notFoundError) NotFound()     {}
+
+func TestSnapshotContainers_InheritImageLabels(t *testing.T) {
+	cli := &imageDockerClient{}
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{
+		Prefixes:           []string{dlabels.DefaultLabelPrefix},
+		InheritImageLabels: true,
+	}
+
+	entities, err := source.snapshotContainers(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("snapshotContainers failed: %v", err)
+	}
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 containers, got %d", len(entities))
+	}
+
+	// c1 has the image values, so both labels are attributed to the image
+	if got := entities[0].Meta[MetaOriginPrefix+"bosun.backup"]; got != OriginImage {
+		t.Errorf("c1 bosun.backup origin = %q, want image", got)
+	}
+	if got := entities[0].Meta[MetaOriginPrefix+"bosun.role"]; got != OriginImage {
+		t.Errorf("c1 bosun.role origin = %q, want image", got)
+	}
+
+	// c2 overrides bosun.role and inherits bosun.backup
+	want := map[string]string{"bosun.backup": "daily", "bosun.role": "replica"}
+	if !reflect.DeepEqual(entities[1].Labels, want) {
+		t.Errorf("c2 labels = %v, want %v", entities[1].Labels, want)
+	}
+	if got := entities[1].Meta[MetaOriginPrefix+"bosun.role"]; got != OriginContainer {
+		t.Errorf("c2 bosun.role origin = %q, want container", got)
+	}
+	if _, ok := entities[1].Meta[MetaOriginPrefix+"vendor.name"]; ok {
+		t.Errorf("c2 records origin of a filtered label: %v", entities[1].Meta)
+	}
+
+	// c3's image is gone: only container labels
+	if got := entities[2].Meta[MetaOriginPrefix+"bosun.role"]; got != OriginContainer {
+		t.Errorf("c3 bosun.role origin = %q, want container", got)
+	}
+
+	// One inspection per image ID, reused by later snapshots
+	if _, err := source.snapshotContainers(context.Background(), sel); err != nil {
+		t.Fatalf("snapshotContainers failed: %v", err)
+	}
+	if n := cli.inspects.Load(); n != 2 {
+		t.Errorf("expected 2 image inspections, got %d", n)
+	}
+}
+
+func TestSnapshotContainers_ImageLabelsOptIn(t *testing.T) {
+	cli := &imageDockerClient{}
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}}
+
+	entities, err := source.snapshotContainers(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("snapshotContainers failed: %v", err)
+	}
+	if _, ok := entities[1].Labels["bosun.backup"]; ok {
+		t.Errorf("image labels merged without InheritImageLabels: %v", entities[1].Labels)
+	}
+	if n := cli.inspects.Load(); n != 0 {
+		t.Errorf("expected no image inspections, got %d", n)
+	}
+}
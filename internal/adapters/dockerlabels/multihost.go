@@ -0,0 +1,120 @@
+package dockerlabels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"golang.org/x/sync/errgroup"
+)
+
+// MetaHost is the Meta key MultiHostSource sets to the name of the host an entity was found on.
+const MetaHost = "host"
+
+// Host is a label source for one Docker daemon.
+type Host struct {
+	Name   string
+	Source ports.LabelSource
+}
+
+// MultiHostSource merges the snapshots of several Docker daemons into one,
+// recording in Meta which host each entity was found on.
+type MultiHostSource struct {
+	Hosts []Host
+}
+
+// HostError is the failure of a single host.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("host %s: %v", e.Host, e.Err)
+}
+
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError reports the hosts that failed while the others succeeded.
+type PartialError struct {
+	Failed []*HostError
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("%d host(s) failed: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// Snapshot implements the LabelSource interface. Hosts are queried
+// concurrently and one failing host does not cancel the others. When some
+// hosts fail, Snapshot returns the merged snapshot of the reachable hosts
+// together with a *PartialError; when all fail, it returns only an error.
+func (m *MultiHostSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	if len(m.Hosts) == 0 {
+		return dlabels.Snapshot{}, errors.New("no hosts configured")
+	}
+
+	// The host is only known here, so meta:host requirements are evaluated
+	// after merging rather than by every host.
+	perHost := sel
+	perHost.LabelSelector = slices.DeleteFunc(slices.Clone(sel.LabelSelector), func(r dselector.Requirement) bool {
+		return r.Key == dselector.MetaPrefix+MetaHost
+	})
+
+	snaps := make([]dlabels.Snapshot, len(m.Hosts))
+	errs := make([]error, len(m.Hosts))
+	var g errgroup.Group
+	for i, h := range m.Hosts {
+		g.Go(func() error {
+			snaps[i], errs[i] = h.Source.Snapshot(ctx, perHost)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	merged := dlabels.Snapshot{TakenAt: time.Now()}
+	var failed []*HostError
+	for i, h := range m.Hosts {
+		if errs[i] != nil {
+			failed = append(failed, &HostError{Host: h.Name, Err: errs[i]})
+			continue
+		}
+		for _, e := range snaps[i].Entities {
+			if e.Meta == nil {
+				e.Meta = make(map[string]string)
+			}
+			e.Meta[MetaHost] = h.Name
+			if sel.LabelSelector.Matches(e) {
+				merged.Entities = append(merged.Entities, e)
+			}
+		}
+	}
+
+	if len(failed) == len(m.Hosts) {
+		return dlabels.Snapshot{}, fmt.Errorf("all hosts failed: %w", errors.Join((&PartialError{Failed: failed}).Unwrap()...))
+	}
+	dlabels.SortEntities(merged.Entities)
+	if len(failed) > 0 {
+		return merged, &PartialError{Failed: failed}
+	}
+	return merged, nil
+}
@@ -0,0 +1,101 @@
+package dockerlabels
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// stubSource returns a fixed snapshot or error and records the selector it got
+type stubSource struct {
+	entities []dlabels.LabeledEntity
+	err      error
+	sel      ports.Selector
+}
+
+func (s *stubSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	s.sel = sel
+	if s.err != nil {
+		return dlabels.Snapshot{}, s.err
+	}
+	return dlabels.Snapshot{Entities: s.entities}, nil
+}
+
+func TestMultiHostSource_Merge(t *testing.T) {
+	web := &stubSource{entities: []dlabels.LabeledEntity{
+		{Kind: dlabels.KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}},
+		{Kind: dlabels.KindContainer, ID: "c1", Name: "web-1", Meta: map[string]string{"image": "nginx"}},
+	}}
+	db := &stubSource{entities: []dlabels.LabeledEntity{
+		{Kind: dlabels.KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "hourly"}},
+	}}
+	src := &MultiHostSource{Hosts: []Host{{Name: "web1", Source: web}, {Name: "db1", Source: db}}}
+
+	snap, err := src.Snapshot(context.Background(), ports.Selector{})
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var got []string
+	for _, e := range snap.Entities {
+		got = append(got, e.Meta[MetaHost]+"/"+e.Name)
+	}
+	want := []string{"web1/web-1", "web1/data", "db1/data"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entities = %v, want %v", got, want)
+	}
+	if snap.Entities[0].Meta["image"] != "nginx" {
+		t.Errorf("existing meta lost: %v", snap.Entities[0].Meta)
+	}
+}
+
+func TestMultiHostSource_PartialFailure(t *testing.T) {
+	down := errors.New("connection refused")
+	src := &MultiHostSource{Hosts: []Host{
+		{Name: "web1", Source: &stubSource{entities: []dlabels.LabeledEntity{{Kind: dlabels.KindVolume, ID: "a", Name: "a"}}}},
+		{Name: "web2", Source: &stubSource{err: down}},
+	}}
+
+	snap, err := src.Snapshot(context.Background(), ports.Selector{})
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a PartialError, got %v", err)
+	}
+	if len(partial.Failed) != 1 || partial.Failed[0].Host != "web2" || !errors.Is(err, down) {
+		t.Errorf("unexpected failures: %v", partial)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].Meta[MetaHost] != "web1" {
+		t.Errorf("reachable host dropped: %+v", snap.Entities)
+	}
+
+	src.Hosts[0].Source = &stubSource{err: down}
+	if _, err := src.Snapshot(context.Background(), ports.Selector{}); err == nil || errors.As(err, &partial) {
+		t.Errorf("expected a plain error when every host fails, got %v", err)
+	}
+}
+
+func TestMultiHostSource_HostSelector(t *testing.T) {
+	web := &stubSource{entities: []dlabels.LabeledEntity{{Kind: dlabels.KindVolume, ID: "a", Name: "a", Labels: map[string]string{"bosun.x": "1"}}}}
+	db := &stubSource{entities: []dlabels.LabeledEntity{{Kind: dlabels.KindVolume, ID: "b", Name: "b", Labels: map[string]string{"bosun.x": "1"}}}}
+	src := &MultiHostSource{Hosts: []Host{{Name: "web1", Source: web}, {Name: "db1", Source: db}}}
+
+	sel, err := dselector.Parse("bosun.x=1,meta:host=db1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := src.Snapshot(context.Background(), ports.Selector{LabelSelector: sel})
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].Name != "b" {
+		t.Errorf("entities = %+v, want only b", snap.Entities)
+	}
+	// hosts only see the requirements they can evaluate
+	if got := web.sel.LabelSelector.String(); got != "bosun.x=1" {
+		t.Errorf("per-host selector = %q, want %q", got, "bosun.x=1")
+	}
+}
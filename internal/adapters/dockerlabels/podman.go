@@ -0,0 +1,144 @@
+package dockerlabels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Labels set by podman-compose. Releases before 1.1 set only these, without
+// the Docker Compose labels.
+const (
+	LabelPodmanComposeProject = "io.podman.compose.project"
+	LabelPodmanComposeService = "io.podman.compose.service"
+)
+
+// Meta keys for the pod of a container on Podman.
+const (
+	MetaPodID   = "pod.id"
+	MetaPodName = "pod.name"
+)
+
+// Backend is the engine behind the Docker API socket.
+type Backend string
+
+const (
+	BackendDocker Backend = "docker"
+	BackendPodman Backend = "podman"
+)
+
+// DetectBackend tells Docker from Podman by the component list of /version,
+// where Podman reports a "Podman Engine" component.
+func DetectBackend(v types.Version) Backend {
+	for _, c := range v.Components {
+		if strings.HasPrefix(c.Name, "Podman") {
+			return BackendPodman
+		}
+	}
+	return BackendDocker
+}
+
+// Backend returns the engine behind the socket, detected on first use.
+func (d *DockerLabelSource) Backend(ctx context.Context) (Backend, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.backend != "" {
+		return d.backend, nil
+	}
+	v, err := d.CLI.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the Docker API backend: %w", err)
+	}
+	d.backend = DetectBackend(v)
+	return d.backend, nil
+}
+
+// projectLabels returns the labels that can carry the compose project on b.
+func projectLabels(b Backend) []string {
+	if b == BackendPodman {
+		return []string{LabelComposeProject, LabelPodmanComposeProject}
+	}
+	return []string{LabelComposeProject}
+}
+
+// composeLabel returns the Docker Compose label, or else the podman-compose one.
+func composeLabel(labels map[string]string, docker, podman string) string {
+	if v := labels[docker]; v != "" {
+		return v
+	}
+	return labels[podman]
+}
+
+// Pod identifies a Podman pod.
+type Pod struct {
+	ID   string
+	Name string
+}
+
+// podLister reports pod membership, which the Docker-compatible API does not expose.
+type podLister interface {
+	// ContainerPods returns the pod of every container in one, keyed by container ID.
+	ContainerPods(ctx context.Context) (map[string]Pod, error)
+}
+
+// libpodPods reads pod membership from Podman's native libpod API, served on
+// the same socket as the Docker-compatible API.
+type libpodPods struct {
+	http *http.Client
+	base string
+}
+
+// newLibpodPods sends libpod requests through the transport of cli, so they
+// reach the same socket, TLS endpoint or ssh tunnel.
+func newLibpodPods(cli *client.Client) (*libpodPods, error) {
+	u, err := client.ParseHostURL(cli.DaemonHost())
+	if err != nil {
+		return nil, err
+	}
+	httpClient := cli.HTTPClient()
+	scheme := "http"
+	if tr, ok := httpClient.Transport.(*http.Transport); ok && tr.TLSClientConfig != nil {
+		scheme = "https"
+	}
+	host := u.Host
+	if u.Scheme == "unix" || u.Scheme == "npipe" {
+		host = client.DummyHost
+	}
+	return &libpodPods{http: httpClient, base: scheme + "://" + host + strings.TrimSuffix(u.Path, "/")}, nil
+}
+
+func (p *libpodPods) ContainerPods(ctx context.Context) (map[string]Pod, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.base+"/v4.0.0/libpod/containers/json?all=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Podman containers: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list Podman containers: %s", resp.Status)
+	}
+
+	var ctrs []struct {
+		ID      string `json:"Id"`
+		Pod     string `json:"Pod"`
+		PodName string `json:"PodName"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ctrs); err != nil {
+		return nil, fmt.Errorf("failed to decode Podman containers: %w", err)
+	}
+	pods := make(map[string]Pod)
+	for _, c := range ctrs {
+		if c.Pod != "" {
+			pods[c.ID] = Pod{ID: c.Pod, Name: c.PodName}
+		}
+	}
+	return pods, nil
+}
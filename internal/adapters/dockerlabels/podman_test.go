@@ -0,0 +1,205 @@
+package dockerlabels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerconn"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fixtureServer serves the API responses in testdata/<dir>. They are written
+// by hand after the payloads of Podman 4.9 and Docker 28, not recorded from a
+// daemon. List endpoints apply label filters like the daemon does.
+type fixtureServer struct {
+	*httptest.Server
+	libpodCalls atomic.Int32
+}
+
+func newFixtureServer(t *testing.T, dir string) *fixtureServer {
+	t.Helper()
+	fs := &fixtureServer{}
+	load := func(name string, v any) bool {
+		b, err := os.ReadFile(filepath.Join("testdata", dir, name))
+		if os.IsNotExist(err) {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatalf("bad fixture %s/%s: %v", dir, name, err)
+		}
+		return true
+	}
+
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var body any
+		switch apiVersionPrefix.ReplaceAllString(r.URL.Path, "") {
+		case "/_ping":
+			w.Header().Set("Api-Version", "1.41")
+			w.WriteHeader(http.StatusOK)
+			return
+		case "/version":
+			var v types.Version
+			load("version.json", &v)
+			body = v
+		case "/containers/json":
+			var all, out []container.Summary
+			load("containers.json", &all)
+			for _, c := range all {
+				if args.MatchKVList("label", c.Labels) {
+					out = append(out, c)
+				}
+			}
+			body = out
+		case "/volumes":
+			var all volume.ListResponse
+			load("volumes.json", &all)
+			out := volume.ListResponse{Volumes: []*volume.Volume{}}
+			for _, v := range all.Volumes {
+				if args.MatchKVList("label", v.Labels) {
+					out.Volumes = append(out.Volumes, v)
+				}
+			}
+			body = out
+		case "/networks":
+			var all, out []network.Summary
+			load("networks.json", &all)
+			for _, n := range all {
+				if args.MatchKVList("label", n.Labels) {
+					out = append(out, n)
+				}
+			}
+			body = out
+		case "/libpod/containers/json":
+			fs.libpodCalls.Add(1)
+			var raw json.RawMessage
+			if !load("libpod_containers.json", &raw) {
+				http.NotFound(w, r)
+				return
+			}
+			body = raw
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// newFixtureSource connects a DockerLabelSource to the fixture server over tcp
+func newFixtureSource(t *testing.T, srv *fixtureServer) *DockerLabelSource {
+	t.Helper()
+	src, err := New(dockerconn.Config{Host: "tcp://" + srv.Listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return src
+}
+
+func TestDetectBackend(t *testing.T) {
+	for dir, want := range map[string]Backend{"podman": BackendPodman, "docker": BackendDocker} {
+		src := newFixtureSource(t, newFixtureServer(t, dir))
+		got, err := src.Backend(context.Background())
+		if err != nil {
+			t.Fatalf("Backend(%s) failed: %v", dir, err)
+		}
+		if got != want {
+			t.Errorf("Backend(%s) = %s, want %s", dir, got, want)
+		}
+	}
+}
+
+func TestSnapshot_Podman(t *testing.T) {
+	srv := newFixtureServer(t, "podman")
+	src := newFixtureSource(t, srv)
+
+	snap, err := src.Snapshot(context.Background(), ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}})
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	byName := make(map[string]dlabels.LabeledEntity)
+	var names []string
+	for _, e := range snap.Entities {
+		byName[e.Name] = e
+		names = append(names, e.Name)
+	}
+	want := []string{"api", "shop_db_1", "shop_web_1", "shop_data", "shop_default"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("entities = %v, want %v", names, want)
+	}
+
+	// podman-compose < 1.1 only sets io.podman.compose.* labels
+	web := byName["shop_web_1"].Meta
+	if web["compose.project"] != "shop" || web["compose.service"] != "web" {
+		t.Errorf("web meta = %v, expected the podman-compose project and service", web)
+	}
+	if _, ok := web[MetaPodID]; ok {
+		t.Errorf("web is not in a pod: %v", web)
+	}
+
+	api := byName["api"].Meta
+	if api[MetaPodName] != "backend" || api[MetaPodID] != "e1f0d9c8b7a65f4e3d2c1b0a9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c" {
+		t.Errorf("api meta = %v, expected its pod", api)
+	}
+	if srv.libpodCalls.Load() != 1 {
+		t.Errorf("expected 1 libpod call, got %d", srv.libpodCalls.Load())
+	}
+}
+
+func TestSnapshot_PodmanProjectFilter(t *testing.T) {
+	src := newFixtureSource(t, newFixtureServer(t, "podman"))
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}, ProjectFilter: []string{"shop"}}
+
+	snap, err := src.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var names []string
+	for _, e := range snap.Entities {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	// shop_db_1 carries both project labels and is listed once
+	want := []string{"shop_data", "shop_db_1", "shop_default", "shop_web_1"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entities = %v, want %v", names, want)
+	}
+}
+
+func TestSnapshot_DockerSkipsLibpod(t *testing.T) {
+	srv := newFixtureServer(t, "docker")
+	src := newFixtureSource(t, srv)
+	if _, err := src.Snapshot(context.Background(), ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}}); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if n := srv.libpodCalls.Load(); n != 0 {
+		t.Errorf("expected no libpod calls on Docker, got %d", n)
+	}
+}
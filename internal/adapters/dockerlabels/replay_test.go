@@ -0,0 +1,102 @@
+package dockerlabels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/simone-viozzi/bosun/internal/testutil/dockerreplay"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the replay tests")
+
+// replaySelectors are the snapshots recorded by
+// Test_Integration_DockerLabels_VolumeAndNetworkDiscovery, by fixture name.
+// Keep both in sync.
+var replaySelectors = map[string]ports.Selector{
+	"discovery": {
+		Prefixes: []string{dlabels.DefaultLabelPrefix},
+	},
+	"discovery-full": {
+		Prefixes:       []string{dlabels.DefaultLabelPrefix},
+		IncludeStopped: true,
+		Detail:         ports.DetailFull,
+	},
+}
+
+// TestSnapshot_Replay replays the fixtures recorded from each Docker API
+// version in testdata/replay/<version> and compares the snapshot entities with
+// the golden files next to them. Run with -update after recording new fixtures.
+// It is skipped until fixtures are recorded.
+func TestSnapshot_Replay(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "replay", "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		// TODO record the fixtures of API 1.41, 1.44 and 1.51 from a real daemon with make record; until then this test guards nothing
+		t.Skip("no replay fixtures in testdata/replay; record them with make record")
+	}
+	for _, path := range fixtures {
+		if strings.HasSuffix(path, ".golden.json") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		version := filepath.Base(filepath.Dir(path))
+		sel, ok := replaySelectors[name]
+		if !ok {
+			t.Errorf("%s: no selector for fixture %q", path, name)
+			continue
+		}
+		t.Run(version+"/"+name, func(t *testing.T) {
+			fixture, err := dockerreplay.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fixture.APIVersion != version {
+				t.Fatalf("fixture recorded with API %s is in directory %s", fixture.APIVersion, version)
+			}
+			cli, err := dockerreplay.NewReplayClient(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cli.Close()
+			source, err := NewFromClient(cli)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			snap, err := source.Snapshot(context.Background(), sel)
+			if err != nil {
+				t.Fatalf("Snapshot: %v", err)
+			}
+			got, err := json.MarshalIndent(snap.Entities, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(path, ".json") + ".golden.json"
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("snapshot differs from %s:\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -3,28 +3,59 @@ package dockerlabels
 import (
 	"context"
 	"slices"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerconn"
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
 	"github.com/simone-viozzi/bosun/internal/ports"
 	"golang.org/x/sync/errgroup"
 )
 
+// Labels set by Docker Compose on the entities it creates.
+const (
+	LabelComposeProject = dlabels.LabelComposeProject
+	LabelComposeService = "com.docker.compose.service"
+)
+
 // dockerClient defines the subset of Docker client methods we use
 type dockerClient interface {
 	ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error)
 	VolumeList(ctx context.Context, opts volume.ListOptions) (volume.ListResponse, error)
 	NetworkList(ctx context.Context, opts network.ListOptions) ([]network.Summary, error)
+	Events(ctx context.Context, opts events.ListOptions) (<-chan events.Message, <-chan error)
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	Ping(ctx context.Context) (types.Ping, error)
+	ServerVersion(ctx context.Context) (types.Version, error)
 }
 
 type DockerLabelSource struct {
-	CLI dockerClient
+	CLI  dockerClient
+	Pods podLister // pod membership on Podman; nil leaves pods out of Meta
+
+	images imageLabelCache
+
+	mu      sync.Mutex
+	backend Backend // detected on first use
+}
+
+// newSource wraps a connected client, reading pods through its transport.
+func newSource(cli *client.Client) (*DockerLabelSource, error) {
+	pods, err := newLibpodPods(cli)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerLabelSource{CLI: cli, Pods: pods}, nil
 }
 
 func NewFromEnv() (*DockerLabelSource, error) {
@@ -32,21 +63,67 @@ func NewFromEnv() (*DockerLabelSource, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DockerLabelSource{CLI: cli}, nil
+	return newSource(cli)
+}
+
+// NewFromClient uses an existing Docker client, e.g. one wrapping a custom transport.
+func NewFromClient(cli *client.Client) (*DockerLabelSource, error) {
+	return newSource(cli)
+}
+
+// New connects to the Docker daemon selected by cfg.
+func New(cfg dockerconn.Config) (*DockerLabelSource, error) {
+	cli, err := dockerconn.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(cli)
+}
+
+// Ping checks that the Docker daemon is reachable.
+func (d *DockerLabelSource) Ping(ctx context.Context) error {
+	_, err := d.CLI.Ping(ctx)
+	return err
 }
 
 // snapshotContainers collects containers from Docker, filters by label prefixes,
 // and returns labeled entities for containers with matching labels.
-func (s *DockerLabelSource) snapshotContainers(ctx context.Context, sel ports.Selector) ([]dlabels.LabeledEntity, error) {
-	opts := container.ListOptions{All: sel.IncludeStopped}
-	ctrs, err := s.CLI.ContainerList(ctx, opts)
+// Extra filters are added to every list call, e.g. to look up a single container.
+func (s *DockerLabelSource) snapshotContainers(ctx context.Context, sel ports.Selector, extra ...filters.KeyValuePair) ([]dlabels.LabeledEntity, error) {
+	backend, err := s.Backend(ctx)
 	if err != nil {
 		return nil, err
 	}
+	var ctrs []container.Summary
+	for _, f := range projectFilters(sel, backend, extra) {
+		opts := container.ListOptions{All: sel.IncludeStopped, Filters: f}
+		page, err := s.CLI.ContainerList(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		ctrs = append(ctrs, page...)
+	}
+	ctrs = dedupe(ctrs, func(c container.Summary) string { return c.ID })
+
+	var pods map[string]Pod
+	if backend == BackendPodman && s.Pods != nil && len(ctrs) > 0 {
+		if pods, err = s.Pods.ContainerPods(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	var out []dlabels.LabeledEntity
 	for _, c := range ctrs {
-		fl := FilterByPrefixes(c.Labels, sel.Prefixes)
+		labels, origins := c.Labels, map[string]string(nil)
+		if sel.InheritImageLabels {
+			il, err := s.imageLabels(ctx, c.ImageID)
+			if err != nil {
+				return nil, err
+			}
+			labels, origins = mergeImageLabels(il, c.Labels)
+		}
+
+		fl := FilterByPrefixes(labels, sel.Prefixes)
 		if len(fl) == 0 {
 			continue
 		}
@@ -60,13 +137,37 @@ func (s *DockerLabelSource) snapshotContainers(ctx context.Context, sel ports.Se
 			Name:   name,
 			Labels: fl,
 			Meta: map[string]string{
-				"compose.project": c.Labels["com.docker.compose.project"],
-				"compose.service": c.Labels["com.docker.compose.service"],
-				"image":           c.Image,
+				dlabels.MetaComposeProject: composeLabel(c.Labels, LabelComposeProject, LabelPodmanComposeProject),
+				dlabels.MetaComposeService: composeLabel(c.Labels, LabelComposeService, LabelPodmanComposeService),
+				dlabels.MetaImage:          c.Image,
 			},
 		}
-		if instance := c.Labels[dlabels.LabelInstance]; instance != "" {
-			ent.Meta["instance"] = instance
+		if pod, ok := pods[c.ID]; ok {
+			ent.Meta[MetaPodID] = pod.ID
+			ent.Meta[MetaPodName] = pod.Name
+		}
+		if instance := labels[dlabels.LabelInstance]; instance != "" {
+			ent.Meta[dlabels.MetaInstance] = instance
+		}
+		if sel.Detail >= ports.DetailExtended {
+			addContainerDetail(ent.Meta, c)
+		}
+		if sel.Detail >= ports.DetailFull {
+			found, err := s.addInspectDetail(ctx, ent.Meta, c.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				continue
+			}
+		}
+		for k := range origins {
+			if _, ok := fl[k]; ok {
+				ent.Meta[MetaOriginPrefix+k] = origins[k]
+			}
+		}
+		if !sel.LabelSelector.Matches(ent) {
+			continue
 		}
 		out = append(out, ent)
 	}
@@ -75,14 +176,24 @@ func (s *DockerLabelSource) snapshotContainers(ctx context.Context, sel ports.Se
 
 // snapshotVolumes collects volumes from Docker, filters by label prefixes,
 // and returns labeled entities for volumes with matching labels.
-func (s *DockerLabelSource) snapshotVolumes(ctx context.Context, sel ports.Selector) ([]dlabels.LabeledEntity, error) {
-	vl, err := s.CLI.VolumeList(ctx, volume.ListOptions{})
+// Extra filters are added to every list call, e.g. to look up a single volume.
+func (s *DockerLabelSource) snapshotVolumes(ctx context.Context, sel ports.Selector, extra ...filters.KeyValuePair) ([]dlabels.LabeledEntity, error) {
+	backend, err := s.Backend(ctx)
 	if err != nil {
 		return nil, err
 	}
+	var vols []*volume.Volume
+	for _, f := range projectFilters(sel, backend, extra) {
+		vl, err := s.CLI.VolumeList(ctx, volume.ListOptions{Filters: f})
+		if err != nil {
+			return nil, err
+		}
+		vols = append(vols, vl.Volumes...)
+	}
+	vols = dedupe(vols, func(v *volume.Volume) string { return v.Name })
 
 	var out []dlabels.LabeledEntity
-	for _, v := range vl.Volumes {
+	for _, v := range vols {
 		fl := FilterByPrefixes(v.Labels, sel.Prefixes)
 		if len(fl) == 0 {
 			continue
@@ -93,11 +204,20 @@ func (s *DockerLabelSource) snapshotVolumes(ctx context.Context, sel ports.Selec
 			Name:   v.Name,
 			Labels: fl,
 			Meta: map[string]string{
-				"driver": v.Driver,
+				dlabels.MetaDriver: v.Driver,
 			},
 		}
+		if project := composeLabel(v.Labels, LabelComposeProject, LabelPodmanComposeProject); project != "" {
+			ent.Meta[dlabels.MetaComposeProject] = project
+		}
 		if instance := v.Labels[dlabels.LabelInstance]; instance != "" {
-			ent.Meta["instance"] = instance
+			ent.Meta[dlabels.MetaInstance] = instance
+		}
+		if sel.Detail >= ports.DetailExtended {
+			addVolumeDetail(ent.Meta, v)
+		}
+		if !sel.LabelSelector.Matches(ent) {
+			continue
 		}
 		out = append(out, ent)
 	}
@@ -106,11 +226,21 @@ func (s *DockerLabelSource) snapshotVolumes(ctx context.Context, sel ports.Selec
 
 // snapshotNetworks collects networks from Docker, filters by label prefixes,
 // and returns labeled entities for networks with matching labels.
-func (s *DockerLabelSource) snapshotNetworks(ctx context.Context, sel ports.Selector) ([]dlabels.LabeledEntity, error) {
-	nets, err := s.CLI.NetworkList(ctx, network.ListOptions{})
+// Extra filters are added to every list call, e.g. to look up a single network.
+func (s *DockerLabelSource) snapshotNetworks(ctx context.Context, sel ports.Selector, extra ...filters.KeyValuePair) ([]dlabels.LabeledEntity, error) {
+	backend, err := s.Backend(ctx)
 	if err != nil {
 		return nil, err
 	}
+	var nets []network.Summary
+	for _, f := range projectFilters(sel, backend, extra) {
+		page, err := s.CLI.NetworkList(ctx, network.ListOptions{Filters: f})
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, page...)
+	}
+	nets = dedupe(nets, func(n network.Summary) string { return n.ID })
 
 	var out []dlabels.LabeledEntity
 	for _, n := range nets {
@@ -124,18 +254,57 @@ func (s *DockerLabelSource) snapshotNetworks(ctx context.Context, sel ports.Sele
 			Name:   n.Name,
 			Labels: fl,
 			Meta: map[string]string{
-				"driver": n.Driver,
-				"scope":  n.Scope,
+				dlabels.MetaDriver: n.Driver,
+				dlabels.MetaScope:  n.Scope,
 			},
 		}
+		if project := composeLabel(n.Labels, LabelComposeProject, LabelPodmanComposeProject); project != "" {
+			ent.Meta[dlabels.MetaComposeProject] = project
+		}
 		if instance := n.Labels[dlabels.LabelInstance]; instance != "" {
-			ent.Meta["instance"] = instance
+			ent.Meta[dlabels.MetaInstance] = instance
+		}
+		if !sel.LabelSelector.Matches(ent) {
+			continue
 		}
 		out = append(out, ent)
 	}
 	return out, nil
 }
 
+// projectFilters returns the project filters for every label that can carry
+// the project on backend, with the label selector pushdown and the extra
+// filters added to each element.
+func projectFilters(sel ports.Selector, backend Backend, extra []filters.KeyValuePair) []filters.Args {
+	fs := ProjectFilters(sel.ProjectFilter)
+	if len(sel.ProjectFilter) > 0 {
+		for _, key := range projectLabels(backend)[1:] {
+			fs = append(fs, projectLabelFilters(key, sel.ProjectFilter)...)
+		}
+	}
+	extra = append(SelectorFilters(sel.LabelSelector), extra...)
+	for _, f := range fs {
+		for _, kv := range extra {
+			f.Add(kv.Key, kv.Value)
+		}
+	}
+	return fs
+}
+
+// dedupe drops items whose id was already seen, as an item matching several
+// project labels is returned by several list calls.
+func dedupe[T any](items []T, id func(T) string) []T {
+	seen := make(map[string]bool, len(items))
+	return slices.DeleteFunc(items, func(it T) bool {
+		k := id(it)
+		if seen[k] {
+			return true
+		}
+		seen[k] = true
+		return false
+	})
+}
+
 // Snapshot implements the LabelSource interface
 func (d *DockerLabelSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
 	g, ctx := errgroup.WithContext(ctx)
@@ -143,18 +312,27 @@ func (d *DockerLabelSource) Snapshot(ctx context.Context, sel ports.Selector) (d
 	var containers, volumes, networks []dlabels.LabeledEntity
 
 	g.Go(func() error {
+		if !sel.WantsKind(dlabels.KindContainer) {
+			return nil
+		}
 		var err error
 		containers, err = d.snapshotContainers(ctx, sel)
 		return err
 	})
 
 	g.Go(func() error {
+		if !sel.WantsKind(dlabels.KindVolume) {
+			return nil
+		}
 		var err error
 		volumes, err = d.snapshotVolumes(ctx, sel)
 		return err
 	})
 
 	g.Go(func() error {
+		if !sel.WantsKind(dlabels.KindNetwork) {
+			return nil
+		}
 		var err error
 		networks, err = d.snapshotNetworks(ctx, sel)
 		return err
@@ -167,17 +345,7 @@ func (d *DockerLabelSource) Snapshot(ctx context.Context, sel ports.Selector) (d
 	entities := slices.Concat(containers, volumes, networks)
 
 	// Sort entities by Kind (container < volume < network), then by Name
-	kindOrder := map[dlabels.Kind]int{
-		dlabels.KindContainer: 0,
-		dlabels.KindVolume:    1,
-		dlabels.KindNetwork:   2,
-	}
-	sort.Slice(entities, func(i, j int) bool {
-		if entities[i].Kind != entities[j].Kind {
-			return kindOrder[entities[i].Kind] < kindOrder[entities[j].Kind]
-		}
-		return entities[i].Name < entities[j].Name
-	})
+	dlabels.SortEntities(entities)
 
 	return dlabels.Snapshot{
 		Entities: entities,
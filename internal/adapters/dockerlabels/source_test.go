@@ -4,12 +4,19 @@ import (
 	"context"
 	"testing"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
 	"github.com/simone-viozzi/bosun/internal/ports"
+	"reflect"
 	"sort"
+	"sync/atomic"
 )
 
 // mockDockerClient is a minimal mock that doesn't actually connect to Docker
@@ -88,6 +95,26 @@ func (m *mockDockerClient) NetworkList(ctx context.Context, opts network.ListOpt
 	}, nil
 }
 
+func (m *mockDockerClient) Events(ctx context.Context, opts events.ListOptions) (<-chan events.Message, <-chan error) {
+	return nil, nil
+}
+
+func (m *mockDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	return image.InspectResponse{}, nil
+}
+
+func (m *mockDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return container.InspectResponse{}, nil
+}
+
+func (m *mockDockerClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (m *mockDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	return types.Version{}, nil
+}
+
 func TestSnapshotContainers_MetaEnrichment(t *testing.T) {
 	source := &DockerLabelSource{CLI: &mockDockerClient{}}
 	sel := ports.Selector{
@@ -235,3 +262,171 @@ func TestEntitySorting(t *testing.T) {
 		}
 	}
 }
+
+// filteringDockerClient applies the label filters it receives, like the Docker API does,
+// and counts list calls so tests can check filtering is pushed down
+type filteringDockerClient struct {
+	calls atomic.Int32
+}
+
+func (m *filteringDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	m.calls.Add(1)
+	all := []container.Summary{
+		{ID: "c1", Names: []string{"/alpha-web-1"}, Labels: map[string]string{"bosun.test": "true", LabelComposeProject: "alpha"}},
+		{ID: "c2", Names: []string{"/beta-web-1"}, Labels: map[string]string{"bosun.test": "true", LabelComposeProject: "beta"}},
+		{ID: "c3", Names: []string{"/standalone"}, Labels: map[string]string{"bosun.test": "true"}},
+	}
+	var out []container.Summary
+	for _, c := range all {
+		if opts.Filters.MatchKVList("label", c.Labels) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *filteringDockerClient) VolumeList(ctx context.Context, opts volume.ListOptions) (volume.ListResponse, error) {
+	m.calls.Add(1)
+	all := []*volume.Volume{
+		{Name: "alpha_data", Labels: map[string]string{"bosun.test": "true", LabelComposeProject: "alpha"}},
+		{Name: "gamma_data", Labels: map[string]string{"bosun.test": "true", LabelComposeProject: "gamma"}},
+	}
+	var out volume.ListResponse
+	for _, v := range all {
+		if opts.Filters.MatchKVList("label", v.Labels) {
+			out.Volumes = append(out.Volumes, v)
+		}
+	}
+	return out, nil
+}
+
+func (m *filteringDockerClient) NetworkList(ctx context.Context, opts network.ListOptions) ([]network.Summary, error) {
+	m.calls.Add(1)
+	all := []network.Summary{
+		{ID: "n1", Name: "beta_default", Labels: map[string]string{"bosun.test": "true", LabelComposeProject: "beta"}},
+		{ID: "n2", Name: "shared", Labels: map[string]string{"bosun.test": "true"}},
+	}
+	var out []network.Summary
+	for _, n := range all {
+		if opts.Filters.MatchKVList("label", n.Labels) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (m *filteringDockerClient) Events(ctx context.Context, opts events.ListOptions) (<-chan events.Message, <-chan error) {
+	return nil, nil
+}
+
+func (m *filteringDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	return image.InspectResponse{}, nil
+}
+
+func (m *filteringDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return container.InspectResponse{}, nil
+}
+
+func (m *filteringDockerClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (m *filteringDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	return types.Version{}, nil
+}
+
+func TestSnapshot_ProjectFilter(t *testing.T) {
+	cli := &filteringDockerClient{}
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{
+		Prefixes:      []string{dlabels.DefaultLabelPrefix},
+		ProjectFilter: []string{"alpha", "beta"},
+	}
+
+	snap, err := source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	var names []string
+	for _, e := range snap.Entities {
+		names = append(names, e.Name)
+	}
+	want := []string{"alpha-web-1", "beta-web-1", "alpha_data", "beta_default"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entities = %v, want %v", names, want)
+	}
+
+	// one list call per project and per kind
+	if n := cli.calls.Load(); n != 6 {
+		t.Errorf("expected 6 list calls, got %d", n)
+	}
+}
+
+func TestSnapshot_NoProjectFilter(t *testing.T) {
+	cli := &filteringDockerClient{}
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}}
+
+	snap, err := source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(snap.Entities) != 7 {
+		t.Errorf("expected 7 entities, got %d", len(snap.Entities))
+	}
+	if n := cli.calls.Load(); n != 3 {
+		t.Errorf("expected 3 list calls, got %d", n)
+	}
+}
+
+func TestSnapshot_KindFilter(t *testing.T) {
+	cli := &filteringDockerClient{}
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{
+		Prefixes: []string{dlabels.DefaultLabelPrefix},
+		Kinds:    []dlabels.Kind{dlabels.KindVolume, dlabels.KindNetwork},
+	}
+
+	snap, err := source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	for _, e := range snap.Entities {
+		if e.Kind == dlabels.KindContainer {
+			t.Errorf("unexpected container %s", e.Name)
+		}
+	}
+	if len(snap.Entities) != 4 {
+		t.Errorf("expected 4 entities, got %d", len(snap.Entities))
+	}
+	// unselected kinds are not listed at all
+	if n := cli.calls.Load(); n != 2 {
+		t.Errorf("expected 2 list calls, got %d", n)
+	}
+}
+
+func TestSnapshot_LabelSelector(t *testing.T) {
+	source := &DockerLabelSource{CLI: &filteringDockerClient{}}
+	sel := ports.Selector{
+		Prefixes: []string{dlabels.DefaultLabelPrefix},
+		LabelSelector: dselector.Selector{
+			{Key: "bosun.test", Op: dselector.Equals, Values: []string{"true"}},
+			{Key: "meta:compose.project", Op: dselector.NotIn, Values: []string{"beta"}},
+		},
+	}
+
+	snap, err := source.Snapshot(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	var names []string
+	for _, e := range snap.Entities {
+		names = append(names, e.Name)
+	}
+	// entities without a compose project have no compose.project in Meta, so notin keeps them
+	want := []string{"alpha-web-1", "standalone", "alpha_data", "gamma_data", "shared"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("entities = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,266 @@
+package dockerlabels
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// Reconnect backoff bounds used after the event stream fails, e.g. on daemon restart.
+var (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// containerStates maps container event actions to the resulting container state.
+// Actions not listed here do not affect the watched entities.
+var containerStates = map[events.Action]string{
+	events.ActionCreate:  "created",
+	events.ActionStart:   "running",
+	events.ActionRestart: "running",
+	events.ActionUnPause: "running",
+	events.ActionPause:   "paused",
+	events.ActionDie:     "exited",
+	events.ActionStop:    "exited",
+	events.ActionDestroy: "removed",
+	events.ActionRename:  "", // the state is unchanged; update reports the new name
+}
+
+// eventKinds maps the watched Docker event types to entity kinds.
+var eventKinds = map[events.Type]dlabels.Kind{
+	events.ContainerEventType: dlabels.KindContainer,
+	events.VolumeEventType:    dlabels.KindVolume,
+	events.NetworkEventType:   dlabels.KindNetwork,
+}
+
+type watchedEntity struct {
+	entity dlabels.LabeledEntity
+	state  string
+}
+
+// watcher tracks the entities seen so far and turns Docker events into changes.
+type watcher struct {
+	src   *DockerLabelSource
+	sel   ports.Selector
+	known map[dlabels.EntityKey]watchedEntity
+
+	msgs   <-chan events.Message
+	errs   <-chan error
+	cancel context.CancelFunc
+}
+
+// Watch implements the LabelWatcher interface.
+// It subscribes to Docker events, takes an initial snapshot and emits every
+// entity as ChangeAdded, then emits changes as events arrive. When the event
+// stream fails it reconnects with backoff and resyncs from a fresh snapshot.
+func (d *DockerLabelSource) Watch(ctx context.Context, sel ports.Selector) (<-chan dlabels.Change, error) {
+	w := &watcher{src: d, sel: sel, known: make(map[dlabels.EntityKey]watchedEntity)}
+	initial, err := w.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan dlabels.Change)
+	go w.run(ctx, out, initial)
+	return out, nil
+}
+
+// connect subscribes to the event stream, then takes a snapshot and returns the
+// changes between it and the known entities. Subscribing first ensures no event
+// between the snapshot and the subscription is lost.
+func (w *watcher) connect(ctx context.Context) ([]dlabels.Change, error) {
+	evCtx, cancel := context.WithCancel(ctx)
+	opts := events.ListOptions{Filters: filters.NewArgs()}
+	for typ, kind := range eventKinds {
+		if w.sel.WantsKind(kind) {
+			opts.Filters.Add("type", string(typ))
+		}
+	}
+	msgs, errs := w.src.CLI.Events(evCtx, opts)
+
+	snap, err := w.src.Snapshot(evCtx, w.sel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w.msgs, w.errs, w.cancel = msgs, errs, cancel
+	return w.resync(snap), nil
+}
+
+// resync replaces the known entities with the snapshot and returns the differences.
+func (w *watcher) resync(snap dlabels.Snapshot) []dlabels.Change {
+	var changes []dlabels.Change
+	seen := make(map[dlabels.EntityKey]bool, len(snap.Entities))
+	for _, e := range snap.Entities {
+		key := e.Key()
+		seen[key] = true
+		if c, ok := w.update(key, &e, ""); ok {
+			c.At = snap.TakenAt
+			changes = append(changes, c)
+		}
+	}
+	for key := range w.known {
+		if seen[key] {
+			continue
+		}
+		if c, ok := w.update(key, nil, ""); ok {
+			c.At = snap.TakenAt
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// update records the current entity for key (nil when it no longer matches the
+// selector) and returns the resulting change, if any.
+func (w *watcher) update(key dlabels.EntityKey, cur *dlabels.LabeledEntity, state string) (dlabels.Change, bool) {
+	prev, known := w.known[key]
+	switch {
+	case cur == nil && !known:
+		return dlabels.Change{}, false
+	case cur == nil:
+		delete(w.known, key)
+		return dlabels.Change{Type: dlabels.ChangeRemoved, Entity: prev.entity, State: state}, true
+	}
+
+	next := watchedEntity{entity: *cur, state: state}
+	if state == "" {
+		next.state = prev.state
+	}
+	w.known[key] = next
+
+	switch {
+	case !known:
+		return dlabels.Change{Type: dlabels.ChangeAdded, Entity: *cur, State: next.state}, true
+	case !maps.Equal(prev.entity.Labels, cur.Labels):
+		return dlabels.Change{Type: dlabels.ChangeLabelsChanged, Entity: *cur, State: next.state}, true
+	case prev.entity.Name != cur.Name:
+		return dlabels.Change{Type: dlabels.ChangeRenamed, Entity: *cur, State: next.state}, true
+	case state != "" && state != prev.state:
+		return dlabels.Change{Type: dlabels.ChangeStateChanged, Entity: *cur, State: state}, true
+	}
+	return dlabels.Change{}, false
+}
+
+// handle looks up the entity an event refers to and returns the resulting change, if any.
+func (w *watcher) handle(ctx context.Context, msg events.Message) (dlabels.Change, bool, error) {
+	var (
+		found []dlabels.LabeledEntity
+		state string
+		err   error
+	)
+	kind, ok := eventKinds[msg.Type]
+	if !ok || !w.sel.WantsKind(kind) {
+		return dlabels.Change{}, false, nil
+	}
+	switch msg.Type {
+	case events.ContainerEventType:
+		if state, ok = containerStates[msg.Action]; !ok {
+			return dlabels.Change{}, false, nil
+		}
+		found, err = w.src.snapshotContainers(ctx, w.sel, filters.Arg("id", msg.Actor.ID))
+	case events.VolumeEventType:
+		if msg.Action != events.ActionCreate && msg.Action != events.ActionDestroy {
+			return dlabels.Change{}, false, nil
+		}
+		found, err = w.src.snapshotVolumes(ctx, w.sel, filters.Arg("name", msg.Actor.ID))
+	case events.NetworkEventType:
+		if msg.Action != events.ActionCreate && msg.Action != events.ActionDestroy {
+			return dlabels.Change{}, false, nil
+		}
+		found, err = w.src.snapshotNetworks(ctx, w.sel, filters.Arg("id", msg.Actor.ID))
+	default:
+		return dlabels.Change{}, false, nil
+	}
+	if err != nil {
+		return dlabels.Change{}, false, err
+	}
+
+	// Docker matches id and name filters by prefix, so pick the exact entity.
+	key := dlabels.EntityKey{Kind: kind, ID: msg.Actor.ID}
+	var cur *dlabels.LabeledEntity
+	for i := range found {
+		if found[i].ID == msg.Actor.ID {
+			cur = &found[i]
+			break
+		}
+	}
+
+	c, ok := w.update(key, cur, state)
+	if ok {
+		c.At = time.Unix(0, msg.TimeNano)
+	}
+	return c, ok, nil
+}
+
+// run emits the initial changes and then follows the event stream until ctx is done.
+func (w *watcher) run(ctx context.Context, out chan<- dlabels.Change, pending []dlabels.Change) {
+	defer close(out)
+	defer func() { w.cancel() }()
+
+	emit := func(changes ...dlabels.Change) bool {
+		for _, c := range changes {
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	if !emit(pending...) {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-w.msgs:
+			c, ok, err := w.handle(ctx, msg)
+			if err != nil {
+				// The daemon is likely going away; resync from a fresh snapshot.
+				pending, ok = w.reconnect(ctx)
+				if !ok || !emit(pending...) {
+					return
+				}
+				continue
+			}
+			if ok && !emit(c) {
+				return
+			}
+		case <-w.errs:
+			pending, ok := w.reconnect(ctx)
+			if !ok || !emit(pending...) {
+				return
+			}
+		}
+	}
+}
+
+// reconnect drops the current subscription and retries connect with exponential
+// backoff until it succeeds or ctx is done.
+func (w *watcher) reconnect(ctx context.Context) ([]dlabels.Change, bool) {
+	w.cancel()
+	delay := watchRetryMin
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		changes, err := w.connect(ctx)
+		if err == nil {
+			return changes, true
+		}
+		delay = min(delay*2, watchRetryMax)
+	}
+}
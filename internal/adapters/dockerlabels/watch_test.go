@@ -0,0 +1,299 @@
+package dockerlabels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// eventDockerClient is a mutable mock that serves list calls from its current
+// state and hands out a fresh event stream on every Events call
+type eventDockerClient struct {
+	mu         sync.Mutex
+	containers []container.Summary
+	volumes    []*volume.Volume
+	networks   []network.Summary
+	msgs       chan events.Message
+	errs       chan error
+	subscribed chan struct{}
+}
+
+func newEventDockerClient() *eventDockerClient {
+	return &eventDockerClient{subscribed: make(chan struct{}, 8)}
+}
+
+func matchID(f filters.Args, key, id string) bool {
+	vals := f.Get(key)
+	if len(vals) == 0 {
+		return true
+	}
+	for _, v := range vals {
+		if strings.HasPrefix(id, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *eventDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []container.Summary
+	for _, c := range m.containers {
+		if !opts.All && c.State != "running" {
+			continue
+		}
+		if matchID(opts.Filters, "id", c.ID) && opts.Filters.MatchKVList("label", c.Labels) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *eventDockerClient) VolumeList(ctx context.Context, opts volume.ListOptions) (volume.ListResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out volume.ListResponse
+	for _, v := range m.volumes {
+		if matchID(opts.Filters, "name", v.Name) && opts.Filters.MatchKVList("label", v.Labels) {
+			out.Volumes = append(out.Volumes, v)
+		}
+	}
+	return out, nil
+}
+
+func (m *eventDockerClient) NetworkList(ctx context.Context, opts network.ListOptions) ([]network.Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []network.Summary
+	for _, n := range m.networks {
+		if matchID(opts.Filters, "id", n.ID) && opts.Filters.MatchKVList("label", n.Labels) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (m *eventDockerClient) Events(ctx context.Context, opts events.ListOptions) (<-chan events.Message, <-chan error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.msgs = make(chan events.Message)
+	m.errs = make(chan error, 1)
+	m.subscribed <- struct{}{}
+	return m.msgs, m.errs
+}
+
+func (m *eventDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	return image.InspectResponse{}, nil
+}
+
+func (m *eventDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return container.InspectResponse{}, nil
+}
+
+func (m *eventDockerClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (m *eventDockerClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	return types.Version{}, nil
+}
+
+func (m *eventDockerClient) send(t *testing.T, msg events.Message) {
+	t.Helper()
+	m.mu.Lock()
+	msgs := m.msgs
+	m.mu.Unlock()
+	select {
+	case msgs <- msg:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("event %s/%s not consumed", msg.Type, msg.Action)
+	}
+}
+
+func (m *eventDockerClient) setContainerState(id, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.containers {
+		if m.containers[i].ID == id {
+			m.containers[i].State = state
+		}
+	}
+}
+
+func nextChange(t *testing.T, ch <-chan dlabels.Change) dlabels.Change {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		if !ok {
+			t.Fatal("change channel closed unexpectedly")
+		}
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+	return dlabels.Change{}
+}
+
+func TestWatch_ContainerLifecycle(t *testing.T) {
+	cli := newEventDockerClient()
+	cli.volumes = []*volume.Volume{{Name: "data", Labels: map[string]string{"bosun.backup": "daily"}}}
+	cli.containers = []container.Summary{
+		{ID: "c1", Names: []string{"/web"}, State: "created", Labels: map[string]string{"bosun.role": "web"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &DockerLabelSource{CLI: cli}
+	ch, err := source.Watch(ctx, ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	if c := nextChange(t, ch); c.Type != dlabels.ChangeAdded || c.Entity.Name != "data" {
+		t.Fatalf("expected initial volume added, got %+v", c)
+	}
+
+	cli.setContainerState("c1", "running")
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c1"}})
+	c := nextChange(t, ch)
+	if c.Type != dlabels.ChangeAdded || c.Entity.Name != "web" || c.State != "running" {
+		t.Fatalf("expected container added running, got %+v", c)
+	}
+
+	// Exec events do not affect the entity set
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionExecStart, Actor: events.Actor{ID: "c1"}})
+
+	cli.setContainerState("c1", "exited")
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionDie, Actor: events.Actor{ID: "c1"}})
+	c = nextChange(t, ch)
+	if c.Type != dlabels.ChangeRemoved || c.Entity.ID != "c1" {
+		t.Fatalf("expected container removed, got %+v", c)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestWatch_StateChangedWithStopped(t *testing.T) {
+	cli := newEventDockerClient()
+	cli.containers = []container.Summary{
+		{ID: "c1", Names: []string{"/web"}, State: "running", Labels: map[string]string{"bosun.role": "web"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &DockerLabelSource{CLI: cli}
+	sel := ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}, IncludeStopped: true}
+	ch, err := source.Watch(ctx, sel)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	nextChange(t, ch)
+
+	cli.setContainerState("c1", "exited")
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionDie, Actor: events.Actor{ID: "c1"}})
+	c := nextChange(t, ch)
+	if c.Type != dlabels.ChangeStateChanged || c.State != "exited" {
+		t.Fatalf("expected state change to exited, got %+v", c)
+	}
+
+	// A stop following die does not repeat the state change
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionStop, Actor: events.Actor{ID: "c1"}})
+	cli.setContainerState("c1", "running")
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionStart, Actor: events.Actor{ID: "c1"}})
+	c = nextChange(t, ch)
+	if c.Type != dlabels.ChangeStateChanged || c.State != "running" {
+		t.Fatalf("expected state change to running, got %+v", c)
+	}
+}
+
+func TestWatch_Rename(t *testing.T) {
+	cli := newEventDockerClient()
+	cli.containers = []container.Summary{
+		{ID: "c1", Names: []string{"/web"}, State: "running", Labels: map[string]string{"bosun.role": "web"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &DockerLabelSource{CLI: cli}
+	ch, err := source.Watch(ctx, ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	nextChange(t, ch)
+
+	cli.mu.Lock()
+	cli.containers[0].Names = []string{"/web-old"}
+	cli.mu.Unlock()
+	cli.send(t, events.Message{Type: events.ContainerEventType, Action: events.ActionRename, Actor: events.Actor{ID: "c1"}})
+	c := nextChange(t, ch)
+	if c.Type != dlabels.ChangeRenamed || c.Entity.Name != "web-old" {
+		t.Fatalf("expected container renamed to web-old, got %+v", c)
+	}
+}
+
+func TestWatch_ResyncAfterStreamError(t *testing.T) {
+	prevMin := watchRetryMin
+	watchRetryMin = time.Millisecond
+	defer func() { watchRetryMin = prevMin }()
+
+	cli := newEventDockerClient()
+	cli.volumes = []*volume.Volume{{Name: "data", Labels: map[string]string{"bosun.backup": "daily"}}}
+	cli.networks = []network.Summary{{ID: "n1", Name: "front", Labels: map[string]string{"bosun.zone": "dmz"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &DockerLabelSource{CLI: cli}
+	ch, err := source.Watch(ctx, ports.Selector{Prefixes: []string{dlabels.DefaultLabelPrefix}})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	nextChange(t, ch)
+	nextChange(t, ch)
+	<-cli.subscribed
+
+	// Simulate a daemon restart during which the volume was recreated and the network removed
+	cli.mu.Lock()
+	cli.volumes = []*volume.Volume{{Name: "data", Labels: map[string]string{"bosun.backup": "weekly"}}}
+	cli.networks = nil
+	cli.errs <- errors.New("unexpected EOF")
+	cli.mu.Unlock()
+
+	got := map[dlabels.ChangeType]string{}
+	for range 2 {
+		c := nextChange(t, ch)
+		got[c.Type] = c.Entity.Name
+	}
+	if got[dlabels.ChangeLabelsChanged] != "data" {
+		t.Errorf("expected labels-changed for volume data, got %v", got)
+	}
+	if got[dlabels.ChangeRemoved] != "front" {
+		t.Errorf("expected removed for network front, got %v", got)
+	}
+
+	select {
+	case <-cli.subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher did not resubscribe")
+	}
+}
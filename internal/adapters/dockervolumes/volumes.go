@@ -0,0 +1,210 @@
+// Package dockervolumes archives, restores and manages Docker volumes.
+package dockervolumes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerconn"
+	dvolume "github.com/simone-viozzi/bosun/internal/domain/volume"
+)
+
+// DefaultHelperImage is the image of the throwaway containers used to access volumes.
+const DefaultHelperImage = "busybox:latest"
+
+// mountPoint is where helper containers mount the volume. Archives are rooted
+// at its base name, i.e. every entry starts with "volume/" as the archive
+// layout of dvolume expects.
+const mountPoint = "/" + dvolume.ContentDir
+
+// dockerClient defines the subset of Docker client methods we use
+type dockerClient interface {
+	ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerRemove(ctx context.Context, containerID string, opts container.RemoveOptions) error
+	ContainerStart(ctx context.Context, containerID string, opts container.StartOptions) error
+	ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error)
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, opts container.CopyToContainerOptions) error
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImagePull(ctx context.Context, ref string, opts image.PullOptions) (io.ReadCloser, error)
+	VolumeCreate(ctx context.Context, opts volume.CreateOptions) (volume.Volume, error)
+	VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+}
+
+// DockerVolumes implements ports.VolumeStore.
+type DockerVolumes struct {
+	CLI         dockerClient
+	HelperImage string // defaults to DefaultHelperImage
+}
+
+func NewFromEnv() (*DockerVolumes, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	return &DockerVolumes{CLI: cli}, nil
+}
+
+// New connects to the Docker daemon selected by cfg.
+func New(cfg dockerconn.Config) (*DockerVolumes, error) {
+	cli, err := dockerconn.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerVolumes{CLI: cli}, nil
+}
+
+func (d *DockerVolumes) helperImage() string {
+	if d.HelperImage != "" {
+		return d.HelperImage
+	}
+	return DefaultHelperImage
+}
+
+// ensureImage pulls the helper image if it is not present locally.
+func (d *DockerVolumes) ensureImage(ctx context.Context) error {
+	ref := d.helperImage()
+	if _, err := d.CLI.ImageInspect(ctx, ref); err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	rc, err := d.CLI.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull helper image %s: %w", ref, err)
+	}
+	defer rc.Close()
+	// The pull only completes once the progress stream is drained
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
+
+// withHelper creates a helper container mounting volume at mountPoint, calls fn
+// with its ID and removes it afterwards. The container only runs when started
+// by fn: the archive endpoints work on created containers.
+func (d *DockerVolumes) withHelper(ctx context.Context, volume string, readOnly bool, cmd []string, fn func(id string) error) error {
+	if err := d.ensureImage(ctx); err != nil {
+		return err
+	}
+
+	bind := volume + ":" + mountPoint
+	if readOnly {
+		bind += ":ro"
+	}
+	resp, err := d.CLI.ContainerCreate(ctx,
+		&container.Config{Image: d.helperImage(), Cmd: cmd},
+		&container.HostConfig{Binds: []string{bind}},
+		nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to create helper container for %s: %w", volume, err)
+	}
+	defer func() {
+		// Remove even when ctx is cancelled, so no helper is left behind
+		_ = d.CLI.ContainerRemove(context.WithoutCancel(ctx), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	return fn(resp.ID)
+}
+
+// Archive implements the VolumeArchiver interface.
+func (d *DockerVolumes) Archive(ctx context.Context, volume string, w io.Writer) error {
+	return d.withHelper(ctx, volume, true, nil, func(id string) error {
+		rc, _, err := d.CLI.CopyFromContainer(ctx, id, mountPoint)
+		if err != nil {
+			return fmt.Errorf("failed to read volume %s: %w", volume, err)
+		}
+		defer rc.Close()
+		if _, err := io.Copy(w, rc); err != nil {
+			return fmt.Errorf("failed to archive volume %s: %w", volume, err)
+		}
+		return nil
+	})
+}
+
+// Extract implements the VolumeStore interface. The helper first empties the
+// volume, then the archive is unpacked at the root of its filesystem, so entries
+// under "volume/" land in the mounted volume.
+func (d *DockerVolumes) Extract(ctx context.Context, name string, r io.Reader) error {
+	empty := []string{"find", mountPoint, "-mindepth", "1", "-delete"}
+	return d.withHelper(ctx, name, false, empty, func(id string) error {
+		if err := d.run(ctx, id); err != nil {
+			return fmt.Errorf("failed to empty volume %s: %w", name, err)
+		}
+		if err := d.CLI.CopyToContainer(ctx, id, "/", r, container.CopyToContainerOptions{}); err != nil {
+			return fmt.Errorf("failed to restore volume %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// run starts a helper container and waits for its command to succeed.
+func (d *DockerVolumes) run(ctx context.Context, id string) error {
+	waitC, errC := d.CLI.ContainerWait(ctx, id, container.WaitConditionNextExit)
+	if err := d.CLI.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-waitC:
+		if resp.Error != nil {
+			return errors.New(resp.Error.Message)
+		}
+		if resp.StatusCode != 0 {
+			return fmt.Errorf("helper exited with status %d", resp.StatusCode)
+		}
+		return nil
+	case err := <-errC:
+		return err
+	}
+}
+
+// Inspect implements the VolumeStore interface.
+func (d *DockerVolumes) Inspect(ctx context.Context, name string) (dvolume.Volume, bool, error) {
+	v, err := d.CLI.VolumeInspect(ctx, name)
+	if client.IsErrNotFound(err) {
+		return dvolume.Volume{}, false, nil
+	}
+	if err != nil {
+		return dvolume.Volume{}, false, err
+	}
+	return dvolume.Volume{Name: v.Name, Driver: v.Driver, Labels: v.Labels, Options: v.Options}, true, nil
+}
+
+// Create implements the VolumeStore interface.
+func (d *DockerVolumes) Create(ctx context.Context, v dvolume.Volume) error {
+	_, err := d.CLI.VolumeCreate(ctx, volume.CreateOptions{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		DriverOpts: v.Options,
+		Labels:     v.Labels,
+	})
+	return err
+}
+
+// Remove implements the VolumeStore interface.
+func (d *DockerVolumes) Remove(ctx context.Context, name string) error {
+	return d.CLI.VolumeRemove(ctx, name, false)
+}
+
+// InUse implements the VolumeStore interface.
+func (d *DockerVolumes) InUse(ctx context.Context, name string) (bool, error) {
+	ctrs, err := d.CLI.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("volume", name)),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(ctrs) > 0, nil
+}
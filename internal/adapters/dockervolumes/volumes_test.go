@@ -0,0 +1,227 @@
+package dockervolumes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	dvolume "github.com/simone-viozzi/bosun/internal/domain/volume"
+)
+
+// mockDockerClient records the calls made by DockerVolumes
+type mockDockerClient struct {
+	calls      []string
+	imageFound bool
+	binds      []string
+	copyErr    error
+	listOpts   container.ListOptions
+	cmd        []string
+	exitCode   int64
+	copied     string
+	volumes    map[string]volume.Volume
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+func (notFoundError) NotFound()     {}
+
+func (m *mockDockerClient) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	m.listOpts = opts
+	return []container.Summary{{ID: "c1"}, {ID: "c2"}}, nil
+}
+
+func (m *mockDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	m.calls = append(m.calls, "create "+config.Image)
+	m.binds = hostConfig.Binds
+	m.cmd = config.Cmd
+	return container.CreateResponse{ID: "helper"}, nil
+}
+
+func (m *mockDockerClient) ContainerRemove(ctx context.Context, containerID string, opts container.RemoveOptions) error {
+	m.calls = append(m.calls, "remove "+containerID)
+	return nil
+}
+
+func (m *mockDockerClient) ContainerStart(ctx context.Context, containerID string, opts container.StartOptions) error {
+	m.calls = append(m.calls, "start "+containerID)
+	return nil
+}
+
+func (m *mockDockerClient) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	waitC := make(chan container.WaitResponse, 1)
+	waitC <- container.WaitResponse{StatusCode: m.exitCode}
+	return waitC, make(chan error)
+}
+
+func (m *mockDockerClient) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, opts container.CopyToContainerOptions) error {
+	m.calls = append(m.calls, "copy-to "+containerID+":"+dstPath)
+	b, err := io.ReadAll(content)
+	m.copied = string(b)
+	return err
+}
+
+func (m *mockDockerClient) VolumeCreate(ctx context.Context, opts volume.CreateOptions) (volume.Volume, error) {
+	m.calls = append(m.calls, "volume-create "+opts.Name)
+	v := volume.Volume{Name: opts.Name, Driver: opts.Driver, Labels: opts.Labels, Options: opts.DriverOpts}
+	m.volumes[opts.Name] = v
+	return v, nil
+}
+
+func (m *mockDockerClient) VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error) {
+	v, ok := m.volumes[volumeID]
+	if !ok {
+		return volume.Volume{}, notFoundError{}
+	}
+	return v, nil
+}
+
+func (m *mockDockerClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	m.calls = append(m.calls, "volume-remove "+volumeID)
+	delete(m.volumes, volumeID)
+	return nil
+}
+
+func (m *mockDockerClient) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	m.calls = append(m.calls, "copy "+containerID+":"+srcPath)
+	if m.copyErr != nil {
+		return nil, container.PathStat{}, m.copyErr
+	}
+	return io.NopCloser(strings.NewReader("tar-bytes")), container.PathStat{}, nil
+}
+
+func (m *mockDockerClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	if !m.imageFound {
+		return image.InspectResponse{}, notFoundError{}
+	}
+	return image.InspectResponse{}, nil
+}
+
+func (m *mockDockerClient) ImagePull(ctx context.Context, ref string, opts image.PullOptions) (io.ReadCloser, error) {
+	m.calls = append(m.calls, "pull "+ref)
+	m.imageFound = true
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+func TestArchive(t *testing.T) {
+	cli := &mockDockerClient{}
+	d := &DockerVolumes{CLI: cli}
+
+	var buf bytes.Buffer
+	if err := d.Archive(context.Background(), "app_data", &buf); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if buf.String() != "tar-bytes" {
+		t.Errorf("archive = %q, want tar-bytes", buf.String())
+	}
+
+	want := []string{"pull busybox:latest", "create busybox:latest", "copy helper:/volume", "remove helper"}
+	if strings.Join(cli.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", cli.calls, want)
+	}
+	if len(cli.binds) != 1 || cli.binds[0] != "app_data:/volume:ro" {
+		t.Errorf("binds = %v, want read-only mount of app_data", cli.binds)
+	}
+}
+
+func TestArchive_RemovesHelperOnError(t *testing.T) {
+	cli := &mockDockerClient{imageFound: true, copyErr: errors.New("boom")}
+	d := &DockerVolumes{CLI: cli, HelperImage: "alpine:3"}
+
+	if err := d.Archive(context.Background(), "app_data", io.Discard); err == nil {
+		t.Fatal("expected an error")
+	}
+	if last := cli.calls[len(cli.calls)-1]; last != "remove helper" {
+		t.Errorf("last call = %q, want helper removal", last)
+	}
+	if cli.calls[0] != "create alpine:3" {
+		t.Errorf("expected the custom helper image without a pull, got %v", cli.calls)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	cli := &mockDockerClient{imageFound: true}
+	d := &DockerVolumes{CLI: cli}
+
+	if err := d.Extract(context.Background(), "app_data", strings.NewReader("tar-bytes")); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	want := []string{"create busybox:latest", "start helper", "copy-to helper:/", "remove helper"}
+	if strings.Join(cli.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", cli.calls, want)
+	}
+	if len(cli.binds) != 1 || cli.binds[0] != "app_data:/volume" {
+		t.Errorf("binds = %v, want writable mount of app_data", cli.binds)
+	}
+	if len(cli.cmd) == 0 || cli.cmd[0] != "find" {
+		t.Errorf("cmd = %v, want the volume to be emptied first", cli.cmd)
+	}
+	if cli.copied != "tar-bytes" {
+		t.Errorf("copied = %q, want tar-bytes", cli.copied)
+	}
+}
+
+func TestExtract_EmptyFails(t *testing.T) {
+	cli := &mockDockerClient{imageFound: true, exitCode: 1}
+	d := &DockerVolumes{CLI: cli}
+
+	if err := d.Extract(context.Background(), "app_data", strings.NewReader("tar-bytes")); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, c := range cli.calls {
+		if strings.HasPrefix(c, "copy-to") {
+			t.Errorf("expected no copy after a failed cleanup, got %v", cli.calls)
+		}
+	}
+	if last := cli.calls[len(cli.calls)-1]; last != "remove helper" {
+		t.Errorf("last call = %q, want helper removal", last)
+	}
+}
+
+func TestInspectAndCreate(t *testing.T) {
+	cli := &mockDockerClient{volumes: map[string]volume.Volume{}}
+	d := &DockerVolumes{CLI: cli}
+	ctx := context.Background()
+
+	if _, ok, err := d.Inspect(ctx, "app_data"); err != nil || ok {
+		t.Fatalf("Inspect of a missing volume = %v, %v, want not found", ok, err)
+	}
+
+	want := dvolume.Volume{Name: "app_data", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+	if err := d.Create(ctx, want); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	got, ok, err := d.Inspect(ctx, "app_data")
+	if err != nil || !ok {
+		t.Fatalf("Inspect = %v, %v, want found", ok, err)
+	}
+	if got.Driver != "local" || got.Labels["bosun.backup"] != "daily" {
+		t.Errorf("Inspect = %+v, want %+v", got, want)
+	}
+}
+
+func TestInUse(t *testing.T) {
+	cli := &mockDockerClient{}
+	d := &DockerVolumes{CLI: cli}
+
+	inUse, err := d.InUse(context.Background(), "app_data")
+	if err != nil {
+		t.Fatalf("InUse failed: %v", err)
+	}
+	if !inUse {
+		t.Error("expected the volume to be in use")
+	}
+	if !cli.listOpts.All {
+		t.Error("expected stopped containers to be considered")
+	}
+}
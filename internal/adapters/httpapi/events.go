@@ -0,0 +1,269 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// DefaultEventBuffer is the number of changes kept for resuming clients.
+const DefaultEventBuffer = 1024
+
+const (
+	// eventSnapshot names the SSE event carrying the full filtered state;
+	// change events are named after their dlabels.ChangeType.
+	eventSnapshot = "snapshot"
+	// keepAliveInterval is how often an idle stream sends a comment, so
+	// proxies do not time the connection out.
+	keepAliveInterval = 15 * time.Second
+)
+
+// sequencedChange is an upstream change with its position in the stream.
+type sequencedChange struct {
+	seq    uint64
+	change dlabels.Change
+}
+
+// eventHub follows a single upstream watch and lets any number of clients read
+// it from a position. It keeps the last changes in a ring so reconnecting
+// clients can resume, together with the state before the oldest of them, from
+// which the state at any buffered position is rebuilt.
+type eventHub struct {
+	epoch string // distinguishes event IDs of different server runs
+	size  int
+
+	mu     sync.Mutex
+	seq    uint64
+	base   map[dlabels.EntityKey]dlabels.LabeledEntity // state before buf[0]
+	buf    []sequencedChange
+	notify map[chan struct{}]struct{}
+	done   chan struct{}
+}
+
+// upstreamSelector is watched once for all clients, which filter it down with
+// dlabels.Filter. The empty prefix keeps every label; project filters match the
+// resolved compose project meta, so they work for Podman entities too.
+var upstreamSelector = ports.Selector{Prefixes: []string{""}}
+
+func newEventHub(size int) *eventHub {
+	if size <= 0 {
+		size = DefaultEventBuffer
+	}
+	return &eventHub{
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:   size,
+		base:   make(map[dlabels.EntityKey]dlabels.LabeledEntity),
+		notify: make(map[chan struct{}]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// run consumes the upstream changes until the channel is closed.
+func (h *eventHub) run(ch <-chan dlabels.Change) {
+	defer close(h.done)
+	for c := range ch {
+		h.publish(c)
+	}
+}
+
+func (h *eventHub) publish(c dlabels.Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	h.buf = append(h.buf, sequencedChange{seq: h.seq, change: c})
+	if len(h.buf) > h.size {
+		applyChange(h.base, h.buf[0].change)
+		h.buf = slices.Delete(h.buf, 0, 1)
+	}
+	for n := range h.notify {
+		select {
+		case n <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribe registers a channel signalled after every published change.
+func (h *eventHub) subscribe() (<-chan struct{}, func()) {
+	n := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.notify[n] = struct{}{}
+	h.mu.Unlock()
+	return n, func() {
+		h.mu.Lock()
+		delete(h.notify, n)
+		h.mu.Unlock()
+	}
+}
+
+// read returns the changes after position from or, when resync is set or from
+// is no longer buffered, the full state at the latest position.
+func (h *eventHub) read(from uint64, resync bool) (state map[dlabels.EntityKey]dlabels.LabeledEntity, changes []sequencedChange, last uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	oldest := h.seq - uint64(len(h.buf)) // position of base
+	if resync || from < oldest || from > h.seq {
+		state = maps.Clone(h.base)
+		for _, sc := range h.buf {
+			applyChange(state, sc.change)
+		}
+		return state, nil, h.seq
+	}
+	return nil, slices.Clone(h.buf[from-oldest:]), h.seq
+}
+
+// stateAt rebuilds the state at a buffered position.
+func (h *eventHub) stateAt(pos uint64) map[dlabels.EntityKey]dlabels.LabeledEntity {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := maps.Clone(h.base)
+	for _, sc := range h.buf {
+		if sc.seq > pos {
+			break
+		}
+		applyChange(state, sc.change)
+	}
+	return state
+}
+
+// applyChange updates an unfiltered state with an upstream change.
+func applyChange(state map[dlabels.EntityKey]dlabels.LabeledEntity, c dlabels.Change) {
+	if c.Type == dlabels.ChangeRemoved {
+		delete(state, c.Entity.Key())
+		return
+	}
+	state[c.Entity.Key()] = c.Entity
+}
+
+// eventID formats a position as an SSE event ID.
+func (h *eventHub) eventID(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseEventID returns the position of an event ID of this run.
+func (h *eventHub) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// handleEvents streams label changes as Server-Sent Events. A new client first
+// receives a "snapshot" event, then one event per change named after its type.
+// A client reconnecting with Last-Event-ID (or the lastEventId parameter)
+// resumes after that event, or receives a fresh snapshot if it is too old.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if s.hub == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("event stream not started"))
+		return
+	}
+	q := r.URL.Query()
+	if err := checkParams(q, "prefix", "project", "kind", "selector", "lastEventId"); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("lastEventId")
+	}
+	q.Del("lastEventId")
+	sel, err := SelectorFromQuery(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	pos, resume := s.hub.parseEventID(lastID)
+
+	notify, unsubscribe := s.hub.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	filter := dlabels.Filter{Prefixes: sel.Prefixes, Kinds: sel.Kinds, Projects: sel.ProjectFilter}
+	if !sel.LabelSelector.Empty() {
+		filter.Match = sel.LabelSelector.Matches
+	}
+	view := dlabels.NewView(filter)
+	if resume {
+		// Seed the view with what the client saw up to its last event
+		for _, e := range s.hub.stateAt(pos) {
+			view.Apply(dlabels.Change{Type: dlabels.ChangeAdded, Entity: e})
+		}
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	resync := !resume
+	for {
+		state, changes, last := s.hub.read(pos, resync)
+		resync = false
+		if state != nil {
+			view = dlabels.NewView(filter)
+			for _, e := range state {
+				view.Apply(dlabels.Change{Type: dlabels.ChangeAdded, Entity: e})
+			}
+			snap := dlabels.Snapshot{Entities: view.Entities(), TakenAt: time.Now()}
+			if err := writeEvent(w, s.hub.eventID(last), eventSnapshot, snap); err != nil {
+				return
+			}
+		}
+		for _, sc := range changes {
+			c, ok := view.Apply(sc.change)
+			if !ok {
+				continue
+			}
+			if err := writeEvent(w, s.hub.eventID(sc.seq), string(c.Type), c); err != nil {
+				return
+			}
+		}
+		pos = last
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.hub.done:
+			return
+		case <-notify:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes one SSE event with a JSON payload.
+func writeEvent(w http.ResponseWriter, id, event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, b)
+	return err
+}
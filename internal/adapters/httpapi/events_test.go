@@ -0,0 +1,335 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// fakeWatcher hands out a channel the test publishes upstream changes on
+type fakeWatcher struct {
+	ch  chan dlabels.Change
+	sel ports.Selector
+}
+
+func (f *fakeWatcher) Watch(ctx context.Context, sel ports.Selector) (<-chan dlabels.Change, error) {
+	f.sel = sel
+	out := make(chan dlabels.Change)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case c := <-f.ch:
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// sseClient reads events from a streaming response
+type sseClient struct {
+	t    *testing.T
+	resp *http.Response
+	evs  chan sseEvent
+}
+
+func openEvents(t *testing.T, url, lastID string) *sseClient {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status = %d", url, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	c := &sseClient{t: t, resp: resp, evs: make(chan sseEvent, 16)}
+	go func() {
+		defer close(c.evs)
+		sc := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev.event != "" {
+					c.evs <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return c
+}
+
+func (c *sseClient) next() sseEvent {
+	c.t.Helper()
+	select {
+	case ev, ok := <-c.evs:
+		if !ok {
+			c.t.Fatal("stream closed unexpectedly")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		c.t.Fatal("timed out waiting for event")
+	}
+	return sseEvent{}
+}
+
+func (c *sseClient) close() { c.resp.Body.Close() }
+
+// change builds an upstream change, resolving the compose project meta from
+// the labels like the Docker source does
+func change(typ dlabels.ChangeType, kind dlabels.Kind, id string, labels map[string]string) dlabels.Change {
+	e := dlabels.LabeledEntity{Kind: kind, ID: id, Name: id, Labels: labels}
+	if project := labels[dlabels.LabelComposeProject]; project != "" {
+		e.Meta = map[string]string{dlabels.MetaComposeProject: project}
+	}
+	return dlabels.Change{Type: typ, Entity: e}
+}
+
+// startEvents serves a Server with a fake watcher and returns a function
+// publishing an upstream change and waiting until the hub has it
+func startEvents(t *testing.T, buffer int) (*httptest.Server, func(dlabels.Change)) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	w := &fakeWatcher{ch: make(chan dlabels.Change)}
+	s := &Server{Source: &fakeSource{}, Watcher: w, EventBuffer: buffer}
+	ch, _ := w.Watch(ctx, upstreamSelector)
+	s.hub = newEventHub(buffer)
+	go s.hub.run(ch)
+
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+
+	publish := func(c dlabels.Change) {
+		t.Helper()
+		s.hub.mu.Lock()
+		want := s.hub.seq + 1
+		s.hub.mu.Unlock()
+		w.ch <- c
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			s.hub.mu.Lock()
+			seq := s.hub.seq
+			s.hub.mu.Unlock()
+			if seq >= want {
+				return
+			}
+		}
+		t.Fatal("change not published")
+	}
+	return srv, publish
+}
+
+func TestEvents_SnapshotThenFilteredChanges(t *testing.T) {
+	srv, publish := startEvents(t, 0)
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web", dlabels.LabelComposeProject: "shop"}))
+	publish(change(dlabels.ChangeAdded, dlabels.KindVolume, "data", map[string]string{"bosun.backup": "daily", dlabels.LabelComposeProject: "shop"}))
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "blog", map[string]string{"bosun.role": "web", dlabels.LabelComposeProject: "blog"}))
+
+	c := openEvents(t, srv.URL+"/v1/events?kind=container&project=shop", "")
+	defer c.close()
+
+	ev := c.next()
+	if ev.event != "snapshot" {
+		t.Fatalf("first event = %q, want snapshot", ev.event)
+	}
+	var snap dlabels.Snapshot
+	if err := json.Unmarshal([]byte(ev.data), &snap); err != nil {
+		t.Fatalf("invalid snapshot: %v", err)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].ID != "web" {
+		t.Fatalf("snapshot = %+v, want only container web", snap.Entities)
+	}
+	if _, ok := snap.Entities[0].Labels[dlabels.LabelComposeProject]; ok {
+		t.Error("expected labels outside the default prefix to be dropped")
+	}
+
+	// Not selected: a volume, another project, a label outside the prefix
+	publish(change(dlabels.ChangeRemoved, dlabels.KindVolume, "data", nil))
+	publish(change(dlabels.ChangeLabelsChanged, dlabels.KindContainer, "blog", map[string]string{"bosun.role": "api", dlabels.LabelComposeProject: "blog"}))
+	publish(change(dlabels.ChangeLabelsChanged, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web", "traefik.enable": "true", dlabels.LabelComposeProject: "shop"}))
+	publish(change(dlabels.ChangeLabelsChanged, dlabels.KindContainer, "web", map[string]string{"bosun.role": "api", dlabels.LabelComposeProject: "shop"}))
+
+	ev = c.next()
+	if ev.event != string(dlabels.ChangeLabelsChanged) {
+		t.Fatalf("event = %q, want labels-changed", ev.event)
+	}
+	var got dlabels.Change
+	if err := json.Unmarshal([]byte(ev.data), &got); err != nil {
+		t.Fatalf("invalid change: %v", err)
+	}
+	if got.Entity.ID != "web" || got.Entity.Labels["bosun.role"] != "api" {
+		t.Errorf("change = %+v, want web relabeled to api", got)
+	}
+}
+
+func TestEvents_ProjectFilterUsesMeta(t *testing.T) {
+	srv, publish := startEvents(t, 0)
+	// podman-compose sets its own project label, which the source resolves into Meta
+	pod := change(dlabels.ChangeAdded, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web", "io.podman.compose.project": "shop"})
+	pod.Entity.Meta = map[string]string{dlabels.MetaComposeProject: "shop"}
+	publish(pod)
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "blog", map[string]string{"bosun.role": "web", dlabels.LabelComposeProject: "blog"}))
+
+	c := openEvents(t, srv.URL+"/v1/events?project=shop", "")
+	defer c.close()
+
+	var snap dlabels.Snapshot
+	if err := json.Unmarshal([]byte(c.next().data), &snap); err != nil {
+		t.Fatalf("invalid snapshot: %v", err)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].ID != "web" {
+		t.Fatalf("snapshot = %+v, want only container web", snap.Entities)
+	}
+}
+
+func TestEvents_LabelSelector(t *testing.T) {
+	srv, publish := startEvents(t, 0)
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web"}))
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "db", map[string]string{"bosun.role": "db"}))
+
+	c := openEvents(t, srv.URL+"/v1/events?selector="+url.QueryEscape("bosun.role in (db,cache)"), "")
+	defer c.close()
+
+	var snap dlabels.Snapshot
+	if err := json.Unmarshal([]byte(c.next().data), &snap); err != nil {
+		t.Fatalf("invalid snapshot: %v", err)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].ID != "db" {
+		t.Fatalf("snapshot = %+v, want only container db", snap.Entities)
+	}
+
+	// web starts matching, so the client sees it added
+	publish(change(dlabels.ChangeLabelsChanged, dlabels.KindContainer, "web", map[string]string{"bosun.role": "cache"}))
+	ev := c.next()
+	if ev.event != string(dlabels.ChangeAdded) {
+		t.Errorf("event = %q, want added", ev.event)
+	}
+}
+
+func TestEvents_ResumeWithLastEventID(t *testing.T) {
+	srv, publish := startEvents(t, 0)
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web"}))
+
+	c := openEvents(t, srv.URL+"/v1/events", "")
+	snapEv := c.next()
+	publish(change(dlabels.ChangeAdded, dlabels.KindVolume, "data", map[string]string{"bosun.backup": "daily"}))
+	seen := c.next()
+	c.close()
+
+	// Changes while disconnected
+	publish(change(dlabels.ChangeLabelsChanged, dlabels.KindVolume, "data", map[string]string{"bosun.backup": "weekly"}))
+	publish(change(dlabels.ChangeRemoved, dlabels.KindContainer, "web", nil))
+
+	c = openEvents(t, srv.URL+"/v1/events", seen.id)
+	defer c.close()
+	for _, want := range []dlabels.ChangeType{dlabels.ChangeLabelsChanged, dlabels.ChangeRemoved} {
+		if ev := c.next(); ev.event != string(want) {
+			t.Fatalf("event = %q, want %q", ev.event, want)
+		}
+	}
+
+	// Resuming from the snapshot replays everything after it
+	c2 := openEvents(t, srv.URL+"/v1/events?lastEventId="+snapEv.id, "")
+	defer c2.close()
+	if ev := c2.next(); ev.event != string(dlabels.ChangeAdded) || !strings.Contains(ev.data, `"data"`) {
+		t.Errorf("first replayed event = %+v, want volume data added", ev)
+	}
+}
+
+func TestEvents_StaleLastEventIDGetsSnapshot(t *testing.T) {
+	srv, publish := startEvents(t, 1)
+	publish(change(dlabels.ChangeAdded, dlabels.KindContainer, "web", map[string]string{"bosun.role": "web"}))
+
+	c := openEvents(t, srv.URL+"/v1/events", "")
+	first := c.next()
+	c.close()
+
+	// The ring only holds one change, so the client falls behind
+	publish(change(dlabels.ChangeAdded, dlabels.KindVolume, "a", map[string]string{"bosun.backup": "daily"}))
+	publish(change(dlabels.ChangeAdded, dlabels.KindVolume, "b", map[string]string{"bosun.backup": "daily"}))
+
+	for _, id := range []string{first.id, "previous-run-7"} {
+		c := openEvents(t, srv.URL+"/v1/events", id)
+		ev := c.next()
+		c.close()
+		if ev.event != "snapshot" {
+			t.Fatalf("Last-Event-ID %s: event = %q, want snapshot", id, ev.event)
+		}
+		var snap dlabels.Snapshot
+		_ = json.Unmarshal([]byte(ev.data), &snap)
+		if len(snap.Entities) != 3 {
+			t.Errorf("Last-Event-ID %s: snapshot has %d entities, want 3", id, len(snap.Entities))
+		}
+	}
+}
+
+func TestEvents_EndOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &fakeWatcher{ch: make(chan dlabels.Change)}
+	s := &Server{Source: &fakeSource{}, Watcher: w, ShutdownTimeout: 5 * time.Second}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	c := openEvents(t, "http://"+ln.Addr().String()+"/v1/events", "")
+	defer c.close()
+	c.next()
+	if w.sel.Prefixes[0] != "" {
+		t.Errorf("upstream prefixes = %q, want every label", w.sel.Prefixes)
+	}
+
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve waited for the open event stream")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("shutdown took %v", time.Since(start))
+	}
+}
@@ -0,0 +1,275 @@
+// Package httpapi serves Bosun data over HTTP.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// DefaultShutdownTimeout bounds how long Serve waits for in-flight requests on shutdown.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Server exposes a LabelSource as a read-only JSON API:
+//
+//	GET /v1/snapshot               snapshot; query: prefix, stopped, project, kind
+//	GET /v1/entities/{kind}/{id}   one entity by ID or name; query: prefix
+//	GET /v1/events                 Server-Sent Events of changes, with Watcher; query: prefix, project, kind
+//	GET /healthz                   liveness
+//	GET /readyz                    readiness, false while the source is unreachable or shutting down
+type Server struct {
+	Source ports.LabelSource
+	// Ready reports whether the source can serve requests; nil means always ready.
+	Ready           func(ctx context.Context) error
+	ShutdownTimeout time.Duration // defaults to DefaultShutdownTimeout
+
+	// Watcher enables /v1/events when set.
+	Watcher     ports.LabelWatcher
+	EventBuffer int // changes kept for resuming clients; defaults to DefaultEventBuffer
+
+	draining atomic.Bool
+	hub      *eventHub
+}
+
+// errorResponse is the body of every non-2xx response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// statusResponse is the body of the health endpoints.
+type statusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	mux.HandleFunc("GET /v1/snapshot", s.handleSnapshot)
+	mux.HandleFunc("GET /v1/entities/{kind}/{id}", s.handleEntity)
+	if s.Watcher != nil {
+		mux.HandleFunc("GET /v1/events", s.handleEvents)
+	}
+	return mux
+}
+
+// Serve serves the API on ln until ctx is done, then stops accepting
+// connections, reports not ready and waits up to ShutdownTimeout for
+// in-flight requests to finish. Event streams end as soon as ctx is done.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.Watcher != nil {
+		ch, err := s.Watcher.Watch(ctx, upstreamSelector)
+		if err != nil {
+			return fmt.Errorf("failed to watch labels: %w", err)
+		}
+		s.hub = newEventHub(s.EventBuffer)
+		go s.hub.run(ch)
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down HTTP server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "unavailable", Error: "shutting down"})
+		return
+	}
+	if s.Ready != nil {
+		if err := s.Ready(r.Context()); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "unavailable", Error: err.Error()})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	sel, err := SelectorFromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	snap, err := s.Source.Snapshot(r.Context(), sel)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("failed to get snapshot: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request) {
+	kind := dlabels.Kind(r.PathValue("kind"))
+	if !kind.Valid() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown kind %q", kind))
+		return
+	}
+	q := r.URL.Query()
+	if err := checkParams(q, "prefix"); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sel := ports.Selector{
+		Prefixes:       prefixes(q),
+		IncludeStopped: true,
+		Kinds:          []dlabels.Kind{kind},
+	}
+
+	snap, err := s.Source.Snapshot(r.Context(), sel)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("failed to get snapshot: %w", err))
+		return
+	}
+
+	// IDs win over names, which Docker does not require to be unique across kinds
+	id := r.PathValue("id")
+	var byName *dlabels.LabeledEntity
+	for i, e := range snap.Entities {
+		if e.ID == id {
+			writeJSON(w, http.StatusOK, e)
+			return
+		}
+		if e.Name == id && byName == nil {
+			byName = &snap.Entities[i]
+		}
+	}
+	if byName != nil {
+		writeJSON(w, http.StatusOK, byName)
+		return
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("%s %s not found", kind, id))
+}
+
+// SelectorFromQuery maps snapshot query parameters onto a selector. List
+// parameters may be repeated or comma-separated:
+//
+//	prefix   label prefixes (default "bosun.")
+//	stopped  include stopped containers (bool)
+//	project  compose projects
+//	kind     entity kinds: container, volume, network
+//	selector label selector, e.g. "bosun.role=web,!bosun.ignore" (not split on commas)
+//	detail   metadata detail level: basic, extended, full
+func SelectorFromQuery(q url.Values) (ports.Selector, error) {
+	if err := checkParams(q, "prefix", "stopped", "project", "kind", "selector", "detail"); err != nil {
+		return ports.Selector{}, err
+	}
+
+	sel := ports.Selector{
+		Prefixes:      prefixes(q),
+		ProjectFilter: listParam(q, "project"),
+	}
+	if v := q.Get("stopped"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return sel, fmt.Errorf("invalid stopped value %q", v)
+		}
+		sel.IncludeStopped = b
+	}
+	for _, v := range listParam(q, "kind") {
+		k := dlabels.Kind(v)
+		if !k.Valid() {
+			return sel, fmt.Errorf("unknown kind %q", v)
+		}
+		sel.Kinds = append(sel.Kinds, k)
+	}
+	for _, v := range q["selector"] {
+		ls, err := dselector.Parse(v)
+		if err != nil {
+			return sel, err
+		}
+		sel.LabelSelector = append(sel.LabelSelector, ls...)
+	}
+	detail, err := ports.ParseDetail(q.Get("detail"))
+	if err != nil {
+		return sel, err
+	}
+	sel.Detail = detail
+	return sel, sel.CheckLabelSelector()
+}
+
+// checkParams rejects query parameters outside allowed, so typos do not silently widen a query.
+func checkParams(q url.Values, allowed ...string) error {
+	for key := range q {
+		if !slices.Contains(allowed, key) {
+			return fmt.Errorf("unknown query parameter %q", key)
+		}
+	}
+	return nil
+}
+
+// prefixes returns the prefix parameter, defaulting to the Bosun prefix.
+func prefixes(q url.Values) []string {
+	if p := listParam(q, "prefix"); len(p) > 0 {
+		return p
+	}
+	return []string{dlabels.DefaultLabelPrefix}
+}
+
+// listParam returns the values of a repeated or comma-separated parameter.
+func listParam(q url.Values, key string) []string {
+	var out []string
+	for _, v := range q[key] {
+		for part := range strings.SplitSeq(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
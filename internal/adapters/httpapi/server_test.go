@@ -0,0 +1,248 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// fakeSource records the last selector and filters a fixed entity list by kind
+type fakeSource struct {
+	sel ports.Selector
+	err error
+}
+
+func (f *fakeSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	f.sel = sel
+	if f.err != nil {
+		return dlabels.Snapshot{}, f.err
+	}
+	all := []dlabels.LabeledEntity{
+		{Kind: dlabels.KindContainer, ID: "c1", Name: "web", Labels: map[string]string{"bosun.role": "web"}},
+		{Kind: dlabels.KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}},
+	}
+	snap := dlabels.Snapshot{TakenAt: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)}
+	for _, e := range all {
+		if sel.WantsKind(e.Kind) {
+			snap.Entities = append(snap.Entities, e)
+		}
+	}
+	return snap, nil
+}
+
+func get(t *testing.T, h http.Handler, target string) (int, []byte) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s: Content-Type = %q, want application/json", target, ct)
+	}
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestSelectorFromQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    ports.Selector
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  ports.Selector{Prefixes: []string{"bosun."}},
+		},
+		{
+			name:  "all parameters",
+			query: "prefix=bosun.,acme.&stopped=true&project=a&project=b&kind=container,volume",
+			want: ports.Selector{
+				Prefixes:       []string{"bosun.", "acme."},
+				IncludeStopped: true,
+				ProjectFilter:  []string{"a", "b"},
+				Kinds:          []dlabels.Kind{dlabels.KindContainer, dlabels.KindVolume},
+			},
+		},
+		{
+			name:  "label selector",
+			query: "selector=" + url.QueryEscape("bosun.role=web,bosun.backup in (daily,weekly)"),
+			want: ports.Selector{
+				Prefixes: []string{"bosun."},
+				LabelSelector: dselector.Selector{
+					{Key: "bosun.role", Op: dselector.Equals, Values: []string{"web"}},
+					{Key: "bosun.backup", Op: dselector.In, Values: []string{"daily", "weekly"}},
+				},
+			},
+		},
+		{
+			name:  "detail",
+			query: "detail=full",
+			want:  ports.Selector{Prefixes: []string{"bosun."}, Detail: ports.DetailFull},
+		},
+		{
+			name:  "selector on another prefix",
+			query: "prefix=bosun.,com.docker.&selector=" + url.QueryEscape("com.docker.compose.service=web,meta:state=running"),
+			want: ports.Selector{
+				Prefixes: []string{"bosun.", "com.docker."},
+				LabelSelector: dselector.Selector{
+					{Key: "com.docker.compose.service", Op: dselector.Equals, Values: []string{"web"}},
+					{Key: "meta:state", Op: dselector.Equals, Values: []string{"running"}},
+				},
+			},
+		},
+		{name: "invalid selector", query: "selector=bosun.role+like+web", wantErr: true},
+		{name: "selector outside the prefixes", query: "selector=" + url.QueryEscape("!traefik.enable"), wantErr: true},
+		{name: "unknown detail", query: "detail=verbose", wantErr: true},
+		{name: "invalid stopped", query: "stopped=maybe", wantErr: true},
+		{name: "unknown kind", query: "kind=pod", wantErr: true},
+		{name: "unknown parameter", query: "kinds=volume", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/snapshot?"+tt.query, nil)
+			got, err := SelectorFromQuery(req.URL.Query())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectorFromQuery(%q) failed: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectorFromQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotEndpoint(t *testing.T) {
+	src := &fakeSource{}
+	h := (&Server{Source: src}).Handler()
+
+	code, body := get(t, h, "/v1/snapshot?kind=volume&stopped=1")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", code, body)
+	}
+	var snap dlabels.Snapshot
+	if err := json.Unmarshal(body, &snap); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(snap.Entities) != 1 || snap.Entities[0].Name != "data" {
+		t.Errorf("entities = %+v, want only the volume", snap.Entities)
+	}
+	if !src.sel.IncludeStopped {
+		t.Error("expected stopped=1 to include stopped containers")
+	}
+
+	if code, _ := get(t, h, "/v1/snapshot?kind=pod"); code != http.StatusBadRequest {
+		t.Errorf("invalid kind: status = %d, want 400", code)
+	}
+
+	src.err = errors.New("daemon unreachable")
+	if code, _ := get(t, h, "/v1/snapshot"); code != http.StatusServiceUnavailable {
+		t.Errorf("source error: status = %d, want 503", code)
+	}
+}
+
+func TestEntityEndpoint(t *testing.T) {
+	src := &fakeSource{}
+	h := (&Server{Source: src}).Handler()
+
+	tests := []struct {
+		target   string
+		wantCode int
+		wantName string
+	}{
+		{"/v1/entities/container/c1", http.StatusOK, "web"},
+		{"/v1/entities/container/web", http.StatusOK, "web"},
+		{"/v1/entities/volume/data", http.StatusOK, "data"},
+		{"/v1/entities/volume/web", http.StatusNotFound, ""},
+		{"/v1/entities/pod/web", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		code, body := get(t, h, tt.target)
+		if code != tt.wantCode {
+			t.Errorf("GET %s: status = %d, want %d (%s)", tt.target, code, tt.wantCode, body)
+			continue
+		}
+		if tt.wantName == "" {
+			continue
+		}
+		var e dlabels.LabeledEntity
+		if err := json.Unmarshal(body, &e); err != nil || e.Name != tt.wantName {
+			t.Errorf("GET %s: entity = %s, want %s", tt.target, body, tt.wantName)
+		}
+	}
+	if !src.sel.IncludeStopped {
+		t.Error("expected entity lookups to include stopped containers")
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var readyErr error
+	s := &Server{Source: &fakeSource{}, Ready: func(ctx context.Context) error { return readyErr }}
+	h := s.Handler()
+
+	if code, _ := get(t, h, "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want 200", code)
+	}
+	if code, _ := get(t, h, "/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz status = %d, want 200", code)
+	}
+
+	readyErr = errors.New("cannot connect to the Docker daemon")
+	if code, _ := get(t, h, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz status = %d, want 503 when the source is down", code)
+	}
+	if code, _ := get(t, h, "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want 200 regardless of the source", code)
+	}
+}
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{Source: &fakeSource{}}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v, want nil after shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after ctx was cancelled")
+	}
+	if !s.draining.Load() {
+		t.Error("expected the server to report draining")
+	}
+}
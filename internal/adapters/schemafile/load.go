@@ -0,0 +1,84 @@
+// Package schemafile loads label schemas from YAML or JSON files.
+package schemafile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/domain/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// file is the on-disk layout of a schema. JSON documents are valid YAML, so
+// both formats decode through the same structs.
+//
+//	prefix: bosun.
+//	unknownKeys: error
+//	keys:
+//	  bosun.backup:
+//	    type: string
+//	    allowed: [daily, weekly]
+//	    kinds: [volume]
+type file struct {
+	Prefix      string             `yaml:"prefix"`
+	UnknownKeys string             `yaml:"unknownKeys"`
+	Keys        map[string]keySpec `yaml:"keys"`
+}
+
+type keySpec struct {
+	Type        string   `yaml:"type"`
+	Allowed     []string `yaml:"allowed"`
+	Required    bool     `yaml:"required"`
+	Kinds       []string `yaml:"kinds"`
+	Description string   `yaml:"description"`
+}
+
+// Load reads and validates the schema at path.
+func Load(path string) (schema.Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return schema.Schema{}, fmt.Errorf("failed to read schema: %w", err)
+	}
+	s, err := Parse(b)
+	if err != nil {
+		return schema.Schema{}, fmt.Errorf("schema %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes and validates a YAML or JSON schema document.
+// Unknown fields are rejected so typos in the schema itself are caught.
+func Parse(b []byte) (schema.Schema, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return schema.Schema{}, err
+	}
+
+	s := schema.Schema{
+		Prefix:      f.Prefix,
+		UnknownKeys: schema.Severity(f.UnknownKeys),
+	}
+	for _, key := range slices.Sorted(maps.Keys(f.Keys)) {
+		ks := f.Keys[key]
+		spec := schema.KeySpec{
+			Key:         key,
+			Type:        schema.Type(ks.Type),
+			Allowed:     ks.Allowed,
+			Required:    ks.Required,
+			Description: ks.Description,
+		}
+		for _, k := range ks.Kinds {
+			spec.Kinds = append(spec.Kinds, dlabels.Kind(k))
+		}
+		s.Keys = append(s.Keys, spec)
+	}
+	return s.Normalize()
+}
@@ -0,0 +1,77 @@
+package schemafile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/domain/schema"
+)
+
+func TestLoad_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	doc := `
+unknownKeys: warning
+keys:
+  bosun.backup:
+    allowed: [daily, weekly]
+    kinds: [volume]
+  bosun.replicas:
+    type: int
+    required: true
+    kinds: [container]
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Prefix != dlabels.DefaultLabelPrefix || s.UnknownKeys != schema.SeverityWarning {
+		t.Errorf("unexpected header: %+v", s)
+	}
+	if len(s.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(s.Keys))
+	}
+	backup, ok := s.Lookup("bosun.backup")
+	if !ok || backup.Type != schema.TypeString || !backup.AppliesTo(dlabels.KindVolume) || backup.AppliesTo(dlabels.KindContainer) {
+		t.Errorf("unexpected bosun.backup spec: %+v", backup)
+	}
+	replicas, _ := s.Lookup("bosun.replicas")
+	if replicas.Type != schema.TypeInt || !replicas.Required {
+		t.Errorf("unexpected bosun.replicas spec: %+v", replicas)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	s, err := Parse([]byte(`{"keys": {"bosun.role": {"type": "string", "kinds": ["container"]}}}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, ok := s.Lookup("bosun.role"); !ok {
+		t.Errorf("expected bosun.role to be declared")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{name: "unknown field", doc: "keys:\n  bosun.a:\n    requird: true\n", wantErr: "requird"},
+		{name: "invalid type", doc: "keys:\n  bosun.a:\n    type: float\n", wantErr: "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Package storage persists Bosun data on the local filesystem.
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+const (
+	// snapshotLayout is the timestamp that names and identifies a snapshot, always UTC.
+	snapshotLayout = "20060102T150405.000Z"
+	snapshotPrefix = "snapshot-"
+	jsonExt        = ".json"
+	gzipExt        = ".json.gz"
+)
+
+// ErrSnapshotNotFound is returned by Get for an unknown snapshot ID.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotDir implements ports.SnapshotStore on a local directory, storing each
+// snapshot as <Dir>/snapshot-<timestamp>.json, or .json.gz when Gzip is set.
+// The timestamp is the snapshot ID.
+type SnapshotDir struct {
+	Dir  string
+	Gzip bool             // compress newly saved snapshots
+	Now  func() time.Time // used by Prune; defaults to time.Now
+}
+
+// NewSnapshotDir creates a SnapshotDir rooted at dir.
+func NewSnapshotDir(dir string) *SnapshotDir {
+	return &SnapshotDir{Dir: dir}
+}
+
+// Save implements the SnapshotStore interface. The file is written to a
+// temporary file and renamed into place, so partial snapshots are never listed.
+func (s *SnapshotDir) Save(ctx context.Context, snap dlabels.Snapshot) (dlabels.SnapshotInfo, error) {
+	if snap.TakenAt.IsZero() {
+		return dlabels.SnapshotInfo{}, errors.New("snapshot has no timestamp")
+	}
+	if err := os.MkdirAll(s.Dir, 0o750); err != nil {
+		return dlabels.SnapshotInfo{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	id := snap.TakenAt.UTC().Format(snapshotLayout)
+	if _, err := s.path(id); err == nil {
+		return dlabels.SnapshotInfo{}, fmt.Errorf("snapshot %s already exists", id)
+	}
+	ext := jsonExt
+	if s.Gzip {
+		ext = gzipExt
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, ".partial-*")
+	if err != nil {
+		return dlabels.SnapshotInfo{}, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	err = s.encode(tmp, snap)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return dlabels.SnapshotInfo{}, fmt.Errorf("failed to write snapshot %s: %w", id, err)
+	}
+	path := filepath.Join(s.Dir, snapshotPrefix+id+ext)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return dlabels.SnapshotInfo{}, fmt.Errorf("failed to store snapshot %s: %w", id, err)
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return dlabels.SnapshotInfo{}, err
+	}
+	at, _ := time.Parse(snapshotLayout, id)
+	return dlabels.SnapshotInfo{ID: id, TakenAt: at, Size: st.Size()}, nil
+}
+
+// encode writes snap as JSON to f, compressed if Gzip is set, and syncs it.
+func (s *SnapshotDir) encode(f *os.File, snap dlabels.Snapshot) error {
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if s.Gzip {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// List implements the SnapshotStore interface.
+func (s *SnapshotDir) List(ctx context.Context) ([]dlabels.SnapshotInfo, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var out []dlabels.SnapshotInfo
+	for _, e := range entries {
+		id, ok := parseSnapshotName(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		at, err := time.Parse(snapshotLayout, id)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dlabels.SnapshotInfo{ID: id, TakenAt: at, Size: info.Size()})
+	}
+	slices.SortFunc(out, func(a, b dlabels.SnapshotInfo) int {
+		return a.TakenAt.Compare(b.TakenAt)
+	})
+	return out, nil
+}
+
+// Get implements the SnapshotStore interface.
+func (s *SnapshotDir) Get(ctx context.Context, id string) (dlabels.Snapshot, error) {
+	var snap dlabels.Snapshot
+	path, err := s.path(id)
+	if err != nil {
+		return snap, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return snap, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, gzipExt) {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return snap, fmt.Errorf("failed to read snapshot %s: %w", id, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("failed to decode snapshot %s: %w", id, err)
+	}
+	return snap, nil
+}
+
+// Prune implements the SnapshotStore interface.
+func (s *SnapshotDir) Prune(ctx context.Context, policy dlabels.RetentionPolicy) ([]dlabels.SnapshotInfo, error) {
+	history, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	var removed []dlabels.SnapshotInfo
+	for _, info := range policy.Expired(history, now) {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		path, err := s.path(info.ID)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove snapshot %s: %w", info.ID, err)
+		}
+		removed = append(removed, info)
+	}
+	return removed, nil
+}
+
+// path returns the file holding the snapshot with the given ID, compressed or not.
+func (s *SnapshotDir) path(id string) (string, error) {
+	if _, err := time.Parse(snapshotLayout, id); err != nil {
+		return "", fmt.Errorf("invalid snapshot id %q", id)
+	}
+	for _, ext := range []string{jsonExt, gzipExt} {
+		path := filepath.Join(s.Dir, snapshotPrefix+id+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+}
+
+// parseSnapshotName returns the ID of a snapshot file name.
+func parseSnapshotName(name string) (string, bool) {
+	id, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return "", false
+	}
+	for _, ext := range []string{gzipExt, jsonExt} {
+		if id, ok := strings.CutSuffix(id, ext); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func testSnapshot(at time.Time, backup string) dlabels.Snapshot {
+	return dlabels.Snapshot{
+		TakenAt: at,
+		Entities: []dlabels.LabeledEntity{
+			{Kind: dlabels.KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": backup}},
+		},
+	}
+}
+
+func TestSnapshotDir_SaveListGet(t *testing.T) {
+	ctx := context.Background()
+	store := NewSnapshotDir(t.TempDir())
+	t1 := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+
+	info, err := store.Save(ctx, testSnapshot(t1, "daily"))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if info.ID != "20250310T120000.000Z" || !info.TakenAt.Equal(t1) || info.Size == 0 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	store.Gzip = true
+	if _, err := store.Save(ctx, testSnapshot(t2, "weekly")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, "snapshot-20250311T120000.000Z.json.gz")); err != nil {
+		t.Errorf("expected a gzip file: %v", err)
+	}
+
+	// Stray files are ignored
+	if err := os.WriteFile(filepath.Join(store.Dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(history) != 2 || !history[0].TakenAt.Equal(t1) || !history[1].TakenAt.Equal(t2) {
+		t.Fatalf("List() = %+v, want both snapshots oldest first", history)
+	}
+
+	for i, want := range []string{"daily", "weekly"} {
+		snap, err := store.Get(ctx, history[i].ID)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", history[i].ID, err)
+		}
+		if got := snap.Entities[0].Labels["bosun.backup"]; got != want {
+			t.Errorf("Get(%s) bosun.backup = %q, want %q", history[i].ID, got, want)
+		}
+	}
+
+	if _, err := store.Save(ctx, testSnapshot(t1, "daily")); err == nil {
+		t.Error("Expected saving a snapshot with the same timestamp to fail")
+	}
+}
+
+func TestSnapshotDir_Get(t *testing.T) {
+	store := NewSnapshotDir(t.TempDir())
+
+	if _, err := store.Get(context.Background(), "20250310T120000.000Z"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Expected ErrSnapshotNotFound, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "../../etc/passwd"); err == nil || errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("Expected an invalid id error, got %v", err)
+	}
+}
+
+func TestSnapshotDir_Prune(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := NewSnapshotDir(t.TempDir())
+	store.Now = func() time.Time { return base.AddDate(0, 0, 10) }
+	for d := range 5 {
+		if _, err := store.Save(ctx, testSnapshot(base.AddDate(0, 0, d), "daily")); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	removed, err := store.Prune(ctx, dlabels.RetentionPolicy{KeepLast: 2})
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("removed %d snapshots, want 3", len(removed))
+	}
+
+	history, _ := store.List(ctx)
+	var got []string
+	for _, s := range history {
+		got = append(got, s.ID)
+	}
+	want := []string{"20250304T000000.000Z", "20250305T000000.000Z"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List() after Prune = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	dbackup "github.com/simone-viozzi/bosun/internal/domain/backup"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// BackupService runs the volume backups scheduled through bosun.backup labels.
+type BackupService struct {
+	Source     ports.LabelSource
+	Archiver   ports.VolumeArchiver
+	Containers ports.ContainerController
+	Target     ports.BackupTarget
+	Now        func() time.Time // defaults to time.Now
+}
+
+// BackupRunOptions selects which scheduled volumes a run considers.
+type BackupRunOptions struct {
+	Projects []string // only volumes of these compose projects
+	Volumes  []string // only these volumes
+	Force    bool     // back up selected volumes even when not due
+	DryRun   bool     // plan only, archive nothing
+}
+
+// BackupResult is the outcome of one scheduled volume in a run.
+type BackupResult struct {
+	Job     dbackup.Job
+	Backup  dbackup.Backup // set when a backup was taken
+	Skipped bool           // not due, or a dry run
+	Stopped []string       // containers stopped and restarted around the backup
+	Err     error
+}
+
+func (s *BackupService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Plan returns the backup jobs for the selected volumes, with Due set from the
+// latest backup in the target (or forced).
+func (s *BackupService) Plan(ctx context.Context, opts BackupRunOptions) ([]dbackup.Job, error) {
+	snap, err := s.Source.Snapshot(ctx, ports.Selector{
+		Prefixes:      []string{dlabels.DefaultLabelPrefix},
+		ProjectFilter: opts.Projects,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	last := make(map[string]time.Time)
+	backups, err := s.Target.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	for _, b := range backups {
+		if b.TakenAt.After(last[b.Volume]) {
+			last[b.Volume] = b.TakenAt
+		}
+	}
+
+	var jobs []dbackup.Job
+	for _, job := range dbackup.Plan(snap, last, s.now()) {
+		if len(opts.Volumes) > 0 && !slices.Contains(opts.Volumes, job.Volume) {
+			continue
+		}
+		if opts.Force && job.Err == nil {
+			job.Due = true
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Run backs up every due volume. A failing volume does not stop the others;
+// its error is reported in its result.
+func (s *BackupService) Run(ctx context.Context, opts BackupRunOptions) ([]BackupResult, error) {
+	jobs, err := s.Plan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BackupResult, 0, len(jobs))
+	for _, job := range jobs {
+		res := BackupResult{Job: job, Err: job.Err}
+		switch {
+		case job.Err != nil:
+		case !job.Due || opts.DryRun:
+			res.Skipped = true
+		default:
+			res.Backup, res.Stopped, res.Err = s.backup(ctx, job)
+		}
+		results = append(results, res)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// backup archives one volume into the target, stopping the containers that
+// mount it first when the job asks for it.
+func (s *BackupService) backup(ctx context.Context, job dbackup.Job) (b dbackup.Backup, stopped []string, err error) {
+	if job.Stop {
+		var restart func() error
+		stopped, restart, err = stopUsers(ctx, s.Containers, job.Volume)
+		defer func() { err = errors.Join(err, restart()) }()
+		if err != nil {
+			return b, stopped, err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	archived := make(chan error, 1)
+	go func() {
+		err := s.Archiver.Archive(ctx, job.Volume, pw)
+		pw.CloseWithError(err)
+		archived <- err
+	}()
+	b, err = s.Target.Save(ctx, job.Volume, s.now(), pr)
+	// Unblock the archiver if the target gave up early, and wait for it so
+	// its own failure, e.g. to clean up, is not lost
+	pr.CloseWithError(err)
+	if aerr := <-archived; aerr != nil && !errors.Is(err, aerr) && !errors.Is(aerr, err) {
+		err = errors.Join(err, fmt.Errorf("failed to archive %s: %w", job.Volume, aerr))
+	}
+	return b, stopped, err
+}
+
+// stopUsers stops the running containers that mount volume. The returned
+// restart function starts the stopped ones again in reverse order, even if ctx
+// is cancelled; it must be called even when stopping fails part way.
+func stopUsers(ctx context.Context, c ports.ContainerController, volume string) (stopped []string, restart func() error, err error) {
+	ids, err := c.ContainersUsingVolume(ctx, volume)
+	if err != nil {
+		return nil, func() error { return nil }, fmt.Errorf("failed to list containers using %s: %w", volume, err)
+	}
+	return stopContainers(ctx, c, ids)
+}
+
+// stopContainers stops the containers ids in order, with a restart function
+// like the one of stopUsers.
+func stopContainers(ctx context.Context, c ports.ContainerController, ids []string) (stopped []string, restart func() error, err error) {
+	restart = func() error {
+		var errs error
+		restartCtx := context.WithoutCancel(ctx)
+		for _, id := range slices.Backward(stopped) {
+			if err := c.Start(restartCtx, id); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("failed to restart container %s: %w", id, err))
+			}
+		}
+		return errs
+	}
+	for _, id := range ids {
+		if err := c.Stop(ctx, id); err != nil {
+			return stopped, restart, fmt.Errorf("failed to stop container %s: %w", id, err)
+		}
+		stopped = append(stopped, id)
+	}
+	return stopped, restart, nil
+}
@@ -0,0 +1,205 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dbackup "github.com/simone-viozzi/bosun/internal/domain/backup"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+type fakeSource struct {
+	snap dlabels.Snapshot
+}
+
+func (f *fakeSource) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	return f.snap, nil
+}
+
+type fakeArchiver struct {
+	fail map[string]bool
+	log  *[]string
+}
+
+func (f *fakeArchiver) Archive(ctx context.Context, volume string, w io.Writer) error {
+	*f.log = append(*f.log, "archive "+volume)
+	if f.fail[volume] {
+		return errors.New("disk on fire")
+	}
+	_, err := io.WriteString(w, "contents of "+volume)
+	return err
+}
+
+type fakeContainers struct {
+	users map[string][]string
+	log   *[]string
+}
+
+func (f *fakeContainers) ContainersUsingVolume(ctx context.Context, volume string) ([]string, error) {
+	return f.users[volume], nil
+}
+
+func (f *fakeContainers) Stop(ctx context.Context, id string) error {
+	*f.log = append(*f.log, "stop "+id)
+	return nil
+}
+
+func (f *fakeContainers) StopTimeout(ctx context.Context, id string, timeout time.Duration) error {
+	return f.Stop(ctx, id)
+}
+
+func (f *fakeContainers) Start(ctx context.Context, id string) error {
+	*f.log = append(*f.log, "start "+id)
+	return nil
+}
+
+type memTarget struct {
+	backups []dbackup.Backup
+	data    map[string]string
+	full    bool // Save fails without reading
+}
+
+func (m *memTarget) Save(ctx context.Context, volume string, at time.Time, r io.Reader) (dbackup.Backup, error) {
+	if m.full {
+		return dbackup.Backup{}, errors.New("no space left on device")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return dbackup.Backup{}, err
+	}
+	bk := dbackup.Backup{ID: volume + "@" + at.Format(time.RFC3339), Volume: volume, TakenAt: at, Size: int64(len(b))}
+	m.backups = append(m.backups, bk)
+	m.data[bk.ID] = string(b)
+	return bk, nil
+}
+
+func (m *memTarget) List(ctx context.Context, volume string) ([]dbackup.Backup, error) {
+	return m.backups, nil
+}
+
+func newBackupService(t *testing.T, now time.Time, log *[]string) (*app.BackupService, *memTarget, *fakeArchiver) {
+	t.Helper()
+	vol := func(name string, labels map[string]string) dlabels.LabeledEntity {
+		return dlabels.LabeledEntity{Kind: dlabels.KindVolume, ID: name, Name: name, Labels: labels}
+	}
+	target := &memTarget{
+		data:    map[string]string{},
+		backups: []dbackup.Backup{{Volume: "fresh", TakenAt: now.Add(-time.Hour)}},
+	}
+	archiver := &fakeArchiver{fail: map[string]bool{}, log: log}
+	svc := &app.BackupService{
+		Source: &fakeSource{snap: dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+			vol("db", map[string]string{dbackup.LabelSchedule: "daily", dbackup.LabelStop: "true"}),
+			vol("fresh", map[string]string{dbackup.LabelSchedule: "daily"}),
+			vol("bad", map[string]string{dbackup.LabelSchedule: "often"}),
+		}}},
+		Archiver:   archiver,
+		Containers: &fakeContainers{users: map[string][]string{"db": {"c1", "c2"}}, log: log},
+		Target:     target,
+		Now:        func() time.Time { return now },
+	}
+	return svc, target, archiver
+}
+
+func TestBackupService_Run(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var log []string
+	svc, target, _ := newBackupService(t, now, &log)
+
+	results, err := svc.Run(context.Background(), app.BackupRunOptions{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	db, fresh, bad := results[0], results[1], results[2]
+	if db.Err != nil || db.Skipped || db.Backup.Volume != "db" || len(db.Stopped) != 2 {
+		t.Errorf("unexpected db result: %+v", db)
+	}
+	if !fresh.Skipped || fresh.Err != nil {
+		t.Errorf("expected fresh to be skipped, got %+v", fresh)
+	}
+	if bad.Err == nil {
+		t.Errorf("expected an invalid schedule error for bad, got %+v", bad)
+	}
+
+	want := []string{"stop c1", "stop c2", "archive db", "start c2", "start c1"}
+	if strings.Join(log, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+	if target.data[db.Backup.ID] != "contents of db" {
+		t.Errorf("stored archive = %q", target.data[db.Backup.ID])
+	}
+}
+
+func TestBackupService_RunForceAndFailure(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var log []string
+	svc, _, archiver := newBackupService(t, now, &log)
+	archiver.fail["db"] = true
+
+	results, err := svc.Run(context.Background(), app.BackupRunOptions{Volumes: []string{"db", "fresh"}, Force: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Err == nil {
+		t.Error("expected the db backup to fail")
+	}
+	if results[1].Skipped || results[1].Err != nil {
+		t.Errorf("expected a forced backup of fresh, got %+v", results[1])
+	}
+
+	// Containers are restarted although the archive failed
+	want := []string{"stop c1", "stop c2", "archive db", "start c2", "start c1", "archive fresh"}
+	if strings.Join(log, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", log, want)
+	}
+}
+
+func TestBackupService_RunKeepsArchiveErrorWhenSaveFails(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var log []string
+	svc, target, archiver := newBackupService(t, now, &log)
+	target.full = true
+	archiver.fail["db"] = true
+
+	results, err := svc.Run(context.Background(), app.BackupRunOptions{Volumes: []string{"db"}, Force: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("expected the db backup to fail, got %+v", results)
+	}
+	msg := results[0].Err.Error()
+	if !strings.Contains(msg, "no space left on device") || !strings.Contains(msg, "failed to archive db: disk on fire") {
+		t.Errorf("error = %q, want both the save and the archive failure", msg)
+	}
+}
+
+func TestBackupService_DryRun(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var log []string
+	svc, _, _ := newBackupService(t, now, &log)
+
+	results, err := svc.Run(context.Background(), app.BackupRunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !results[0].Skipped || !results[0].Job.Due {
+		t.Errorf("expected db due but skipped, got %+v", results[0])
+	}
+	if len(log) != 0 {
+		t.Errorf("dry run touched containers or volumes: %v", log)
+	}
+}
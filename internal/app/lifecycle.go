@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// LifecycleService starts, stops and restarts the containers of a label
+// selection in dependency order.
+type LifecycleService struct {
+	Source     ports.LabelSource
+	Containers ports.ContainerController
+}
+
+// LifecycleOptions controls how an order is executed.
+type LifecycleOptions struct {
+	DryRun bool // report the steps without executing them
+}
+
+// Lifecycle step actions.
+const (
+	StepStart = "start"
+	StepStop  = "stop"
+)
+
+// LifecycleStep is the outcome of starting or stopping one container.
+type LifecycleStep struct {
+	Action string // StepStart or StepStop
+	Unit   dlifecycle.Unit
+	// Skip says why nothing was done, e.g. "already running" or "dry run";
+	// empty when the step was executed.
+	Skip string
+	Err  error
+}
+
+func (s LifecycleStep) String() string {
+	switch {
+	case s.Err != nil:
+		return fmt.Sprintf("%s %s: error: %v", s.Action, s.Unit.Name, s.Err)
+	case s.Skip != "":
+		return fmt.Sprintf("%s %s: %s", s.Action, s.Unit.Name, s.Skip)
+	}
+	return fmt.Sprintf("%s %s: done", s.Action, s.Unit.Name)
+}
+
+// Order takes a snapshot of every container, stopped ones included, and
+// orders those matching sel. It fails when none match.
+func (s *LifecycleService) Order(ctx context.Context, sel dselector.Selector) (dlifecycle.Order, error) {
+	snap, err := s.Source.Snapshot(ctx, ports.Selector{
+		Prefixes:       []string{""}, // every label, as selectors may use any key
+		IncludeStopped: true,
+		Kinds:          []dlabels.Kind{dlabels.KindContainer},
+		Detail:         ports.DetailExtended,
+	})
+	if err != nil {
+		return dlifecycle.Order{}, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	var selected []dlabels.LabeledEntity
+	for _, e := range snap.Entities {
+		if sel.Matches(e) {
+			selected = append(selected, e)
+		}
+	}
+	if len(selected) == 0 {
+		return dlifecycle.Order{}, fmt.Errorf("no containers match %s", sel)
+	}
+	return dlifecycle.Resolve(selected, snap.Entities)
+}
+
+// Up starts the stopped containers of order, dependencies first, passing each
+// step to report. It stops at the first failure, as the containers depending
+// on the failed one would not work either, and returns its error.
+func (s *LifecycleService) Up(ctx context.Context, order dlifecycle.Order, opts LifecycleOptions, report func(LifecycleStep)) error {
+	return s.start(ctx, order.Start(), true, opts, report)
+}
+
+// Down stops the running containers of order, dependents first, honoring
+// their stop timeouts. It stops at the first failure and returns its error.
+func (s *LifecycleService) Down(ctx context.Context, order dlifecycle.Order, opts LifecycleOptions, report func(LifecycleStep)) error {
+	return s.stop(ctx, order.Stop(), opts, report)
+}
+
+// Restart stops the running containers of order like Down, then starts every
+// container of it like Up.
+func (s *LifecycleService) Restart(ctx context.Context, order dlifecycle.Order, opts LifecycleOptions, report func(LifecycleStep)) error {
+	if err := s.stop(ctx, order.Stop(), opts, report); err != nil {
+		return err
+	}
+	return s.start(ctx, order.Start(), false, opts, report)
+}
+
+// start starts units in order; with skipRunning, running ones are left alone
+func (s *LifecycleService) start(ctx context.Context, units []dlifecycle.Unit, skipRunning bool, opts LifecycleOptions, report func(LifecycleStep)) error {
+	for _, u := range units {
+		step := LifecycleStep{Action: StepStart, Unit: u}
+		switch {
+		case skipRunning && u.Running:
+			step.Skip = "already running"
+		case opts.DryRun:
+			step.Skip = "dry run"
+		default:
+			if err := s.Containers.Start(ctx, u.ID); err != nil {
+				step.Err = fmt.Errorf("failed to start container %s: %w", u.Name, err)
+			}
+		}
+		report(step)
+		if step.Err != nil {
+			return step.Err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stop stops the running units in order
+func (s *LifecycleService) stop(ctx context.Context, units []dlifecycle.Unit, opts LifecycleOptions, report func(LifecycleStep)) error {
+	for _, u := range units {
+		step := LifecycleStep{Action: StepStop, Unit: u}
+		switch {
+		case !u.Running:
+			step.Skip = "already stopped"
+		case opts.DryRun:
+			step.Skip = "dry run"
+		default:
+			var err error
+			if u.StopTimeout != nil {
+				err = s.Containers.StopTimeout(ctx, u.ID, *u.StopTimeout)
+			} else {
+				err = s.Containers.Stop(ctx, u.ID)
+			}
+			if err != nil {
+				step.Err = fmt.Errorf("failed to stop container %s: %w", u.Name, err)
+			}
+		}
+		report(step)
+		if step.Err != nil {
+			return step.Err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package app_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+)
+
+// shopEngine has a web -> api -> db chain labeled bosun.role=shop and an
+// unrelated worker
+func shopEngine() *fakeEngine {
+	engine := &fakeEngine{}
+	engine.add("web", "shop", "running")
+	engine.add("api", "shop", "exited")
+	engine.add("db", "shop", "exited")
+	engine.add("worker", "worker", "running")
+	engine.ctrs[0].Labels[dlifecycle.LabelDependsOn] = "api"
+	engine.ctrs[0].Labels[dlifecycle.LabelStopTimeout] = "5s"
+	engine.ctrs[1].Labels[dlifecycle.LabelDependsOn] = "db"
+	return engine
+}
+
+func shopOrder(t *testing.T, svc *app.LifecycleService) dlifecycle.Order {
+	t.Helper()
+	sel, err := dselector.Parse("bosun.role=shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := svc.Order(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Order failed: %v", err)
+	}
+	return order
+}
+
+func collect(steps *[]string) func(app.LifecycleStep) {
+	return func(s app.LifecycleStep) { *steps = append(*steps, s.String()) }
+}
+
+func TestLifecycleService_UpDownRestart(t *testing.T) {
+	ctx := context.Background()
+	engine := shopEngine()
+	svc := &app.LifecycleService{Source: engine, Containers: engine}
+
+	var steps []string
+	if err := svc.Up(ctx, shopOrder(t, svc), app.LifecycleOptions{}, collect(&steps)); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	want := []string{"start db: done", "start api: done", "start web: already running"}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("up steps = %v, want %v", steps, want)
+	}
+
+	engine.log, steps = nil, nil
+	if err := svc.Down(ctx, shopOrder(t, svc), app.LifecycleOptions{}, collect(&steps)); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if want := []string{"stop web after 5s", "stop api", "stop db"}; !reflect.DeepEqual(engine.log, want) {
+		t.Errorf("down log = %v, want %v", engine.log, want)
+	}
+
+	// Restart starts stopped containers as well
+	engine.add("cache", "shop", "running")
+	engine.ctrs[1].Labels[dlifecycle.LabelDependsOn] = "db,cache"
+	engine.log = nil
+	if err := svc.Restart(ctx, shopOrder(t, svc), app.LifecycleOptions{}, collect(&steps)); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if want := []string{"stop cache", "start cache", "start db", "start api", "start web"}; !reflect.DeepEqual(engine.log, want) {
+		t.Errorf("restart log = %v, want %v", engine.log, want)
+	}
+}
+
+func TestLifecycleService_UpStopsAtFailure(t *testing.T) {
+	engine := shopEngine()
+	engine.fail = map[string]bool{"db": true}
+	engine.setState("web", "exited")
+	svc := &app.LifecycleService{Source: engine, Containers: engine}
+
+	var steps []string
+	err := svc.Up(context.Background(), shopOrder(t, svc), app.LifecycleOptions{}, collect(&steps))
+	if err == nil || !strings.Contains(err.Error(), "failed to start container db") {
+		t.Fatalf("Up error = %v, want db failure", err)
+	}
+	if len(steps) != 1 || !reflect.DeepEqual(engine.log, []string{"start db"}) {
+		t.Errorf("steps = %v, log = %v; want only db attempted", steps, engine.log)
+	}
+}
+
+func TestLifecycleService_DryRun(t *testing.T) {
+	engine := shopEngine()
+	svc := &app.LifecycleService{Source: engine, Containers: engine}
+
+	var steps []string
+	if err := svc.Restart(context.Background(), shopOrder(t, svc), app.LifecycleOptions{DryRun: true}, collect(&steps)); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	want := []string{
+		"stop web: dry run", "stop api: already stopped", "stop db: already stopped",
+		"start db: dry run", "start api: dry run", "start web: dry run",
+	}
+	if !reflect.DeepEqual(steps, want) || len(engine.log) != 0 {
+		t.Errorf("steps = %v, log = %v; want %v and no calls", steps, engine.log, want)
+	}
+}
+
+func TestLifecycleService_OrderErrors(t *testing.T) {
+	engine := shopEngine()
+	engine.ctrs[2].Labels[dlifecycle.LabelDependsOn] = "web"
+	svc := &app.LifecycleService{Source: engine, Containers: engine}
+
+	sel, _ := dselector.Parse("bosun.role=shop")
+	if _, err := svc.Order(context.Background(), sel); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("Order error = %v, want a cycle", err)
+	}
+	sel, _ = dselector.Parse("bosun.role=nothing")
+	if _, err := svc.Order(context.Background(), sel); err == nil || !strings.Contains(err.Error(), "no containers match bosun.role=nothing") {
+		t.Errorf("Order error = %v, want no match", err)
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/domain/desired"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// ReconcileService brings groups of containers to the state declared in a desired.Config.
+type ReconcileService struct {
+	Source     ports.LabelSource
+	Containers ports.ContainerController
+}
+
+// ApplyOptions controls how a plan is executed.
+type ApplyOptions struct {
+	DryRun bool // report the actions without executing them
+}
+
+// ActionResult is the outcome of one planned action.
+type ActionResult struct {
+	Action  desired.Action
+	Skipped bool // a dry run
+	Err     error
+}
+
+// ReconcileRound is one plan and apply pass of Run.
+type ReconcileRound struct {
+	Plan    desired.Plan
+	Results []ActionResult
+	Err     error // taking the snapshot failed; Plan and Results are empty
+}
+
+// Plan takes a snapshot of every labeled container, stopped ones included, with
+// the metadata cfg refers to, and compares it with cfg.
+func (s *ReconcileService) Plan(ctx context.Context, cfg desired.Config) (desired.Plan, error) {
+	sel := ports.Selector{
+		Prefixes:       []string{""}, // every label, as selectors may use any key
+		IncludeStopped: true,
+		Kinds:          []dlabels.Kind{dlabels.KindContainer},
+		Detail:         ports.DetailExtended,
+	}
+	if cfg.NeedsHealth() {
+		sel.Detail = ports.DetailFull
+	}
+	snap, err := s.Source.Snapshot(ctx, sel)
+	if err != nil {
+		return desired.Plan{}, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return desired.MakePlan(cfg, snap), nil
+}
+
+// Apply executes the actions of plan in order. A failing action does not stop
+// the others; its error is reported in its result. A restart is a stop followed
+// by a start.
+func (s *ReconcileService) Apply(ctx context.Context, plan desired.Plan, opts ApplyOptions) ([]ActionResult, error) {
+	results := make([]ActionResult, 0, len(plan.Actions))
+	for _, a := range plan.Actions {
+		res := ActionResult{Action: a}
+		if opts.DryRun {
+			res.Skipped = true
+		} else {
+			res.Err = s.execute(ctx, a)
+		}
+		results = append(results, res)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+func (s *ReconcileService) execute(ctx context.Context, a desired.Action) error {
+	switch a.Type {
+	case desired.ActionStart:
+		if err := s.Containers.Start(ctx, a.ContainerID); err != nil {
+			return fmt.Errorf("failed to start container %s: %w", a.Container, err)
+		}
+	case desired.ActionStop:
+		if err := s.Containers.Stop(ctx, a.ContainerID); err != nil {
+			return fmt.Errorf("failed to stop container %s: %w", a.Container, err)
+		}
+	case desired.ActionRestart:
+		if err := s.Containers.Stop(ctx, a.ContainerID); err != nil {
+			return fmt.Errorf("failed to stop container %s: %w", a.Container, err)
+		}
+		if err := s.Containers.Start(ctx, a.ContainerID); err != nil {
+			return fmt.Errorf("failed to start container %s: %w", a.Container, err)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", a.Type)
+	}
+	return nil
+}
+
+// Run plans and applies cfg every interval until ctx is done, passing each
+// round to report. A failed snapshot is reported and retried at the next round.
+func (s *ReconcileService) Run(ctx context.Context, cfg desired.Config, interval time.Duration, opts ApplyOptions, report func(ReconcileRound)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var round ReconcileRound
+		round.Plan, round.Err = s.Plan(ctx, cfg)
+		if round.Err == nil {
+			round.Results, round.Err = s.Apply(ctx, round.Plan, opts)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		report(round)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
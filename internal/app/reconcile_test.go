@@ -0,0 +1,201 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	"github.com/simone-viozzi/bosun/internal/domain/desired"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// fakeEngine is a LabelSource and ContainerController over in-memory
+// containers whose state changes when they are started and stopped
+type fakeEngine struct {
+	mu     sync.Mutex
+	ctrs   []dlabels.LabeledEntity
+	fail   map[string]bool // container IDs whose start fails
+	log    []string
+	detail ports.Detail
+}
+
+func (f *fakeEngine) add(id, role, state string) {
+	f.ctrs = append(f.ctrs, dlabels.LabeledEntity{
+		Kind:   dlabels.KindContainer,
+		ID:     id,
+		Name:   id,
+		Labels: map[string]string{"bosun.role": role},
+		Meta:   map[string]string{dlabels.MetaState: state},
+	})
+}
+
+func (f *fakeEngine) Snapshot(ctx context.Context, sel ports.Selector) (dlabels.Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.detail = sel.Detail
+	snap := dlabels.Snapshot{TakenAt: time.Now()}
+	for _, c := range f.ctrs {
+		c.Meta = map[string]string{dlabels.MetaState: c.Meta[dlabels.MetaState], dlabels.MetaHealth: c.Meta[dlabels.MetaHealth]}
+		snap.Entities = append(snap.Entities, c)
+	}
+	return snap, nil
+}
+
+func (f *fakeEngine) setState(id, state string) {
+	for i := range f.ctrs {
+		if f.ctrs[i].ID == id {
+			f.ctrs[i].Meta[dlabels.MetaState] = state
+			delete(f.ctrs[i].Meta, dlabels.MetaHealth)
+		}
+	}
+}
+
+func (f *fakeEngine) ContainersUsingVolume(ctx context.Context, volume string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeEngine) Stop(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, "stop "+id)
+	f.setState(id, "exited")
+	return nil
+}
+
+func (f *fakeEngine) StopTimeout(ctx context.Context, id string, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, fmt.Sprintf("stop %s after %s", id, timeout))
+	f.setState(id, "exited")
+	return nil
+}
+
+func (f *fakeEngine) Start(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, "start "+id)
+	if f.fail[id] {
+		return errors.New("port already allocated")
+	}
+	f.setState(id, "running")
+	return nil
+}
+
+func workersConfig(t *testing.T) desired.Config {
+	t.Helper()
+	workers, _ := dselector.Parse("bosun.role=worker")
+	maint, _ := dselector.Parse("bosun.role=maintenance")
+	cfg, err := desired.Config{Groups: []desired.Group{
+		{Name: "workers", Selector: workers, Scale: 2, RestartUnhealthy: true},
+		{Name: "maintenance", Selector: maint, State: desired.StateStopped},
+	}}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestReconcileService_PlanAndApply(t *testing.T) {
+	engine := &fakeEngine{}
+	engine.add("worker-1", "worker", "running")
+	engine.add("worker-2", "worker", "exited")
+	engine.add("worker-3", "worker", "exited")
+	engine.add("maint-1", "maintenance", "running")
+	engine.ctrs[0].Meta[dlabels.MetaHealth] = "unhealthy"
+	svc := &app.ReconcileService{Source: engine, Containers: engine}
+	cfg := workersConfig(t)
+
+	plan, err := svc.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if engine.detail != ports.DetailFull {
+		t.Errorf("Detail = %v, want full for restartUnhealthy", engine.detail)
+	}
+
+	// A dry run changes nothing
+	results, err := svc.Apply(context.Background(), plan, app.ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(results) != 3 || !results[0].Skipped || len(engine.log) != 0 {
+		t.Fatalf("dry run results = %+v, log = %v", results, engine.log)
+	}
+
+	if _, err := svc.Apply(context.Background(), plan, app.ApplyOptions{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []string{"start worker-2", "stop worker-1", "start worker-1", "stop maint-1"}
+	if !reflect.DeepEqual(engine.log, want) {
+		t.Errorf("log = %v, want %v", engine.log, want)
+	}
+
+	// Converged: the next plan is empty
+	plan, err = svc.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected an empty plan after apply, got %+v", plan)
+	}
+}
+
+func TestReconcileService_ApplyContinuesAfterFailure(t *testing.T) {
+	engine := &fakeEngine{fail: map[string]bool{"worker-1": true}}
+	engine.add("worker-1", "worker", "exited")
+	engine.add("worker-2", "worker", "exited")
+	svc := &app.ReconcileService{Source: engine, Containers: engine}
+
+	plan, err := svc.Plan(context.Background(), workersConfig(t))
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	results, err := svc.Apply(context.Background(), plan, app.ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Err == nil || results[1].Err != nil {
+		t.Errorf("results = %+v, want worker-1 failed and worker-2 started", results)
+	}
+}
+
+func TestReconcileService_Run(t *testing.T) {
+	engine := &fakeEngine{}
+	engine.add("worker-1", "worker", "exited")
+	svc := &app.ReconcileService{Source: engine, Containers: engine}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var rounds []app.ReconcileRound
+	err := svc.Run(ctx, workersConfig(t), time.Millisecond, app.ApplyOptions{}, func(r app.ReconcileRound) {
+		rounds = append(rounds, r)
+		if len(rounds) == 1 {
+			// Something stops the worker behind Bosun's back
+			engine.Stop(ctx, "worker-1")
+		}
+		if len(rounds) == 3 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(rounds) != 3 {
+		t.Fatalf("rounds = %d, want 3", len(rounds))
+	}
+	for i, r := range rounds[:2] {
+		if len(r.Results) != 1 || r.Results[0].Action.Type != desired.ActionStart {
+			t.Errorf("round %d results = %+v, want worker-1 started", i, r.Results)
+		}
+	}
+	if n := len(rounds[2].Plan.Actions); n != 0 {
+		t.Errorf("round 3 actions = %d, want none", n)
+	}
+}
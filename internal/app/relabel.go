@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// RelabelService edits the labels of existing containers. Docker labels are
+// immutable, so the container is replaced by a copy carrying the new labels.
+type RelabelService struct {
+	Containers ports.ContainerRecreator
+}
+
+// RelabelOptions controls a label edit.
+type RelabelOptions struct {
+	DryRun bool // report the changes without recreating the container
+}
+
+// Relabel applies edit to the labels of the container with the given name or
+// ID. A container already labeled as edited is left alone and the result has
+// no changes.
+func (s *RelabelService) Relabel(ctx context.Context, ref string, edit drecreate.Edit, opts RelabelOptions) (drecreate.Result, error) {
+	if ref == "" {
+		return drecreate.Result{}, errors.New("no container given")
+	}
+	if edit.Empty() {
+		return drecreate.Result{}, errors.New("no labels to change")
+	}
+	return s.Containers.Recreate(ctx, ref, edit, opts.DryRun)
+}
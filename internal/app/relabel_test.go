@@ -0,0 +1,43 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+)
+
+// fakeRecreator records the recreations asked for
+type fakeRecreator struct {
+	calls []string
+}
+
+func (f *fakeRecreator) Recreate(ctx context.Context, ref string, edit drecreate.Edit, dryRun bool) (drecreate.Result, error) {
+	call := ref
+	if dryRun {
+		call += " (dry run)"
+	}
+	f.calls = append(f.calls, call)
+	return drecreate.Result{Container: ref}, nil
+}
+
+func TestRelabelService(t *testing.T) {
+	ctx := context.Background()
+	rec := &fakeRecreator{}
+	svc := &app.RelabelService{Containers: rec}
+	edit := drecreate.Edit{Set: map[string]string{"bosun.role": "api"}}
+
+	if _, err := svc.Relabel(ctx, "web", edit, app.RelabelOptions{DryRun: true}); err != nil {
+		t.Fatalf("Relabel failed: %v", err)
+	}
+	if _, err := svc.Relabel(ctx, "web", drecreate.Edit{}, app.RelabelOptions{}); err == nil {
+		t.Error("Relabel with an empty edit succeeded")
+	}
+	if _, err := svc.Relabel(ctx, "", edit, app.RelabelOptions{}); err == nil {
+		t.Error("Relabel without a container succeeded")
+	}
+	if len(rec.calls) != 1 || rec.calls[0] != "web (dry run)" {
+		t.Errorf("calls = %v, want one dry run of web", rec.calls)
+	}
+}
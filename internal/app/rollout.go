@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// DefaultRolloutPollInterval is how often a restarted batch is checked when
+// RolloutService.PollInterval is zero.
+const DefaultRolloutPollInterval = time.Second
+
+// RolloutService restarts the containers of a label selection in batches,
+// waiting for each batch to become ready before restarting the next.
+type RolloutService struct {
+	Source     ports.LabelSource
+	Containers ports.ContainerController
+	Inspector  ports.ContainerInspector
+	// PollInterval is how often a batch is checked; zero means
+	// DefaultRolloutPollInterval.
+	PollInterval time.Duration
+}
+
+// RolloutOptions controls a rolling restart.
+type RolloutOptions struct {
+	// BatchTimeout bounds how long a restarted batch may take to become
+	// ready; zero waits indefinitely.
+	BatchTimeout time.Duration
+	DryRun       bool // report the batches without restarting them
+}
+
+// RolloutPlan is the containers a rolling restart restarts, in batches, and
+// the matching ones it leaves alone because they are not running.
+type RolloutPlan struct {
+	Batches [][]dlifecycle.Unit `json:"batches"`
+	Skipped []dlifecycle.Unit   `json:"skipped,omitempty"`
+}
+
+// RolloutEvent is the progress of one container during a rolling restart.
+type RolloutEvent struct {
+	Batch     int // 1-based
+	Batches   int
+	Container string
+	Message   string // e.g. "restarting" or "healthy after 4s"
+}
+
+func (e RolloutEvent) String() string {
+	return fmt.Sprintf("batch %d/%d: %s: %s", e.Batch, e.Batches, e.Container, e.Message)
+}
+
+// Plan takes a snapshot of every container, stopped ones included, and
+// splits the running ones matching sel, sorted by name, into batches of at
+// most maxUnavailable containers. It fails when none match.
+func (s *RolloutService) Plan(ctx context.Context, sel dselector.Selector, maxUnavailable drollout.MaxUnavailable) (RolloutPlan, error) {
+	snap, err := s.Source.Snapshot(ctx, ports.Selector{
+		Prefixes:       []string{""}, // every label, as selectors may use any key
+		IncludeStopped: true,
+		Kinds:          []dlabels.Kind{dlabels.KindContainer},
+		Detail:         ports.DetailExtended,
+	})
+	if err != nil {
+		return RolloutPlan{}, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	var plan RolloutPlan
+	var running []dlifecycle.Unit
+	var errs []error
+	for _, e := range snap.Entities {
+		if !sel.Matches(e) {
+			continue
+		}
+		u, err := dlifecycle.UnitOf(e)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if u.Running {
+			running = append(running, u)
+		} else {
+			plan.Skipped = append(plan.Skipped, u)
+		}
+	}
+	if len(errs) > 0 {
+		return RolloutPlan{}, errors.Join(errs...)
+	}
+	if len(running) == 0 && len(plan.Skipped) == 0 {
+		return RolloutPlan{}, fmt.Errorf("no containers match %s", sel)
+	}
+	byName := func(a, b dlifecycle.Unit) int { return strings.Compare(a.Name, b.Name) }
+	slices.SortFunc(running, byName)
+	slices.SortFunc(plan.Skipped, byName)
+	plan.Batches = drollout.Batches(running, maxUnavailable.Of(len(running)))
+	return plan, nil
+}
+
+// Restart restarts the batches of plan one after another, passing each step
+// to report. A batch is done when all of its containers are ready: healthy,
+// or running when they have no healthcheck. If a container fails to restart,
+// exits, turns unhealthy or is not ready within opts.BatchTimeout, Restart
+// returns an error naming it and leaves the later batches untouched.
+func (s *RolloutService) Restart(ctx context.Context, plan RolloutPlan, opts RolloutOptions, report func(RolloutEvent)) error {
+	total := len(plan.Batches)
+	for i, batch := range plan.Batches {
+		event := func(u dlifecycle.Unit, msg string) RolloutEvent {
+			return RolloutEvent{Batch: i + 1, Batches: total, Container: u.Name, Message: msg}
+		}
+		for _, u := range batch {
+			if opts.DryRun {
+				report(event(u, "would restart"))
+				continue
+			}
+			report(event(u, "restarting"))
+			if err := s.restart(ctx, u); err != nil {
+				return fmt.Errorf("batch %d/%d: %w", i+1, total, err)
+			}
+		}
+		if opts.DryRun {
+			continue
+		}
+		if err := s.wait(ctx, batch, opts.BatchTimeout, func(u dlifecycle.Unit, msg string) { report(event(u, msg)) }); err != nil {
+			return fmt.Errorf("batch %d/%d: %w", i+1, total, err)
+		}
+	}
+	return nil
+}
+
+// restart stops u, honoring its stop timeout, and starts it again
+func (s *RolloutService) restart(ctx context.Context, u dlifecycle.Unit) error {
+	var err error
+	if u.StopTimeout != nil {
+		err = s.Containers.StopTimeout(ctx, u.ID, *u.StopTimeout)
+	} else {
+		err = s.Containers.Stop(ctx, u.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", u.Name, err)
+	}
+	if err := s.Containers.Start(ctx, u.ID); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", u.Name, err)
+	}
+	return nil
+}
+
+// wait polls the containers of batch until all are ready, one of them fails,
+// or timeout passes, reporting each container as it becomes ready
+func (s *RolloutService) wait(ctx context.Context, batch []dlifecycle.Unit, timeout time.Duration, ready func(dlifecycle.Unit, string)) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultRolloutPollInterval
+	}
+	started := time.Now()
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := slices.Clone(batch)
+	last := make(map[string]drollout.Status, len(batch))
+	for {
+		var still []dlifecycle.Unit
+		for _, u := range pending {
+			st, err := s.Inspector.Status(ctx, u.ID)
+			if err != nil {
+				return fmt.Errorf("failed to inspect container %s: %w", u.Name, err)
+			}
+			ok, err := drollout.Check(st)
+			if err != nil {
+				return fmt.Errorf("container %s %w", u.Name, err)
+			}
+			if ok {
+				ready(u, fmt.Sprintf("%s after %s", readyWord(st), time.Since(started).Round(time.Second)))
+				continue
+			}
+			last[u.ID] = st
+			still = append(still, u)
+		}
+		if pending = still; len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			waiting := make([]string, len(pending))
+			for k, u := range pending {
+				waiting[k] = fmt.Sprintf("%s (%s)", u.Name, last[u.ID])
+			}
+			return fmt.Errorf("not ready after %s: %s", timeout, strings.Join(waiting, ", "))
+		case <-ticker.C:
+		}
+	}
+}
+
+// readyWord describes a ready status: "healthy", or "running" without a healthcheck
+func readyWord(st drollout.Status) string {
+	if st.Health == "healthy" {
+		return "healthy"
+	}
+	return "running"
+}
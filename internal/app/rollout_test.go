@@ -0,0 +1,212 @@
+package app_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+)
+
+// scriptedInspector returns the statuses scripted for a container one call
+// after another, repeating the last one
+type scriptedInspector struct {
+	mu     sync.Mutex
+	script map[string][]drollout.Status
+}
+
+func (f *scriptedInspector) Status(ctx context.Context, id string) (drollout.Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.script[id]
+	if len(s) == 0 {
+		return drollout.Status{State: "running"}, nil
+	}
+	if len(s) > 1 {
+		f.script[id] = s[1:]
+	}
+	return s[0], nil
+}
+
+var (
+	starting = drollout.Status{State: "running", Health: "starting"}
+	healthy  = drollout.Status{State: "running", Health: "healthy"}
+)
+
+// apiEngine has four running api containers, a stopped one and a worker
+func apiEngine() *fakeEngine {
+	engine := &fakeEngine{}
+	for _, id := range []string{"api-3", "api-1", "api-4", "api-2"} {
+		engine.add(id, "api", "running")
+	}
+	engine.add("api-5", "api", "exited")
+	engine.add("worker", "worker", "running")
+	engine.ctrs[0].Labels[dlifecycle.LabelStopTimeout] = "10"
+	return engine
+}
+
+func apiPlan(t *testing.T, svc *app.RolloutService, maxUnavailable string) app.RolloutPlan {
+	t.Helper()
+	sel, err := dselector.Parse("bosun.role=api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := drollout.ParseMaxUnavailable(maxUnavailable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := svc.Plan(context.Background(), sel, m)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	return plan
+}
+
+func batchNames(plan app.RolloutPlan) [][]string {
+	var out [][]string
+	for _, b := range plan.Batches {
+		var names []string
+		for _, u := range b {
+			names = append(names, u.Name)
+		}
+		out = append(out, names)
+	}
+	return out
+}
+
+func TestRolloutService_Plan(t *testing.T) {
+	engine := apiEngine()
+	svc := &app.RolloutService{Source: engine}
+
+	plan := apiPlan(t, svc, "2")
+	if want := [][]string{{"api-1", "api-2"}, {"api-3", "api-4"}}; !reflect.DeepEqual(batchNames(plan), want) {
+		t.Errorf("batches = %v, want %v", batchNames(plan), want)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].Name != "api-5" {
+		t.Errorf("skipped = %v, want api-5", plan.Skipped)
+	}
+	if got := plan.Batches[1][0].StopTimeout; got == nil || *got != 10*time.Second {
+		t.Errorf("api-3 stop timeout = %v, want 10s", got)
+	}
+
+	plan = apiPlan(t, svc, "50%")
+	if len(plan.Batches) != 2 {
+		t.Errorf("50%% of 4 gave %d batches, want 2", len(plan.Batches))
+	}
+
+	engine.ctrs[1].Labels[dlifecycle.LabelStopTimeout] = "soon"
+	sel, _ := dselector.Parse("bosun.role=api")
+	if _, err := svc.Plan(context.Background(), sel, drollout.MaxUnavailable{N: 1}); err == nil || !strings.Contains(err.Error(), "api-1") {
+		t.Errorf("Plan with an invalid stop timeout = %v, want an error naming api-1", err)
+	}
+	sel, _ = dselector.Parse("bosun.role=db")
+	if _, err := svc.Plan(context.Background(), sel, drollout.MaxUnavailable{N: 1}); err == nil {
+		t.Error("Plan without matches succeeded")
+	}
+}
+
+func TestRolloutService_Restart(t *testing.T) {
+	engine := apiEngine()
+	inspector := &scriptedInspector{script: map[string][]drollout.Status{
+		"api-1": {starting, starting, healthy},
+		"api-2": {starting, healthy},
+	}}
+	svc := &app.RolloutService{Source: engine, Containers: engine, Inspector: inspector, PollInterval: time.Millisecond}
+
+	var events []string
+	err := svc.Restart(context.Background(), apiPlan(t, svc, "2"), app.RolloutOptions{BatchTimeout: time.Second}, func(e app.RolloutEvent) {
+		events = append(events, e.String())
+	})
+	if err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	want := []string{"stop api-1", "start api-1", "stop api-2", "start api-2", "stop api-3 after 10s", "start api-3", "stop api-4", "start api-4"}
+	if !reflect.DeepEqual(engine.log, want) {
+		t.Errorf("log = %v, want %v", engine.log, want)
+	}
+	wantEvents := []string{
+		"batch 1/2: api-1: restarting", "batch 1/2: api-2: restarting",
+		"batch 1/2: api-2: healthy after", "batch 1/2: api-1: healthy after",
+		"batch 2/2: api-3: restarting", "batch 2/2: api-4: restarting",
+		"batch 2/2: api-3: running after", "batch 2/2: api-4: running after",
+	}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("events = %v, want %v", events, wantEvents)
+	}
+	for i, w := range wantEvents {
+		if !strings.HasPrefix(events[i], w) {
+			t.Errorf("event %d = %q, want prefix %q", i, events[i], w)
+		}
+	}
+}
+
+func TestRolloutService_RestartAborts(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  []drollout.Status
+		wantErr string
+	}{
+		{"unhealthy", []drollout.Status{starting, {State: "running", Health: "unhealthy"}}, "batch 1/2: container api-1 unhealthy"},
+		{"exited", []drollout.Status{{State: "exited", ExitCode: 1}}, "batch 1/2: container api-1 exited with code 1"},
+		{"deadline", []drollout.Status{starting}, "batch 1/2: not ready after 20ms: api-1 (running, starting)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := apiEngine()
+			inspector := &scriptedInspector{script: map[string][]drollout.Status{"api-1": tt.script}}
+			svc := &app.RolloutService{Source: engine, Containers: engine, Inspector: inspector, PollInterval: time.Millisecond}
+
+			err := svc.Restart(context.Background(), apiPlan(t, svc, "2"), app.RolloutOptions{BatchTimeout: 20 * time.Millisecond}, func(app.RolloutEvent) {})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Restart = %v, want %q", err, tt.wantErr)
+			}
+			// the second batch is left untouched
+			if want := []string{"stop api-1", "start api-1", "stop api-2", "start api-2"}; !reflect.DeepEqual(engine.log, want) {
+				t.Errorf("log = %v, want %v", engine.log, want)
+			}
+		})
+	}
+}
+
+func TestRolloutService_RestartStartFails(t *testing.T) {
+	engine := apiEngine()
+	engine.fail = map[string]bool{"api-1": true}
+	svc := &app.RolloutService{Source: engine, Containers: engine, Inspector: &scriptedInspector{}}
+
+	err := svc.Restart(context.Background(), apiPlan(t, svc, "1"), app.RolloutOptions{}, func(app.RolloutEvent) {})
+	if err == nil || !strings.Contains(err.Error(), "batch 1/4: failed to start container api-1") {
+		t.Fatalf("Restart = %v, want a start failure in batch 1/4", err)
+	}
+	if want := []string{"stop api-1", "start api-1"}; !reflect.DeepEqual(engine.log, want) {
+		t.Errorf("log = %v, want %v", engine.log, want)
+	}
+}
+
+func TestRolloutService_RestartDryRun(t *testing.T) {
+	engine := apiEngine()
+	svc := &app.RolloutService{Source: engine, Containers: engine, Inspector: &scriptedInspector{}}
+
+	var events []string
+	err := svc.Restart(context.Background(), apiPlan(t, svc, "3"), app.RolloutOptions{DryRun: true}, func(e app.RolloutEvent) {
+		events = append(events, e.String())
+	})
+	if err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if len(engine.log) != 0 {
+		t.Errorf("dry run touched containers: %v", engine.log)
+	}
+	want := []string{
+		"batch 1/2: api-1: would restart", "batch 1/2: api-2: would restart", "batch 1/2: api-3: would restart",
+		"batch 2/2: api-4: would restart",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
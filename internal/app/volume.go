@@ -0,0 +1,278 @@
+package app
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dvolume "github.com/simone-viozzi/bosun/internal/domain/volume"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+var (
+	// ErrVolumeInUse is returned when restoring over a volume referenced by containers without Force.
+	ErrVolumeInUse = errors.New("volume is in use")
+	// ErrVolumeDiffers is returned when restoring over an unused volume whose
+	// driver, options or labels differ from the archive without Force.
+	ErrVolumeDiffers = errors.New("volume differs from the archive")
+)
+
+// maxManifestSize bounds the manifest read from an archive.
+const maxManifestSize = 1 << 20
+
+// VolumeService exports volumes to portable tar archives and restores them
+// together with their labels.
+type VolumeService struct {
+	Volumes    ports.VolumeStore
+	Containers ports.ContainerController
+	// Source, when set, finds the containers that mount a volume in addition
+	// to those Docker reports, e.g. the stopped ones.
+	Source ports.LabelSource
+	Now    func() time.Time // defaults to time.Now
+}
+
+// RestoreOptions controls how an archive is restored.
+type RestoreOptions struct {
+	Name  string // volume to restore into; defaults to the volume in the manifest
+	Force bool   // overwrite a volume that is referenced by containers or differs from the archive
+}
+
+// RestoreResult is the outcome of a restore.
+type RestoreResult struct {
+	Manifest dvolume.Manifest
+	Volume   string
+	Created  bool     // the volume was (re)created from the manifest
+	Stopped  []string // containers stopped and restarted around the restore
+}
+
+func (s *VolumeService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Export writes the named volume to w as a tar archive: the manifest first,
+// then the volume contents under dvolume.ContentDir.
+func (s *VolumeService) Export(ctx context.Context, name string, w io.Writer) (dvolume.Manifest, error) {
+	v, ok, err := s.Volumes.Inspect(ctx, name)
+	if err != nil {
+		return dvolume.Manifest{}, fmt.Errorf("failed to inspect volume %s: %w", name, err)
+	}
+	if !ok {
+		return dvolume.Manifest{}, fmt.Errorf("volume %s not found", name)
+	}
+
+	m := dvolume.NewManifest(v, s.now())
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	tw := tar.NewWriter(w)
+	hdr := &tar.Header{Name: dvolume.ManifestName, Mode: 0o644, Size: int64(len(b)), ModTime: m.ExportedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return m, err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return m, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.Volumes.Archive(ctx, name, pw))
+	}()
+	err = copyEntries(tw, tar.NewReader(pr))
+	// Unblock the archiver if copying gave up early
+	pr.CloseWithError(err)
+	if err != nil {
+		return m, fmt.Errorf("failed to export volume %s: %w", name, err)
+	}
+	return m, tw.Close()
+}
+
+// Restore recreates a volume from an archive written by Export.
+//
+// A missing volume is created with the driver, options and labels of the
+// manifest. An existing volume with the same definition is overwritten in
+// place. One whose definition differs is only removed and recreated with
+// Force, and only when no container references it: Docker can neither
+// relabel a volume nor remove one that containers still reference. A
+// referenced volume is only overwritten with Force: its running users are
+// stopped, its contents are replaced and they are restarted afterwards.
+func (s *VolumeService) Restore(ctx context.Context, r io.Reader, opts RestoreOptions) (res RestoreResult, err error) {
+	tr := tar.NewReader(r)
+	m, err := readManifest(tr)
+	if err != nil {
+		return res, err
+	}
+	target := m.VolumeAs(opts.Name)
+	res.Manifest, res.Volume = m, target.Name
+
+	cur, exists, err := s.Volumes.Inspect(ctx, target.Name)
+	if err != nil {
+		return res, fmt.Errorf("failed to inspect volume %s: %w", target.Name, err)
+	}
+	var users []dvolume.User
+	inUse := false
+	if exists {
+		if users, inUse, err = s.users(ctx, target.Name); err != nil {
+			return res, err
+		}
+	}
+	same := exists && sameDefinition(cur, target)
+
+	switch {
+	case inUse && !same:
+		return res, fmt.Errorf("volume %s%s differs from the archive and Docker cannot recreate a volume that containers reference; "+
+			"remove them first or restore under another name", target.Name, usedBy(users))
+	case inUse && !opts.Force:
+		return res, fmt.Errorf("volume %s%s: %w", target.Name, usedBy(users), ErrVolumeInUse)
+	case inUse:
+		var ids []string
+		for _, u := range users {
+			if u.Running {
+				ids = append(ids, u.ID)
+			}
+		}
+		var restart func() error
+		res.Stopped, restart, err = stopContainers(ctx, s.Containers, ids)
+		defer func() { err = errors.Join(err, restart()) }()
+		if err != nil {
+			return res, err
+		}
+	case same:
+	case exists && !opts.Force:
+		return res, fmt.Errorf("volume %s: %w", target.Name, ErrVolumeDiffers)
+	default:
+		if exists {
+			if err := s.Volumes.Remove(ctx, target.Name); err != nil {
+				return res, fmt.Errorf("failed to remove volume %s: %w", target.Name, err)
+			}
+		}
+		if err := s.Volumes.Create(ctx, target); err != nil {
+			return res, fmt.Errorf("failed to create volume %s: %w", target.Name, err)
+		}
+		res.Created = true
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := copyEntries(tw, tr)
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	err = s.Volumes.Extract(ctx, target.Name, pr)
+	// Unblock the copy if the extraction gave up early
+	pr.CloseWithError(err)
+	if err != nil {
+		return res, fmt.Errorf("failed to restore volume %s: %w", target.Name, err)
+	}
+	return res, nil
+}
+
+// users returns the containers that mount the volume name, from a snapshot
+// of every container and from Docker, and whether any container references it.
+// Containers only Docker reports are running, as it lists no stopped ones.
+func (s *VolumeService) users(ctx context.Context, name string) ([]dvolume.User, bool, error) {
+	var users []dvolume.User
+	if s.Source != nil {
+		snap, err := s.Source.Snapshot(ctx, ports.Selector{
+			Prefixes:       []string{""}, // every labeled container
+			IncludeStopped: true,
+			Kinds:          []dlabels.Kind{dlabels.KindContainer},
+			Detail:         ports.DetailExtended,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get snapshot: %w", err)
+		}
+		users = dvolume.UsersOf(snap.Entities, name)
+	}
+	running, err := s.Containers.ContainersUsingVolume(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list containers using %s: %w", name, err)
+	}
+	for _, id := range running {
+		i := slices.IndexFunc(users, func(u dvolume.User) bool { return u.ID == id })
+		if i < 0 {
+			users = append(users, dvolume.User{ID: id, Name: id, Running: true})
+		} else {
+			users[i].Running = true
+		}
+	}
+	inUse, err := s.Volumes.InUse(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check whether volume %s is in use: %w", name, err)
+	}
+	return users, inUse || len(users) > 0, nil
+}
+
+// usedBy names users for an error message, e.g. " (used by app, worker)".
+func usedBy(users []dvolume.User) string {
+	if len(users) == 0 {
+		return ""
+	}
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return " (used by " + strings.Join(names, ", ") + ")"
+}
+
+// readManifest reads the manifest, which must be the first archive entry.
+func readManifest(tr *tar.Reader) (dvolume.Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != dvolume.ManifestName {
+		return dvolume.Manifest{}, fmt.Errorf("not a bosun volume archive: %s must be the first entry", dvolume.ManifestName)
+	}
+	b, err := io.ReadAll(io.LimitReader(tr, maxManifestSize))
+	if err != nil {
+		return dvolume.Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return dvolume.ParseManifest(b)
+}
+
+// copyEntries copies the volume entries of tr to tw, rejecting anything
+// outside dvolume.ContentDir.
+func copyEntries(tw *tar.Writer, tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if name != dvolume.ContentDir && !strings.HasPrefix(name, dvolume.ContentDir+"/") {
+			return fmt.Errorf("unexpected archive entry %q outside %s/", hdr.Name, dvolume.ContentDir)
+		}
+		if strings.Contains("/"+name+"/", "/../") {
+			return fmt.Errorf("unsafe archive entry %q", hdr.Name)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// sameDefinition reports whether an existing volume already matches the archived one.
+func sameDefinition(cur, want dvolume.Volume) bool {
+	return (want.Driver == "" || cur.Driver == want.Driver) &&
+		maps.Equal(cur.Labels, want.Labels) &&
+		maps.Equal(cur.Options, want.Options)
+}
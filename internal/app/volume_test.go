@@ -0,0 +1,289 @@
+package app_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dvolume "github.com/simone-viozzi/bosun/internal/domain/volume"
+)
+
+// memVolumes keeps volumes and their contents, as a map of file name to data, in memory
+type memVolumes struct {
+	defs  map[string]dvolume.Volume
+	files map[string]map[string]string
+	users map[string]bool
+	log   *[]string
+}
+
+func newMemVolumes(log *[]string) *memVolumes {
+	return &memVolumes{
+		defs:  map[string]dvolume.Volume{},
+		files: map[string]map[string]string{},
+		users: map[string]bool{},
+		log:   log,
+	}
+}
+
+func (m *memVolumes) Archive(ctx context.Context, name string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: "volume/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		return err
+	}
+	for file, data := range m.files[name] {
+		if err := tw.WriteHeader(&tar.Header{Name: "volume/" + file, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (m *memVolumes) Inspect(ctx context.Context, name string) (dvolume.Volume, bool, error) {
+	v, ok := m.defs[name]
+	return v, ok, nil
+}
+
+func (m *memVolumes) Create(ctx context.Context, v dvolume.Volume) error {
+	*m.log = append(*m.log, "create "+v.Name)
+	m.defs[v.Name] = v
+	return nil
+}
+
+func (m *memVolumes) Remove(ctx context.Context, name string) error {
+	*m.log = append(*m.log, "remove "+name)
+	delete(m.defs, name)
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memVolumes) InUse(ctx context.Context, name string) (bool, error) {
+	return m.users[name], nil
+}
+
+func (m *memVolumes) Extract(ctx context.Context, name string, r io.Reader) error {
+	*m.log = append(*m.log, "extract "+name)
+	files := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimPrefix(hdr.Name, "volume/")] = string(b)
+	}
+	m.files[name] = files
+	return nil
+}
+
+func exportVolume(t *testing.T, vols *memVolumes, name string) []byte {
+	t.Helper()
+	svc := &app.VolumeService{Volumes: vols, Now: func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }}
+	var buf bytes.Buffer
+	if _, err := svc.Export(context.Background(), name, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestVolumeExportRestore_RecreatesWithLabels(t *testing.T) {
+	var log []string
+	vols := newMemVolumes(&log)
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+	vols.files["db"] = map[string]string{"data.sql": "select 1"}
+	archive := exportVolume(t, vols, "db")
+
+	// The volume was lost; restoring brings back both contents and labels
+	delete(vols.defs, "db")
+	delete(vols.files, "db")
+
+	svc := &app.VolumeService{Volumes: vols}
+	res, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !res.Created || res.Volume != "db" {
+		t.Errorf("result = %+v, want db created", res)
+	}
+	if got := vols.defs["db"].Labels["bosun.backup"]; got != "daily" {
+		t.Errorf("bosun.backup = %q, want daily", got)
+	}
+	if got := vols.files["db"]["data.sql"]; got != "select 1" {
+		t.Errorf("data.sql = %q, want select 1", got)
+	}
+}
+
+func TestVolumeRestore_RecreatesOnlyWhenDefinitionDiffers(t *testing.T) {
+	var log []string
+	vols := newMemVolumes(&log)
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+	archive := exportVolume(t, vols, "db")
+	svc := &app.VolumeService{Volumes: vols, Containers: &fakeContainers{log: &log}}
+
+	if _, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if strings.Join(log, ",") != "extract db" {
+		t.Errorf("log = %v, want only an extraction", log)
+	}
+
+	// An unlabeled volume is only replaced with Force
+	log = nil
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local"}
+	_, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{})
+	if !errors.Is(err, app.ErrVolumeDiffers) {
+		t.Fatalf("Expected ErrVolumeDiffers, got %v", err)
+	}
+	if len(log) != 0 {
+		t.Errorf("Expected nothing to be touched, got %v", log)
+	}
+	if _, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{Force: true}); err != nil {
+		t.Fatalf("Restore with Force failed: %v", err)
+	}
+	if strings.Join(log, ",") != "remove db,create db,extract db" {
+		t.Errorf("log = %v, want the unlabeled volume to be recreated", log)
+	}
+}
+
+// usersSnapshot has a running container app and a stopped one backup
+// mounting db, and a container mounting another volume
+func usersSnapshot() *fakeSource {
+	ctr := func(name, state, mounts string) dlabels.LabeledEntity {
+		return dlabels.LabeledEntity{Kind: dlabels.KindContainer, ID: name, Name: name,
+			Meta: map[string]string{"state": state, "mounts": mounts}}
+	}
+	return &fakeSource{snap: dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		ctr("app", "running", "db:/var/lib/db"),
+		ctr("backup", "exited", "db:/backup:ro"),
+		ctr("other", "running", "cache:/cache"),
+	}}}
+}
+
+func TestVolumeRestore_InUse(t *testing.T) {
+	var log []string
+	vols := newMemVolumes(&log)
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+	archive := exportVolume(t, vols, "db")
+
+	// Docker only reports worker, which has no labels and is missing from the snapshot
+	containers := &fakeContainers{users: map[string][]string{"db": {"app", "worker"}}, log: &log}
+	svc := &app.VolumeService{Volumes: vols, Containers: containers, Source: usersSnapshot()}
+
+	_, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{})
+	if !errors.Is(err, app.ErrVolumeInUse) || !strings.Contains(err.Error(), "used by app, backup, worker") {
+		t.Fatalf("Expected ErrVolumeInUse naming the users, got %v", err)
+	}
+	if len(log) != 0 {
+		t.Errorf("Expected nothing to be touched, got %v", log)
+	}
+
+	res, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Restore with Force failed: %v", err)
+	}
+	want := "stop app,stop worker,extract db,start worker,start app"
+	if strings.Join(log, ",") != want {
+		t.Errorf("log = %v, want %s", log, want)
+	}
+	if res.Created {
+		t.Error("Expected the volume in use to be restored in place")
+	}
+}
+
+func TestVolumeRestore_InUseFromSnapshot(t *testing.T) {
+	var log []string
+	vols := newMemVolumes(&log)
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local"}
+	archive := exportVolume(t, vols, "db")
+
+	// Neither Docker filter reports app, which the snapshot shows mounting db
+	svc := &app.VolumeService{Volumes: vols, Containers: &fakeContainers{log: &log}, Source: usersSnapshot()}
+	res, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Restore with Force failed: %v", err)
+	}
+	if want := "stop app,extract db,start app"; strings.Join(log, ",") != want {
+		t.Errorf("log = %v, want %s", log, want)
+	}
+	if !reflect.DeepEqual(res.Stopped, []string{"app"}) {
+		t.Errorf("Stopped = %v, want app", res.Stopped)
+	}
+}
+
+func TestVolumeRestore_InUseWithDifferentLabels(t *testing.T) {
+	var log []string
+	vols := newMemVolumes(&log)
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+	archive := exportVolume(t, vols, "db")
+
+	// Docker cannot relabel db while containers reference it, so even Force refuses
+	vols.defs["db"] = dvolume.Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "weekly"}}
+	vols.users["db"] = true
+	svc := &app.VolumeService{Volumes: vols, Containers: &fakeContainers{log: &log}, Source: usersSnapshot()}
+
+	for _, force := range []bool{false, true} {
+		_, err := svc.Restore(context.Background(), bytes.NewReader(archive), app.RestoreOptions{Force: force})
+		if err == nil || !strings.Contains(err.Error(), "used by app, backup") || !strings.Contains(err.Error(), "remove them first") {
+			t.Errorf("Force=%v: expected a refusal naming the users, got %v", force, err)
+		}
+	}
+	if len(log) != 0 {
+		t.Errorf("Expected nothing to be touched, got %v", log)
+	}
+	if got := vols.defs["db"].Labels["bosun.backup"]; got != "weekly" {
+		t.Errorf("bosun.backup = %q, want the volume left alone", got)
+	}
+}
+
+func TestVolumeRestore_RejectsInvalidArchives(t *testing.T) {
+	manifest := `{"version":1,"volume":"db"}`
+	tests := []struct {
+		name    string
+		entries []string
+	}{
+		{"plain tar", []string{"volume/data"}},
+		{"entry outside volume", []string{dvolume.ManifestName, "etc/passwd"}},
+		{"path traversal", []string{dvolume.ManifestName, "volume/../etc/passwd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, name := range tt.entries {
+				data := "x"
+				if name == dvolume.ManifestName {
+					data = manifest
+				}
+				_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))})
+				_, _ = io.WriteString(tw, data)
+			}
+			_ = tw.Close()
+
+			var log []string
+			svc := &app.VolumeService{Volumes: newMemVolumes(&log)}
+			if _, err := svc.Restore(context.Background(), &buf, app.RestoreOptions{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
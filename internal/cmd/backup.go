@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/backupdir"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockervolumes"
+	"github.com/simone-viozzi/bosun/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// errBackupFailed is returned when at least one volume could not be backed up
+var errBackupFailed = errors.New("one or more backups failed")
+
+// NewBackupCmd creates the backup subcommand
+func NewBackupCmd(conn *connectionOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Label-driven volume backups",
+		Long: "Backs up volumes labeled with bosun.backup (hourly, daily, weekly, monthly or a duration) " +
+			"into a local directory as tar+zstd archives.",
+	}
+
+	cmd.AddCommand(NewBackupRunCmd(conn))
+	cmd.AddCommand(NewBackupListCmd())
+
+	return cmd
+}
+
+// backupRunOptions holds the flags of the backup run subcommand
+type backupRunOptions struct {
+	app.BackupRunOptions
+	dir         string
+	helperImage string
+	every       time.Duration
+}
+
+// NewBackupRunCmd creates the backup run subcommand
+func NewBackupRunCmd(conn *connectionOptions) *cobra.Command {
+	var opts backupRunOptions
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Back up every volume whose schedule is due",
+		Long: "Backs up every volume whose bosun.backup schedule is due according to the latest backup in --dir. " +
+			"Containers mounting a volume labeled bosun.backup.stop=true are stopped during its backup and restarted afterwards. " +
+			"With --every the command keeps running and checks the schedules at that interval.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			source, err := newDockerLabelSource(conn)
+			if err != nil {
+				return err
+			}
+			volumes, err := newDockerVolumes(conn)
+			if err != nil {
+				return err
+			}
+			volumes.HelperImage = opts.helperImage
+			containers, err := newDockerContainers(conn)
+			if err != nil {
+				return err
+			}
+
+			svc := &app.BackupService{
+				Source:     source,
+				Archiver:   volumes,
+				Containers: containers,
+				Target:     backupdir.New(opts.dir),
+			}
+			cmd.SilenceUsage = true
+			return runBackup(ctx, cmd.OutOrStdout(), svc, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.dir, "dir", "", "Directory to store backups in")
+	cmd.Flags().StringSliceVar(&opts.Volumes, "volume", nil, "Only back up this volume (repeatable)")
+	cmd.Flags().StringSliceVar(&opts.Projects, "project", nil, "Only back up volumes of this compose project (repeatable)")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Back up the selected volumes even when not due")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only print what would be backed up")
+	cmd.Flags().StringVar(&opts.helperImage, "helper-image", dockervolumes.DefaultHelperImage, "Image of the throwaway container used to read volumes")
+	cmd.Flags().DurationVar(&opts.every, "every", 0, "Keep running and check schedules at this interval (e.g. 5m)")
+	_ = cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
+
+func runBackup(ctx context.Context, w io.Writer, svc *app.BackupService, opts backupRunOptions) error {
+	if opts.every <= 0 {
+		return runBackupOnce(ctx, w, svc, opts.BackupRunOptions)
+	}
+
+	// A failed round is reported but does not stop the loop
+	ticker := time.NewTicker(opts.every)
+	defer ticker.Stop()
+	for {
+		if err := runBackupOnce(ctx, w, svc, opts.BackupRunOptions); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(w, "backup round failed: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func runBackupOnce(ctx context.Context, w io.Writer, svc *app.BackupService, opts app.BackupRunOptions) error {
+	results, err := svc.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed = true
+			fmt.Fprintf(w, "%s: error: %v\n", r.Job.Volume, r.Err)
+		case r.Skipped && r.Job.Due:
+			fmt.Fprintf(w, "%s: due (%s), dry run\n", r.Job.Volume, r.Job.Schedule.Spec)
+		case r.Skipped:
+			next := r.Job.Last.Add(r.Job.Schedule.Interval)
+			fmt.Fprintf(w, "%s: not due (%s), next after %s\n", r.Job.Volume, r.Job.Schedule.Spec, next.Format(time.RFC3339))
+		default:
+			fmt.Fprintf(w, "%s: backed up to %s (%s)", r.Job.Volume, r.Backup.ID, formatSize(r.Backup.Size))
+			if len(r.Stopped) > 0 {
+				fmt.Fprintf(w, ", restarted %d containers", len(r.Stopped))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No volumes with a backup schedule")
+	}
+
+	if failed {
+		return errBackupFailed
+	}
+	return nil
+}
+
+// NewBackupListCmd creates the backup list subcommand
+func NewBackupListCmd() *cobra.Command {
+	var dir, volume string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stored backups",
+		Long:  "Lists the backups stored in --dir, oldest first.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backups, err := backupdir.New(dir).List(cmd.Context(), volume)
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "VOLUME\tTAKEN\tSIZE\tID")
+			for _, b := range backups {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Volume, b.TakenAt.Format(time.RFC3339), formatSize(b.Size), b.ID)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory backups are stored in")
+	cmd.Flags().StringVar(&volume, "volume", "", "Only list backups of this volume")
+	_ = cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
+
+// formatSize renders a byte count with a binary unit, e.g. "1.5 MiB"
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
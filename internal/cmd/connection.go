@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerconn"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockercontainers"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockervolumes"
+	"github.com/spf13/cobra"
+)
+
+// connectionOptions holds the persistent flags of the root command selecting
+// the Docker daemon; the root command passes them to its subcommands.
+type connectionOptions struct {
+	hosts      []string
+	context    string
+	tlsCertDir string
+	apiVersion string
+	timeout    time.Duration
+}
+
+// addConnectionFlags adds the Docker connection flags to cmd and its subcommands
+func addConnectionFlags(cmd *cobra.Command, conn *connectionOptions) {
+	f := cmd.PersistentFlags()
+	f.StringArrayVarP(&conn.hosts, "host", "H", nil, "Docker daemon endpoint (unix://, tcp://, ssh://) or context name; 'labels snapshot' accepts several, as NAME=ENDPOINT")
+	f.StringVar(&conn.context, "context", "", "Docker CLI context (default $DOCKER_CONTEXT or the current context)")
+	f.StringVar(&conn.tlsCertDir, "tls-cert-dir", "", "Directory with ca.pem, cert.pem and key.pem for a tcp:// host")
+	f.StringVar(&conn.apiVersion, "api-version", "", "Docker API version (default negotiated with the daemon)")
+	f.DurationVar(&conn.timeout, "timeout", 0, "Timeout for dialing the daemon and the TLS handshake; API calls are not bounded (default none)")
+	cmd.MarkFlagsMutuallyExclusive("host", "context")
+	_ = cmd.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, _ := dockerconn.Contexts(dockerconn.Config{})
+		return append([]string{dockerconn.DefaultContext}, names...), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// dockerConfig returns the connection settings for commands talking to a single daemon
+func (o *connectionOptions) dockerConfig() (dockerconn.Config, error) {
+	switch len(o.hosts) {
+	case 0:
+		cfg := o.config()
+		cfg.Context = o.context
+		return cfg, nil
+	case 1:
+		_, cfg := o.hostConfig(o.hosts[0])
+		return cfg, nil
+	}
+	return dockerconn.Config{}, errors.New("--host can only be given once for this command")
+}
+
+// config returns the settings shared by every host
+func (o *connectionOptions) config() dockerconn.Config {
+	return dockerconn.Config{
+		TLSCertDir: o.tlsCertDir,
+		APIVersion: o.apiVersion,
+		Timeout:    o.timeout,
+	}
+}
+
+// hostConfig maps a --host value onto connection settings and the name to
+// record for it. The value is an endpoint or a context name, optionally
+// prefixed with NAME=.
+func (o *connectionOptions) hostConfig(v string) (string, dockerconn.Config) {
+	name, target := "", v
+	if n, t, ok := strings.Cut(v, "="); ok && n != "" && !strings.Contains(n, "://") {
+		name, target = n, t
+	}
+	if name == "" {
+		name = target
+	}
+
+	cfg := o.config()
+	if strings.Contains(target, "://") {
+		cfg.Host = target
+	} else {
+		cfg.Context = target
+	}
+	return name, cfg
+}
+
+// newDockerLabelSource connects the label source to the selected daemon
+func newDockerLabelSource(conn *connectionOptions) (*dockerlabels.DockerLabelSource, error) {
+	cfg, err := conn.dockerConfig()
+	if err != nil {
+		return nil, err
+	}
+	source, err := dockerlabels.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w\nIs Docker running?", err)
+	}
+	return source, nil
+}
+
+// newDockerVolumes connects the volume adapter to the selected daemon
+func newDockerVolumes(conn *connectionOptions) (*dockervolumes.DockerVolumes, error) {
+	cfg, err := conn.dockerConfig()
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := dockervolumes.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w\nIs Docker running?", err)
+	}
+	return volumes, nil
+}
+
+// newDockerContainers connects the container adapter to the selected daemon
+func newDockerContainers(conn *connectionOptions) (*dockercontainers.DockerContainers, error) {
+	cfg, err := conn.dockerConfig()
+	if err != nil {
+		return nil, err
+	}
+	containers, err := dockercontainers.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w\nIs Docker running?", err)
+	}
+	return containers, nil
+}
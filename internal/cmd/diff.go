@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/spf13/cobra"
+)
+
+// NewDiffCmd creates the diff subcommand
+func NewDiffCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "diff OLD.json NEW.json",
+		Short: "Compare two label snapshots",
+		Long: "Compares two snapshots written by 'bosun labels snapshot' and reports added and removed entities " +
+			"and changed labels and metadata per entity.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(cmd.OutOrStdout(), args[0], args[1], output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func runDiff(w io.Writer, oldPath, newPath, output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %q (expected text or json)", output)
+	}
+
+	older, err := readSnapshotFile(oldPath)
+	if err != nil {
+		return err
+	}
+	newer, err := readSnapshotFile(newPath)
+	if err != nil {
+		return err
+	}
+
+	d := dlabels.Diff(older, newer)
+
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		// Encode empty lists as [] rather than null
+		if d.Added == nil {
+			d.Added = []dlabels.LabeledEntity{}
+		}
+		if d.Removed == nil {
+			d.Removed = []dlabels.LabeledEntity{}
+		}
+		if d.Changed == nil {
+			d.Changed = []dlabels.EntityDiff{}
+		}
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	}
+
+	writeDiffText(w, d)
+	return nil
+}
+
+// readSnapshotFile decodes a snapshot as written by the snapshot command
+func readSnapshotFile(path string) (dlabels.Snapshot, error) {
+	var snap dlabels.Snapshot
+	f, err := os.Open(path)
+	if err != nil {
+		return snap, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// writeDiffText prints the diff in a human-readable form:
+// "+" added entity, "-" removed entity, "~" changed entity followed by its key changes.
+func writeDiffText(w io.Writer, d dlabels.SnapshotDiff) {
+	if d.Empty() {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+
+	for _, e := range d.Added {
+		fmt.Fprintf(w, "+ %s %s (%s)\n", e.Kind, e.Name, e.ID)
+		for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+			fmt.Fprintf(w, "    label %s=%q\n", k, e.Labels[k])
+		}
+	}
+	for _, e := range d.Removed {
+		fmt.Fprintf(w, "- %s %s (%s)\n", e.Kind, e.Name, e.ID)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "~ %s %s (%s)\n", c.Kind, c.Name, c.ID)
+		writeKeyChanges(w, "label", c.Labels)
+		writeKeyChanges(w, "meta", c.Meta)
+	}
+}
+
+func writeKeyChanges(w io.Writer, what string, changes []dlabels.KeyChange) {
+	for _, kc := range changes {
+		switch kc.Type {
+		case dlabels.KeyAdded:
+			fmt.Fprintf(w, "    %s +%s=%q\n", what, kc.Key, kc.New)
+		case dlabels.KeyRemoved:
+			fmt.Fprintf(w, "    %s -%s=%q\n", what, kc.Key, kc.Old)
+		case dlabels.KeyModified:
+			fmt.Fprintf(w, "    %s ~%s: %q -> %q\n", what, kc.Key, kc.Old, kc.New)
+		}
+	}
+}
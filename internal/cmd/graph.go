@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	dgraph "github.com/simone-viozzi/bosun/internal/domain/graph"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+// graphOptions holds the flags shared by the graph command and its subcommands
+type graphOptions struct {
+	includeStopped bool
+	projects       []string
+	selector       string
+	from           string
+	output         string
+}
+
+// NewGraphCmd creates the graph command
+func NewGraphCmd(conn *connectionOptions) *cobra.Command {
+	var opts graphOptions
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Show how containers, volumes and networks depend on each other",
+		Long: "Builds a graph of compose projects, services, containers, volumes and networks: projects contain services, " +
+			"services contain containers, and containers mount volumes and attach to networks. " +
+			"All labeled entities are included, not only those with Bosun labels; volumes and networks without labels " +
+			"appear as implicit (dashed) nodes when used. --output selects dot (Graphviz), mermaid or json. " +
+			"--from reads a snapshot saved with 'bosun labels snapshot --detail extended' instead of Docker; " +
+			"--project, --selector and --stopped filter it the same way.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGraph(cmd.Context(), cmd.OutOrStdout(), conn, opts)
+		},
+	}
+
+	f := cmd.PersistentFlags()
+	f.BoolVar(&opts.includeStopped, "stopped", false, "Include stopped containers")
+	f.StringSliceVar(&opts.projects, "project", nil, "Only include entities of this compose project (repeatable)")
+	f.StringVarP(&opts.selector, "selector", "l", "", "Label selector restricting the entities, e.g. 'bosun.backup'")
+	f.StringVar(&opts.from, "from", "", "Read the snapshot from this file instead of Docker")
+	f.StringVarP(&opts.output, "output", "o", "", "Output format: dot, mermaid or json for the graph (default dot), text or json for queries (default text)")
+
+	cmd.AddCommand(newGraphQueryCmd(conn, &opts, "dependents", "Show the direct dependents of a node",
+		"Prints the nodes with an edge to KIND/NAME: the containers mounting a volume or attached to a network, "+
+			"the service of a container, the project of a service.",
+		(*dgraph.Graph).Dependents))
+	cmd.AddCommand(newGraphQueryCmd(conn, &opts, "impact", "Show what breaks if a node is removed",
+		"Prints every node depending on KIND/NAME directly or transitively, e.g. for a network the containers "+
+			"attached to it, their services and their projects.",
+		(*dgraph.Graph).Impact))
+
+	return cmd
+}
+
+// newGraphQueryCmd creates a graph subcommand printing the nodes returned by query for its argument
+func newGraphQueryCmd(conn *connectionOptions, opts *graphOptions, name, short, long string, query func(*dgraph.Graph, dgraph.NodeID) []dgraph.Node) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " KIND/NAME",
+		Short: short,
+		Long:  long + " KIND is project, service, container, volume or network; services are named PROJECT/SERVICE.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, ok := dgraph.ParseID(args[0])
+			if !ok {
+				return fmt.Errorf("invalid node %q (expected KIND/NAME, e.g. volume/db-data)", args[0])
+			}
+			if opts.output != "" && opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format %q (expected text or json)", opts.output)
+			}
+			g, err := buildGraph(cmd.Context(), conn, *opts)
+			if err != nil {
+				return err
+			}
+			if _, ok := g.Node(id); !ok {
+				return fmt.Errorf("%s not found", id)
+			}
+			return writeNodes(cmd.OutOrStdout(), query(g, id), opts.output)
+		},
+	}
+}
+
+func runGraph(ctx context.Context, w io.Writer, conn *connectionOptions, opts graphOptions) error {
+	g, err := buildGraph(ctx, conn, opts)
+	if err != nil {
+		return err
+	}
+	switch opts.output {
+	case "", "dot":
+		return g.WriteDOT(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	case "json":
+		return writeJSON(w, g)
+	}
+	return fmt.Errorf("unsupported output format %q (expected dot, mermaid or json)", opts.output)
+}
+
+// buildGraph takes a snapshot with mounts and networks, or reads it from opts.from, and builds its graph
+func buildGraph(ctx context.Context, conn *connectionOptions, opts graphOptions) (*dgraph.Graph, error) {
+	labelSelector, err := dselector.Parse(opts.selector)
+	if err != nil {
+		return nil, err
+	}
+	if opts.from != "" {
+		snap, err := readSnapshotFile(opts.from)
+		if err != nil {
+			return nil, err
+		}
+		return dgraph.Build(filterSnapshot(snap, opts, labelSelector)), nil
+	}
+
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := source.Snapshot(ctx, ports.Selector{
+		Prefixes:       []string{""}, // every label, so entities without Bosun labels are included
+		IncludeStopped: opts.includeStopped,
+		ProjectFilter:  opts.projects,
+		LabelSelector:  labelSelector,
+		Detail:         ports.DetailExtended,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return dgraph.Build(snap), nil
+}
+
+// filterSnapshot applies --project, --selector and --stopped to a saved snapshot,
+// keeping what a snapshot taken from Docker with the same flags would contain.
+// Containers without a recorded state are kept.
+func filterSnapshot(snap dlabels.Snapshot, opts graphOptions, labelSelector dselector.Selector) dlabels.Snapshot {
+	out := dlabels.Snapshot{TakenAt: snap.TakenAt}
+	for _, e := range snap.Entities {
+		switch {
+		case len(opts.projects) > 0 && !slices.Contains(opts.projects, e.Meta[dlabels.MetaComposeProject]):
+		case e.Kind == dlabels.KindContainer && !opts.includeStopped && isStopped(e):
+		case !labelSelector.Matches(e):
+		default:
+			out.Entities = append(out.Entities, e)
+		}
+	}
+	return out
+}
+
+// isStopped reports whether the recorded state of container e is one Docker
+// leaves out of a listing without --all.
+func isStopped(e dlabels.LabeledEntity) bool {
+	switch e.Meta[dlabels.MetaState] {
+	case "", "running", "paused", "restarting":
+		return false
+	}
+	return true
+}
+
+// writeNodes prints one KIND/NAME per line, or the nodes as JSON
+func writeNodes(w io.Writer, nodes []dgraph.Node, output string) error {
+	if output == "json" {
+		if nodes == nil {
+			nodes = []dgraph.Node{}
+		}
+		return writeJSON(w, nodes)
+	}
+	for _, n := range nodes {
+		fmt.Fprintln(w, n.ID)
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
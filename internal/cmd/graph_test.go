@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func TestGraphCmd_FromAppliesFilters(t *testing.T) {
+	ctr := func(name, project, state string) dlabels.LabeledEntity {
+		return dlabels.LabeledEntity{Kind: dlabels.KindContainer, ID: name, Name: name,
+			Labels: map[string]string{"bosun.role": name},
+			Meta:   map[string]string{dlabels.MetaComposeProject: project, dlabels.MetaState: state}}
+	}
+	snap := dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		ctr("web", "shop", "running"),
+		ctr("worker", "shop", "exited"),
+		ctr("blog", "blog", "running"),
+	}}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"container/blog", "container/web"}},
+		{[]string{"--stopped"}, []string{"container/blog", "container/web", "container/worker"}},
+		{[]string{"--project", "shop", "--stopped"}, []string{"container/web", "container/worker"}},
+		{[]string{"--selector", "bosun.role=blog"}, []string{"container/blog"}},
+	}
+	for _, tt := range tests {
+		args := append([]string{"graph", "-o", "json", "--from", path}, tt.args...)
+		stdout, _, err := execute(t, args...)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		var g struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Kind string `json:"kind"`
+			} `json:"nodes"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &g); err != nil {
+			t.Fatalf("%v: invalid JSON: %v", tt.args, err)
+		}
+		var got []string
+		for _, n := range g.Nodes {
+			if n.Kind == "container" {
+				got = append(got, n.ID)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%v: containers = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
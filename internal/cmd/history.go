@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/storage"
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+// historyTimeLayouts are the accepted forms of a point in time, tried in order.
+// Forms without a zone are in local time.
+var historyTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// defaultHistoryDir returns $XDG_STATE_HOME/bosun/snapshots, or
+// ~/.local/state/bosun/snapshots when XDG_STATE_HOME is unset.
+func defaultHistoryDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "bosun", "snapshots")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".bosun", "snapshots")
+	}
+	return filepath.Join(home, ".local", "state", "bosun", "snapshots")
+}
+
+// NewHistoryCmd creates the history subcommand
+func NewHistoryCmd() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List saved label snapshots",
+		Long: "Lists the snapshots saved with 'bosun labels snapshot --save', oldest first. " +
+			"Use 'history show' to print one of them and 'history prune' to delete old ones.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryList(cmd.Context(), cmd.OutOrStdout(), storage.NewSnapshotDir(dir))
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&dir, "history-dir", defaultHistoryDir(), "Directory of the snapshot history")
+
+	cmd.AddCommand(newHistoryShowCmd(&dir))
+	cmd.AddCommand(newHistoryPruneCmd(&dir))
+
+	return cmd
+}
+
+func runHistoryList(ctx context.Context, w io.Writer, store ports.SnapshotStore) error {
+	history, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Fprintln(w, "No saved snapshots")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTAKEN\tSIZE")
+	for _, s := range history {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.ID, s.TakenAt.Local().Format(time.RFC3339), formatSize(s.Size))
+	}
+	return tw.Flush()
+}
+
+// newHistoryShowCmd creates the history show subcommand
+func newHistoryShowCmd(dir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <ID|TIME>",
+		Short: "Print a saved snapshot",
+		Long: "Prints a saved snapshot as JSON, selected by its ID or by a point in time such as " +
+			"2025-03-11 or 2025-03-11 14:30, in which case the latest snapshot taken at or before it is shown. " +
+			"The output can be passed to 'bosun labels diff'.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryShow(cmd.Context(), cmd.OutOrStdout(), storage.NewSnapshotDir(*dir), args[0])
+		},
+	}
+}
+
+func runHistoryShow(ctx context.Context, w io.Writer, store ports.SnapshotStore, ref string) error {
+	id := ref
+	if at, ok := parseHistoryTime(ref); ok {
+		history, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+		info, ok := dlabels.SnapshotAt(history, at)
+		if !ok {
+			return fmt.Errorf("no snapshot saved at or before %s", at.Format(time.RFC3339))
+		}
+		id = info.ID
+	}
+
+	snap, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+// parseHistoryTime parses ref as a point in time in one of historyTimeLayouts.
+func parseHistoryTime(ref string) (time.Time, bool) {
+	for _, layout := range historyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, ref, time.Local); err == nil {
+			if layout == "2006-01-02" {
+				// A day means its end: the state that day was left in
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// newHistoryPruneCmd creates the history prune subcommand
+func newHistoryPruneCmd(dir *string) *cobra.Command {
+	var policy dlabels.RetentionPolicy
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old saved snapshots",
+		Long: "Deletes saved snapshots older than --max-age, never touching the --keep-last most recent ones. " +
+			"Without --max-age every snapshot but the --keep-last most recent is deleted.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if policy.KeepLast <= 0 && policy.MaxAge <= 0 {
+				return fmt.Errorf("at least one of --keep-last or --max-age is required")
+			}
+			removed, err := storage.NewSnapshotDir(*dir).Prune(cmd.Context(), policy)
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %d snapshots\n", len(removed))
+			return err
+		},
+	}
+
+	cmd.Flags().IntVar(&policy.KeepLast, "keep-last", 0, "Always keep this many most recent snapshots")
+	cmd.Flags().DurationVar(&policy.MaxAge, "max-age", 0, "Delete snapshots older than this (e.g. 720h)")
+
+	return cmd
+}
@@ -5,7 +5,7 @@ import (
 )
 
 // NewLabelsCmd creates the labels subcommand
-func NewLabelsCmd() *cobra.Command {
+func NewLabelsCmd(conn *connectionOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "labels",
 		Short: "Label operations",
@@ -13,7 +13,13 @@ func NewLabelsCmd() *cobra.Command {
 	}
 
 	// Add subcommands
-	cmd.AddCommand(NewSnapshotCmd())
+	cmd.AddCommand(NewSnapshotCmd(conn))
+	cmd.AddCommand(NewWatchCmd(conn))
+	cmd.AddCommand(NewDiffCmd())
+	cmd.AddCommand(NewLintCmd(conn))
+	cmd.AddCommand(NewHistoryCmd())
+	cmd.AddCommand(NewLabelsSetCmd(conn))
+	cmd.AddCommand(NewLabelsUnsetCmd(conn))
 
 	return cmd
 }
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/spf13/cobra"
+)
+
+// lifecycleLong describes the ordering shared by up, down and restart
+const lifecycleLong = "Containers are ordered by their " + dlifecycle.LabelDependsOn + " label, a comma-separated list of " +
+	"compose services of the same project or container names, and by " + dlifecycle.LabelOrder + ": a container starts " +
+	"after every selected container with a lower order. Dependencies on containers outside the selection are reported " +
+	"and ignored; cycles and invalid labels are errors. Stops run in reverse order, waiting " + dlifecycle.LabelStopTimeout +
+	" (e.g. 30s) before killing a container. The first failure aborts the command."
+
+// lifecycleOptions holds the flags shared by up, down and restart
+type lifecycleOptions struct {
+	selector string
+	opts     app.LifecycleOptions
+}
+
+// lifecycleRun is the LifecycleService method a command executes
+type lifecycleRun func(*app.LifecycleService, context.Context, dlifecycle.Order, app.LifecycleOptions, func(app.LifecycleStep)) error
+
+// NewUpCmd creates the up command
+func NewUpCmd(conn *connectionOptions) *cobra.Command {
+	return newLifecycleCmd(conn, "up", "Start the containers matching a label selector in dependency order",
+		"Starts the stopped containers matching --selector, dependencies first. ", (*app.LifecycleService).Up)
+}
+
+// NewDownCmd creates the down command
+func NewDownCmd(conn *connectionOptions) *cobra.Command {
+	return newLifecycleCmd(conn, "down", "Stop the containers matching a label selector in reverse dependency order",
+		"Stops the running containers matching --selector, dependents first. ", (*app.LifecycleService).Down)
+}
+
+// NewRestartCmd creates the restart command
+func NewRestartCmd(conn *connectionOptions) *cobra.Command {
+	return newLifecycleCmd(conn, "restart", "Restart the containers matching a label selector in dependency order",
+		"Stops the running containers matching --selector like 'bosun down', then starts all of them like 'bosun up'. ",
+		(*app.LifecycleService).Restart)
+}
+
+func newLifecycleCmd(conn *connectionOptions, use, short, long string, run lifecycleRun) *cobra.Command {
+	var opts lifecycleOptions
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  long + lifecycleLong,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.selector == "" {
+				return errors.New("--selector is required")
+			}
+			sel, err := dselector.Parse(opts.selector)
+			if err != nil {
+				return err
+			}
+			svc, err := newLifecycleService(conn)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return runLifecycle(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), svc, sel, opts.opts, run)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "", "Label selector of the containers, e.g. 'bosun.stack=shop' (required)")
+	cmd.Flags().BoolVar(&opts.opts.DryRun, "dry-run", false, "Only print the steps that would be taken")
+
+	return cmd
+}
+
+// newLifecycleService connects to Docker
+func newLifecycleService(conn *connectionOptions) (*app.LifecycleService, error) {
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := newDockerContainers(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &app.LifecycleService{Source: source, Containers: containers}, nil
+}
+
+func runLifecycle(ctx context.Context, w, errW io.Writer, svc *app.LifecycleService, sel dselector.Selector, opts app.LifecycleOptions, run lifecycleRun) error {
+	order, err := svc.Order(ctx, sel)
+	if err != nil {
+		return err
+	}
+	for _, o := range order.Outside {
+		fmt.Fprintf(errW, "warning: %s\n", o)
+	}
+	return run(svc, ctx, order, opts, func(s app.LifecycleStep) {
+		fmt.Fprintln(w, s)
+	})
+}
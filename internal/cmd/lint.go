@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/schemafile"
+	"github.com/simone-viozzi/bosun/internal/domain/schema"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+// errLintFailed is returned when the snapshot has schema errors, so the process exits non-zero
+var errLintFailed = errors.New("label schema validation failed")
+
+// lintOptions holds the flags of the lint subcommand
+type lintOptions struct {
+	schemaPath     string
+	includeStopped bool
+	projects       []string
+	composeFiles   []string
+	output         string
+}
+
+// NewLintCmd creates the lint subcommand
+func NewLintCmd(conn *connectionOptions) *cobra.Command {
+	var opts lintOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Validate labels against a schema",
+		Long: "Validates the labels of all Docker entities against a YAML or JSON label schema. " +
+			"With --from-compose the compose files are validated offline, before any container exists. " +
+			"Exits non-zero if any error-level violation is found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format %q (expected text or json)", opts.output)
+			}
+
+			s, err := schemafile.Load(opts.schemaPath)
+			if err != nil {
+				return err
+			}
+			source, err := newLabelSource(conn, opts.composeFiles)
+			if err != nil {
+				return err
+			}
+
+			// Violations are a result, not a usage error
+			cmd.SilenceUsage = true
+			return runLint(ctx, cmd.OutOrStdout(), source, s, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.schemaPath, "schema", "", "Path to the label schema (YAML or JSON)")
+	cmd.Flags().BoolVar(&opts.includeStopped, "stopped", false, "Include stopped containers")
+	cmd.Flags().StringSliceVar(&opts.projects, "project", nil, "Only include entities of this compose project (repeatable)")
+	cmd.Flags().StringSliceVar(&opts.composeFiles, "from-compose", nil, "Validate this compose file instead of Docker (repeatable)")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "Output format: text or json")
+	_ = cmd.MarkFlagRequired("schema")
+
+	return cmd
+}
+
+func runLint(ctx context.Context, w io.Writer, source ports.LabelSource, s schema.Schema, opts lintOptions) error {
+	selector := ports.Selector{
+		Prefixes:       s.Prefixes(),
+		IncludeStopped: opts.includeStopped,
+		ProjectFilter:  opts.projects,
+	}
+
+	snapshot, err := source.Snapshot(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	violations := s.Validate(snapshot)
+
+	if opts.output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if violations == nil {
+			violations = []schema.Violation{}
+		}
+		if err := enc.Encode(violations); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	} else {
+		for _, v := range violations {
+			fmt.Fprintln(w, v)
+		}
+		fmt.Fprintf(w, "%d entities checked, %d violations\n", len(snapshot.Entities), len(violations))
+	}
+
+	if schema.HasErrors(violations) {
+		return errLintFailed
+	}
+	return nil
+}
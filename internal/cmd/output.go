@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	"gopkg.in/yaml.v3"
+)
+
+// snapshotFormats lists the values accepted by the snapshot --output flag
+var snapshotFormats = []string{"json", "yaml", "table", "wide", "ndjson", "template"}
+
+// maxTableLabels bounds the width of the LABELS column of the table format
+const maxTableLabels = 60
+
+// snapshotWriter renders a snapshot in one of snapshotFormats
+type snapshotWriter struct {
+	format string
+	tmpl   *template.Template // for the template format
+}
+
+// newSnapshotWriter validates the output flags. A template alone selects the
+// template format.
+func newSnapshotWriter(format, tmpl string) (*snapshotWriter, error) {
+	if tmpl != "" && format == "" {
+		format = "template"
+	}
+	if format == "" {
+		format = "json"
+	}
+	if !slices.Contains(snapshotFormats, format) {
+		return nil, fmt.Errorf("unsupported output format %q (expected %s)", format, strings.Join(snapshotFormats, ", "))
+	}
+
+	sw := &snapshotWriter{format: format}
+	switch {
+	case format == "template" && tmpl == "":
+		return nil, fmt.Errorf("--output template requires --template")
+	case format != "template" && tmpl != "":
+		return nil, fmt.Errorf("--template requires --output template, got %q", format)
+	case format == "template":
+		t, err := template.New("entity").Funcs(templateFuncs).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		sw.tmpl = t
+	}
+	return sw, nil
+}
+
+// templateFuncs are available in --template in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v any) string {
+		switch v := v.(type) {
+		case []string:
+			return strings.Join(v, sep)
+		case map[string]string:
+			return strings.Join(labelPairs(v, ""), sep)
+		}
+		return fmt.Sprint(v)
+	},
+}
+
+// Write renders snap to w.
+func (sw *snapshotWriter) Write(w io.Writer, snap dlabels.Snapshot) error {
+	switch sw.format {
+	case "yaml":
+		return writeYAML(w, snap)
+	case "table":
+		return writeTable(w, snap, false)
+	case "wide":
+		return writeTable(w, snap, true)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, e := range snap.Entities {
+			if err := enc.Encode(e); err != nil {
+				return fmt.Errorf("failed to encode JSON: %w", err)
+			}
+		}
+		return nil
+	case "template":
+		for _, e := range snap.Entities {
+			if err := sw.tmpl.Execute(w, e); err != nil {
+				return fmt.Errorf("failed to execute template: %w", err)
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+// writeYAML renders v as block-style YAML with the same keys as its JSON form
+func writeYAML(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	// JSON is YAML, so decoding it keeps the JSON keys and their order
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow and quoting styles a JSON document parses into;
+// the encoder still quotes strings that would otherwise change type.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// writeTable renders one row per entity. The wide form adds the ID, image or
+// driver, and shows labels in full.
+func writeTable(w io.Writer, snap dlabels.Snapshot, wide bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if wide {
+		fmt.Fprintln(tw, "KIND\tNAME\tID\tPROJECT\tSERVICE\tIMAGE/DRIVER\tLABELS")
+	} else {
+		fmt.Fprintln(tw, "KIND\tNAME\tPROJECT\tSERVICE\tLABELS")
+	}
+
+	for _, e := range snap.Entities {
+		project := orDash(e.Meta[dlabels.MetaComposeProject])
+		service := orDash(e.Meta[dlabels.MetaComposeService])
+		if !wide {
+			labels := strings.Join(labelPairs(e.Labels, dlabels.DefaultLabelPrefix), ",")
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Kind, e.Name, project, service, truncate(labels, maxTableLabels))
+			continue
+		}
+		source := e.Meta[dlabels.MetaImage]
+		if source == "" {
+			source = e.Meta[dlabels.MetaDriver]
+		}
+		labels := strings.Join(labelPairs(e.Labels, ""), ",")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Kind, e.Name, shortID(e.ID), project, service, orDash(source), labels)
+	}
+	return tw.Flush()
+}
+
+// labelPairs returns the labels as sorted key=value pairs, with trim removed
+// from the start of each key
+func labelPairs(labels map[string]string, trim string) []string {
+	out := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		out = append(out, strings.TrimPrefix(k, trim)+"="+labels[k])
+	}
+	return out
+}
+
+// shortID abbreviates 64 character Docker IDs like the docker CLI does
+func shortID(id string) string {
+	if len(id) == 64 {
+		return id[:12]
+	}
+	return id
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+const webID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func outputSnapshot() dlabels.Snapshot {
+	return dlabels.Snapshot{TakenAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Entities: []dlabels.LabeledEntity{
+		{
+			Kind:   dlabels.KindContainer,
+			ID:     webID,
+			Name:   "web",
+			Labels: map[string]string{"bosun.role": "api", "bosun.backup": "daily"},
+			Meta:   map[string]string{"compose.project": "shop", "compose.service": "web", "image": "nginx:1"},
+		},
+		{
+			Kind:   dlabels.KindVolume,
+			ID:     "data",
+			Name:   "data",
+			Labels: map[string]string{"bosun.backup": "true"},
+			Meta:   map[string]string{"driver": "local"},
+		},
+	}}
+}
+
+func TestSnapshotWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		tmpl   string
+		want   string
+	}{
+		{
+			name:   "json",
+			format: "",
+			want: `{
+  "schemaVersion": 1,
+  "takenAt": "2025-01-02T03:04:05Z",
+  "entities": [
+    {
+      "kind": "container",
+      "id": "` + webID + `",
+      "name": "web",
+      "labels": {
+        "bosun.backup": "daily",
+        "bosun.role": "api"
+      },
+      "meta": {
+        "compose.project": "shop",
+        "compose.service": "web",
+        "image": "nginx:1"
+      }
+    },
+    {
+      "kind": "volume",
+      "id": "data",
+      "name": "data",
+      "labels": {
+        "bosun.backup": "true"
+      },
+      "meta": {
+        "driver": "local"
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			want: `schemaVersion: 1
+takenAt: "2025-01-02T03:04:05Z"
+entities:
+  - kind: container
+    id: ` + webID + `
+    name: web
+    labels:
+      bosun.backup: daily
+      bosun.role: api
+    meta:
+      compose.project: shop
+      compose.service: web
+      image: nginx:1
+  - kind: volume
+    id: data
+    name: data
+    labels:
+      bosun.backup: "true"
+    meta:
+      driver: local
+`,
+		},
+		{
+			name:   "table",
+			format: "table",
+			want: `KIND       NAME  PROJECT  SERVICE  LABELS
+container  web   shop     web      backup=daily,role=api
+volume     data  -        -        backup=true
+`,
+		},
+		{
+			name:   "wide",
+			format: "wide",
+			want: `KIND       NAME  ID            PROJECT  SERVICE  IMAGE/DRIVER  LABELS
+container  web   0123456789ab  shop     web      nginx:1       bosun.backup=daily,bosun.role=api
+volume     data  data          -        -        local         bosun.backup=true
+`,
+		},
+		{
+			name:   "ndjson",
+			format: "ndjson",
+			want: `{"kind":"container","id":"` + webID + `","name":"web","labels":{"bosun.backup":"daily","bosun.role":"api"},"meta":{"compose.project":"shop","compose.service":"web","image":"nginx:1"}}
+{"kind":"volume","id":"data","name":"data","labels":{"bosun.backup":"true"},"meta":{"driver":"local"}}
+`,
+		},
+		{
+			name:   "template",
+			format: "template",
+			tmpl:   `{{.Name}} {{join ";" .Labels}}`,
+			want:   "web bosun.backup=daily;bosun.role=api\ndata bosun.backup=true\n",
+		},
+		{
+			name: "template alone",
+			tmpl: `{{.Kind}}/{{.Name}} {{json .Meta.image}}`,
+			want: "container/web \"nginx:1\"\nvolume/data null\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw, err := newSnapshotWriter(tt.format, tt.tmpl)
+			if err != nil {
+				t.Fatalf("newSnapshotWriter failed: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := sw.Write(&buf, outputSnapshot()); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSnapshotWriter_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		tmpl    string
+		wantErr string
+	}{
+		{"unknown format", "xml", "", `unsupported output format "xml"`},
+		{"template without text", "template", "", "--output template requires --template"},
+		{"text with another format", "table", "{{.Name}}", `--template requires --output template, got "table"`},
+		{"unparsable template", "template", "{{.Name", "invalid template"},
+		{"unknown function", "", "{{upper .Name}}", "invalid template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSnapshotWriter(tt.format, tt.tmpl)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newSnapshotWriter(%q, %q) = %v, want error containing %q", tt.format, tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshotWriter_TemplateError(t *testing.T) {
+	sw, err := newSnapshotWriter("template", "{{.Missing}}")
+	if err != nil {
+		t.Fatalf("newSnapshotWriter failed: %v", err)
+	}
+	if err := sw.Write(&bytes.Buffer{}, outputSnapshot()); err == nil || !strings.Contains(err.Error(), "failed to execute template") {
+		t.Errorf("Write = %v, want a template execution error", err)
+	}
+}
+
+func TestWriteTable_TruncatesLabels(t *testing.T) {
+	long := strings.Repeat("x", maxTableLabels)
+	snap := dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		{Kind: dlabels.KindNetwork, ID: "n1", Name: "shop", Labels: map[string]string{"bosun.note": long}},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeTable(&buf, snap, false); err != nil {
+		t.Fatalf("writeTable failed: %v", err)
+	}
+	row := strings.Split(strings.TrimSpace(buf.String()), "\n")[1]
+	labels := row[strings.LastIndex(row, "  ")+2:]
+	if want := truncate("note="+long, maxTableLabels); labels != want {
+		t.Errorf("labels column = %q, want %q", labels, want)
+	}
+
+	buf.Reset()
+	if err := writeTable(&buf, snap, true); err != nil {
+		t.Fatalf("writeTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "bosun.note="+long) {
+		t.Errorf("wide table truncated the labels:\n%s", buf.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"one too long", 11, "one too lo…"},
+		{"ééééé", 4, "ééé…"},
+		{"", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLabelPairs(t *testing.T) {
+	labels := map[string]string{"bosun.role": "api", "app": "shop", "bosun.backup": "daily"}
+	tests := []struct {
+		trim string
+		want []string
+	}{
+		{"", []string{"app=shop", "bosun.backup=daily", "bosun.role=api"}},
+		{"bosun.", []string{"app=shop", "backup=daily", "role=api"}},
+	}
+	for _, tt := range tests {
+		if got := labelPairs(labels, tt.trim); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("labelPairs(%q) = %v, want %v", tt.trim, got, tt.want)
+		}
+	}
+	if got := labelPairs(nil, ""); got == nil || len(got) != 0 {
+		t.Errorf("labelPairs(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestShortIDAndOrDash(t *testing.T) {
+	tests := []struct {
+		in, shortID, orDash string
+	}{
+		{webID, "0123456789ab", webID},
+		{"0123456789abcdef", "0123456789abcdef", "0123456789abcdef"},
+		{"", "", "-"},
+	}
+	for _, tt := range tests {
+		if got := shortID(tt.in); got != tt.shortID {
+			t.Errorf("shortID(%q) = %q, want %q", tt.in, got, tt.shortID)
+		}
+		if got := orDash(tt.in); got != tt.orDash {
+			t.Errorf("orDash(%q) = %q, want %q", tt.in, got, tt.orDash)
+		}
+	}
+}
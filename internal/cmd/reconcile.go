@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/configfile"
+	"github.com/simone-viozzi/bosun/internal/app"
+	"github.com/simone-viozzi/bosun/internal/domain/desired"
+	"github.com/spf13/cobra"
+)
+
+// errApplyFailed is returned when at least one action of a plan failed
+var errApplyFailed = errors.New("one or more actions failed")
+
+// reconcileLong describes the config file shared by plan and apply
+const reconcileLong = "The config file (default " + configfile.DefaultPath + ") declares groups of containers selected by labels " +
+	"and their desired state: running or stopped, optionally with a scale (the number of matching containers to keep running) " +
+	"and restartUnhealthy. Bosun starts and stops existing containers; it does not create them."
+
+// NewPlanCmd creates the plan command
+func NewPlanCmd(conn *connectionOptions) *cobra.Command {
+	var (
+		file   string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the actions that would bring containers to their desired state",
+		Long:  "Compares the containers with the config file and prints the start, stop and restart actions 'bosun apply' would take. " + reconcileLong,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("unsupported output format %q (expected text or json)", output)
+			}
+			svc, cfg, err := newReconcileService(conn, file)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			plan, err := svc.Plan(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			if output == "json" {
+				return writeJSON(cmd.OutOrStdout(), plan)
+			}
+			writePlan(cmd.OutOrStdout(), plan)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", configfile.DefaultPath, "Config file declaring the desired state")
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// NewApplyCmd creates the apply command
+func NewApplyCmd(conn *connectionOptions) *cobra.Command {
+	var (
+		file  string
+		opts  app.ApplyOptions
+		every time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Bring containers to their desired state",
+		Long: "Plans like 'bosun plan' and executes the actions in order; a failing action does not stop the others. " +
+			"With --every the command keeps running and reconciles at that interval. " + reconcileLong,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, cfg, err := newReconcileService(conn, file)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return runApply(cmd.Context(), cmd.OutOrStdout(), svc, cfg, opts, every)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", configfile.DefaultPath, "Config file declaring the desired state")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only print the actions that would be taken")
+	cmd.Flags().DurationVar(&every, "every", 0, "Keep running and reconcile at this interval (e.g. 30s)")
+
+	return cmd
+}
+
+// newReconcileService loads the config file and connects to Docker
+func newReconcileService(conn *connectionOptions, file string) (*app.ReconcileService, desired.Config, error) {
+	cfg, err := configfile.Load(file)
+	if err != nil {
+		return nil, cfg, err
+	}
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return nil, cfg, err
+	}
+	containers, err := newDockerContainers(conn)
+	if err != nil {
+		return nil, cfg, err
+	}
+	return &app.ReconcileService{Source: source, Containers: containers}, cfg, nil
+}
+
+func runApply(ctx context.Context, w io.Writer, svc *app.ReconcileService, cfg desired.Config, opts app.ApplyOptions, every time.Duration) error {
+	if every <= 0 {
+		plan, err := svc.Plan(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		results, err := svc.Apply(ctx, plan, opts)
+		if writeApplyResults(w, plan, results) {
+			return errApplyFailed
+		}
+		return err
+	}
+
+	// A failed round is reported but does not stop the loop
+	return svc.Run(ctx, cfg, every, opts, func(r app.ReconcileRound) {
+		if r.Err != nil {
+			fmt.Fprintf(w, "reconcile round failed: %v\n", r.Err)
+			return
+		}
+		if len(r.Results) > 0 || len(r.Plan.Problems) > 0 {
+			writeApplyResults(w, r.Plan, r.Results)
+		}
+	})
+}
+
+// writePlan prints one line per action, then the problems
+func writePlan(w io.Writer, plan desired.Plan) {
+	for _, a := range plan.Actions {
+		fmt.Fprintln(w, a)
+	}
+	for _, p := range plan.Problems {
+		fmt.Fprintf(w, "problem: %s\n", p)
+	}
+	if plan.Empty() {
+		fmt.Fprintln(w, "No changes, containers are in the desired state")
+	}
+}
+
+// writeApplyResults prints the outcome of each action and the problems, and
+// reports whether an action failed
+func writeApplyResults(w io.Writer, plan desired.Plan, results []app.ActionResult) bool {
+	failed := false
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed = true
+			fmt.Fprintf(w, "%s: error: %v\n", r.Action, r.Err)
+		case r.Skipped:
+			fmt.Fprintf(w, "%s: dry run\n", r.Action)
+		default:
+			fmt.Fprintf(w, "%s: done\n", r.Action)
+		}
+	}
+	for _, p := range plan.Problems {
+		fmt.Fprintf(w, "problem: %s\n", p)
+	}
+	if plan.Empty() {
+		fmt.Fprintln(w, "No changes, containers are in the desired state")
+	}
+	return failed
+}
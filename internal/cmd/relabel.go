@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+	"github.com/spf13/cobra"
+)
+
+// relabelLong describes the recreation shared by labels set and unset
+const relabelLong = "Docker labels cannot change, so the container is replaced: bosun creates a copy with the same " +
+	"config, host config, mounts and network attachments and the edited labels, stops the container, swaps the names " +
+	"and starts the copy if the container was running. If any step fails, including the start of the copy, the " +
+	"original container is restored. The replaced container is removed; its volumes are kept. " +
+	"--dry-run prints the config changes without touching the container."
+
+// relabelOptions holds the flags shared by labels set and unset
+type relabelOptions struct {
+	opts   app.RelabelOptions
+	output string
+}
+
+// NewLabelsSetCmd creates the labels set subcommand
+func NewLabelsSetCmd(conn *connectionOptions) *cobra.Command {
+	return newRelabelCmd(conn, "set CONTAINER KEY=VALUE...", "Set labels on an existing container by recreating it",
+		"Sets the given labels on CONTAINER, a name or ID, keeping its other labels. ", drecreate.ParseSet)
+}
+
+// NewLabelsUnsetCmd creates the labels unset subcommand
+func NewLabelsUnsetCmd(conn *connectionOptions) *cobra.Command {
+	return newRelabelCmd(conn, "unset CONTAINER KEY...", "Remove labels from an existing container by recreating it",
+		"Removes the given labels from CONTAINER, a name or ID, keeping its other labels. ", drecreate.ParseUnset)
+}
+
+func newRelabelCmd(conn *connectionOptions, use, short, long string, parse func([]string) (drecreate.Edit, error)) *cobra.Command {
+	var opts relabelOptions
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  long + relabelLong,
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format %q (expected text or json)", opts.output)
+			}
+			edit, err := parse(args[1:])
+			if err != nil {
+				return err
+			}
+			containers, err := newDockerContainers(conn)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			svc := &app.RelabelService{Containers: containers}
+			return runRelabel(cmd.Context(), cmd.OutOrStdout(), svc, args[0], edit, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.opts.DryRun, "dry-run", false, "Only print the config changes")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func runRelabel(ctx context.Context, w io.Writer, svc *app.RelabelService, ref string, edit drecreate.Edit, opts relabelOptions) error {
+	res, err := svc.Relabel(ctx, ref, edit, opts.opts)
+	if err != nil {
+		return err
+	}
+	if opts.output == "json" {
+		if res.Changes == nil {
+			res.Changes = []drecreate.FieldChange{}
+		}
+		return writeJSON(w, res)
+	}
+
+	switch {
+	case len(res.Changes) == 0:
+		fmt.Fprintf(w, "No changes, the labels of %s are already as requested\n", res.Container)
+		return nil
+	case opts.opts.DryRun:
+		fmt.Fprintf(w, "Would recreate %s (%s) with:\n", res.Container, shortID(res.OldID))
+	default:
+		fmt.Fprintf(w, "Recreated %s (%s -> %s) with:\n", res.Container, shortID(res.OldID), shortID(res.NewID))
+	}
+	for _, c := range res.Changes {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+	for _, n := range res.Notes {
+		fmt.Fprintf(w, "note: %s\n", n)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/app"
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/spf13/cobra"
+)
+
+// NewRolloutCmd creates the rollout subcommand
+func NewRolloutCmd(conn *connectionOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout",
+		Short: "Rolling operations on label selections",
+		Long:  "Operates on the containers matching a label selector a few at a time, so the others keep serving.",
+	}
+
+	cmd.AddCommand(NewRolloutRestartCmd(conn))
+
+	return cmd
+}
+
+// rolloutRestartOptions holds the flags of the rollout restart subcommand
+type rolloutRestartOptions struct {
+	selector       string
+	maxUnavailable string
+	opts           app.RolloutOptions
+}
+
+// NewRolloutRestartCmd creates the rollout restart subcommand
+func NewRolloutRestartCmd(conn *connectionOptions) *cobra.Command {
+	var opts rolloutRestartOptions
+
+	cmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the containers matching a label selector in health-gated batches",
+		Long: "Restarts the running containers matching --selector, sorted by name, in batches of at most " +
+			"--max-unavailable containers. After each batch it waits until every container of the batch reports the " +
+			"Docker health status healthy, or is running when it has no healthcheck, before restarting the next batch. " +
+			"If a container fails to start, exits, turns unhealthy or is not ready within --batch-timeout, the rollout " +
+			"aborts and the later batches keep running untouched. Stops wait " + dlifecycle.LabelStopTimeout +
+			" (e.g. 30s) before killing a container.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.selector == "" {
+				return errors.New("--selector is required")
+			}
+			sel, err := dselector.Parse(opts.selector)
+			if err != nil {
+				return err
+			}
+			maxUnavailable, err := drollout.ParseMaxUnavailable(opts.maxUnavailable)
+			if err != nil {
+				return err
+			}
+			if opts.opts.BatchTimeout < 0 {
+				return errors.New("--batch-timeout must not be negative")
+			}
+			svc, err := newRolloutService(conn)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return runRolloutRestart(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), svc, sel, maxUnavailable, opts.opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "", "Label selector of the containers, e.g. 'bosun.role=api' (required)")
+	cmd.Flags().StringVar(&opts.maxUnavailable, "max-unavailable", "1", "Containers restarted at once, as a count or a percentage of the running ones (e.g. 2 or 25%)")
+	cmd.Flags().DurationVar(&opts.opts.BatchTimeout, "batch-timeout", 5*time.Minute, "How long a batch may take to become ready before the rollout aborts (0 waits indefinitely)")
+	cmd.Flags().BoolVar(&opts.opts.DryRun, "dry-run", false, "Only print the batches that would be restarted")
+
+	return cmd
+}
+
+// newRolloutService connects to Docker
+func newRolloutService(conn *connectionOptions) (*app.RolloutService, error) {
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := newDockerContainers(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &app.RolloutService{Source: source, Containers: containers, Inspector: containers}, nil
+}
+
+func runRolloutRestart(ctx context.Context, w, errW io.Writer, svc *app.RolloutService, sel dselector.Selector, maxUnavailable drollout.MaxUnavailable, opts app.RolloutOptions) error {
+	plan, err := svc.Plan(ctx, sel, maxUnavailable)
+	if err != nil {
+		return err
+	}
+	for _, u := range plan.Skipped {
+		fmt.Fprintf(errW, "warning: %s is not running, skipping it\n", u.Name)
+	}
+	if len(plan.Batches) == 0 {
+		fmt.Fprintln(w, "No running containers to restart")
+		return nil
+	}
+	if err := svc.Restart(ctx, plan, opts, func(e app.RolloutEvent) {
+		fmt.Fprintln(w, e)
+	}); err != nil {
+		return fmt.Errorf("rollout aborted: %w", err)
+	}
+	return nil
+}
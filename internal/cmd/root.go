@@ -12,8 +12,21 @@ func NewRootCmd() *cobra.Command {
 		Long:  "Bosun is a CLI tool for managing and inspecting Docker labels.",
 	}
 
+	conn := &connectionOptions{}
+	addConnectionFlags(cmd, conn)
+
 	// Add subcommands
-	cmd.AddCommand(NewLabelsCmd())
+	cmd.AddCommand(NewLabelsCmd(conn))
+	cmd.AddCommand(NewBackupCmd(conn))
+	cmd.AddCommand(NewVolumeCmd(conn))
+	cmd.AddCommand(NewServeCmd(conn))
+	cmd.AddCommand(NewGraphCmd(conn))
+	cmd.AddCommand(NewPlanCmd(conn))
+	cmd.AddCommand(NewApplyCmd(conn))
+	cmd.AddCommand(NewUpCmd(conn))
+	cmd.AddCommand(NewDownCmd(conn))
+	cmd.AddCommand(NewRestartCmd(conn))
+	cmd.AddCommand(NewRolloutCmd(conn))
 
 	return cmd
 }
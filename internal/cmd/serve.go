@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/httpapi"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+// serveOptions holds the flags of the serve subcommand
+type serveOptions struct {
+	listen          string
+	composeFiles    []string
+	shutdownTimeout time.Duration
+	events          bool
+	eventBuffer     int
+}
+
+// NewServeCmd creates the serve subcommand
+func NewServeCmd(conn *connectionOptions) *cobra.Command {
+	var opts serveOptions
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve label snapshots over HTTP",
+		Long: "Starts an HTTP server exposing label snapshots as JSON:\n\n" +
+			"  GET /v1/snapshot?prefix=&stopped=&project=&kind=\n" +
+			"  GET /v1/entities/{kind}/{id}\n" +
+			"  GET /healthz, GET /readyz\n" +
+			"  GET /v1/events?prefix=&project=&kind=  (with --events)\n\n" +
+			"The server shuts down gracefully on SIGINT or SIGTERM.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runServe(cmd.Context(), cmd.ErrOrStderr(), conn, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.listen, "listen", "127.0.0.1:8080", "Address to listen on")
+	cmd.Flags().StringSliceVar(&opts.composeFiles, "from-compose", nil, "Serve entities from this compose file instead of Docker (repeatable)")
+	cmd.Flags().BoolVar(&opts.events, "events", false, "Stream label changes as Server-Sent Events on /v1/events")
+	cmd.Flags().IntVar(&opts.eventBuffer, "event-buffer", httpapi.DefaultEventBuffer, "Number of changes kept for clients resuming with Last-Event-ID")
+	cmd.Flags().DurationVar(&opts.shutdownTimeout, "shutdown-timeout", httpapi.DefaultShutdownTimeout, "How long to wait for in-flight requests on shutdown")
+
+	return cmd
+}
+
+func runServe(ctx context.Context, errW io.Writer, conn *connectionOptions, opts serveOptions) error {
+	source, err := newLabelSource(conn, opts.composeFiles)
+	if err != nil {
+		return err
+	}
+
+	srv := &httpapi.Server{Source: source, ShutdownTimeout: opts.shutdownTimeout, EventBuffer: opts.eventBuffer}
+	if p, ok := source.(interface{ Ping(context.Context) error }); ok {
+		srv.Ready = p.Ping
+	}
+	if opts.events {
+		watcher, ok := source.(ports.LabelWatcher)
+		if !ok {
+			return fmt.Errorf("--events needs a Docker daemon; compose files cannot be watched")
+		}
+		srv.Watcher = watcher
+	}
+
+	ln, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	fmt.Fprintf(errW, "Listening on http://%s\n", ln.Addr())
+
+	return srv.Serve(ctx, ln)
+}
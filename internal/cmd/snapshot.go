@@ -2,60 +2,150 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"strings"
 
 	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
+	"github.com/simone-viozzi/bosun/internal/adapters/storage"
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
 	"github.com/simone-viozzi/bosun/internal/ports"
 	"github.com/spf13/cobra"
 )
 
+// snapshotOptions holds the flags of the snapshot subcommand
+type snapshotOptions struct {
+	includeStopped bool
+	projects       []string
+	imageLabels    bool
+	selector       string
+	detail         string
+	composeFiles   []string
+	allowPartial   bool
+	save           bool
+	historyDir     string
+	gzip           bool
+	output         string
+	template       string
+}
+
 // NewSnapshotCmd creates the snapshot subcommand
-func NewSnapshotCmd() *cobra.Command {
-	var includeStopped bool
+func NewSnapshotCmd(conn *connectionOptions) *cobra.Command {
+	var opts snapshotOptions
 
 	cmd := &cobra.Command{
 		Use:   "snapshot",
-		Short: "Print current label snapshot as JSON",
-		Long:  "Captures and prints a snapshot of all Docker entities with Bosun labels as pretty-printed JSON.",
+		Short: "Print current label snapshot",
+		Long: "Captures and prints a snapshot of all Docker entities with Bosun labels, as pretty-printed JSON by default. " +
+			"--output selects yaml, a table (wide adds IDs and full labels), NDJSON with one entity per line, " +
+			"or a Go text/template given with --template and applied to each entity, e.g. '{{.Name}} {{index .Labels \"bosun.role\"}}'. " +
+			"--selector keeps the entities matching a label selector such as 'bosun.role=web,!bosun.ignore'; " +
+			"keys prefixed with meta: match entity metadata, e.g. 'meta:compose.service=db'. " +
+			"--detail extended adds container state, status, ports, mounts, networks and creation time and volume mountpoints to Meta; " +
+			"--detail full also inspects every container for its health and restart count. " +
+			"--host queries several Docker daemons concurrently and merges their entities, recording each one's host in Meta. " +
+			"With --from-compose the snapshot is computed from compose files, without a Docker daemon. " +
+			"With --save the snapshot is also added to the history in --history-dir; compose snapshots cannot be saved.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			return runSnapshot(ctx, includeStopped)
+			out, err := newSnapshotWriter(opts.output, opts.template)
+			if err != nil {
+				return err
+			}
+			return runSnapshot(ctx, cmd.OutOrStdout(), cmd.ErrOrStderr(), conn, out, opts)
 		},
 	}
 
-	cmd.Flags().BoolVar(&includeStopped, "stopped", false, "Include stopped containers in the snapshot")
+	cmd.Flags().BoolVar(&opts.includeStopped, "stopped", false, "Include stopped containers in the snapshot")
+	cmd.Flags().StringSliceVar(&opts.projects, "project", nil, "Only include entities of this compose project (repeatable)")
+	cmd.Flags().BoolVar(&opts.imageLabels, "image-labels", false, "Inherit labels from container images; container labels win")
+	addDetailFlag(cmd, &opts.detail)
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "", "Label selector, e.g. 'bosun.role=web,bosun.env!=dev,bosun.backup in (daily,weekly),!bosun.ignore'")
+	cmd.Flags().StringSliceVar(&opts.composeFiles, "from-compose", nil, "Read entities from this compose file instead of Docker (repeatable)")
+	cmd.Flags().BoolVar(&opts.allowPartial, "allow-partial", false, "With --host, report unreachable hosts and print the snapshot of the others")
+	cmd.Flags().BoolVar(&opts.save, "save", false, "Also save the snapshot to the history (see 'bosun labels history')")
+	cmd.Flags().StringVar(&opts.historyDir, "history-dir", defaultHistoryDir(), "Directory of the snapshot history")
+	cmd.Flags().BoolVar(&opts.gzip, "gzip", false, "Compress the saved snapshot with gzip")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "Output format: "+strings.Join(snapshotFormats, ", ")+" (default json)")
+	cmd.Flags().StringVar(&opts.template, "template", "", "Go template applied to each entity (implies --output template)")
 
 	return cmd
 }
 
-func runSnapshot(ctx context.Context, includeStopped bool) error {
-	// Create Docker label source
-	source, err := dockerlabels.NewFromEnv()
+func runSnapshot(ctx context.Context, w, errW io.Writer, conn *connectionOptions, out *snapshotWriter, opts snapshotOptions) error {
+	// Create label source
+	var source ports.LabelSource
+	var err error
+	if opts.save && len(opts.composeFiles) > 0 {
+		return errors.New("--save and --from-compose cannot be used together: the history only records snapshots of Docker")
+	}
+	if len(conn.hosts) > 0 {
+		if len(opts.composeFiles) > 0 {
+			return errors.New("--host and --from-compose cannot be used together")
+		}
+		source, err = newMultiHostSource(conn)
+	} else {
+		source, err = newLabelSource(conn, opts.composeFiles)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to connect to Docker: %w\nIs Docker running?", err)
+		return err
+	}
+
+	labelSelector, err := dselector.Parse(opts.selector)
+	if err != nil {
+		return err
+	}
+	detail, err := ports.ParseDetail(opts.detail)
+	if err != nil {
+		return err
 	}
 
 	// Create selector with default prefix
 	selector := ports.Selector{
-		Prefixes:       []string{dlabels.DefaultLabelPrefix},
-		IncludeStopped: includeStopped,
+		Prefixes:           []string{dlabels.DefaultLabelPrefix},
+		IncludeStopped:     opts.includeStopped,
+		ProjectFilter:      opts.projects,
+		InheritImageLabels: opts.imageLabels,
+		LabelSelector:      labelSelector,
+		Detail:             detail,
+	}
+	if err := selector.CheckLabelSelector(); err != nil {
+		return err
 	}
 
 	// Get snapshot
 	snapshot, err := source.Snapshot(ctx, selector)
-	if err != nil {
+	var partial *dockerlabels.PartialError
+	if opts.allowPartial && errors.As(err, &partial) {
+		for _, f := range partial.Failed {
+			fmt.Fprintf(errW, "warning: host %s unreachable: %v\n", f.Host, f.Err)
+		}
+		if opts.save {
+			// diffs against a partial snapshot would report the missing hosts' entities as removed
+			return fmt.Errorf("cannot save a partial snapshot: %d host(s) unreachable", len(partial.Failed))
+		}
+	} else if err != nil {
 		return fmt.Errorf("failed to get snapshot: %w", err)
 	}
 
-	// Print as pretty JSON
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(snapshot); err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
+	if opts.save {
+		store := storage.NewSnapshotDir(opts.historyDir)
+		store.Gzip = opts.gzip
+		info, err := store.Save(ctx, snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+		fmt.Fprintf(errW, "Saved snapshot %s\n", info.ID)
 	}
 
-	return nil
+	return out.Write(w, snapshot)
+}
+
+// addDetailFlag adds the --detail flag selecting the metadata added to Meta
+func addDetailFlag(cmd *cobra.Command, detail *string) {
+	names := ports.DetailNames()
+	cmd.Flags().StringVar(detail, "detail", "", "Metadata detail level: "+strings.Join(names, ", ")+" (default basic)")
+	_ = cmd.RegisterFlagCompletionFunc("detail", cobra.FixedCompletions(names, cobra.ShellCompDirectiveNoFileComp))
 }
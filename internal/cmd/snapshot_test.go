@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+// execute runs the root command with args and returns what it wrote to
+// stdout and stderr
+func execute(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	root := NewRootCmd()
+	var stdout, stderr bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs(args)
+	err := root.ExecuteContext(context.Background())
+	return stdout.String(), stderr.String(), err
+}
+
+// serveWeb serves a fake engine with a running container web and returns its endpoint
+func serveWeb(t *testing.T) string {
+	t.Helper()
+	engine := fakedocker.New()
+	if _, err := engine.AddContainer(fakedocker.ContainerSpec{
+		Name:    "web",
+		Image:   "nginx:1",
+		Labels:  map[string]string{"bosun.role": "web", "com.docker.compose.project": "shop", "com.docker.compose.service": "web"},
+		Running: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return engine.ServeUnix(t)
+}
+
+func TestSnapshotCmd_AllowPartial(t *testing.T) {
+	up := "up=" + serveWeb(t)
+	down := "down=unix:///nonexistent/bosun-test.sock"
+
+	if _, _, err := execute(t, "-H", up, "-H", down, "labels", "snapshot", "-o", "table"); err == nil || !strings.Contains(err.Error(), "down") {
+		t.Fatalf("snapshot without --allow-partial = %v, want an error naming host down", err)
+	}
+
+	stdout, stderr, err := execute(t, "-H", up, "-H", down, "labels", "snapshot", "-o", "table", "--allow-partial")
+	if err != nil {
+		t.Fatalf("snapshot --allow-partial failed: %v", err)
+	}
+	want := "KIND       NAME  PROJECT  SERVICE  LABELS\ncontainer  web   shop     web      role=web\n"
+	if stdout != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", stdout, want)
+	}
+	if !strings.HasPrefix(stderr, "warning: host down unreachable: ") || strings.Count(stderr, "\n") != 1 {
+		t.Errorf("stderr = %q, want one warning about host down", stderr)
+	}
+}
+
+func TestSnapshotCmd_AllowPartialRefusesSave(t *testing.T) {
+	up := "up=" + serveWeb(t)
+	down := "down=unix:///nonexistent/bosun-test.sock"
+	dir := t.TempDir()
+
+	_, _, err := execute(t, "-H", up, "-H", down, "labels", "snapshot", "--allow-partial", "--save", "--history-dir", dir)
+	if err == nil || !strings.Contains(err.Error(), "partial") {
+		t.Fatalf("snapshot --allow-partial --save = %v, want an error", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("history = %v, want nothing saved", entries)
+	}
+}
+
+func TestSnapshotCmd_Selector(t *testing.T) {
+	host := serveWeb(t)
+
+	stdout, _, err := execute(t, "-H", host, "labels", "snapshot", "-o", "template", "--template", "{{.Kind}}/{{.Name}} {{join \",\" .Labels}}", "-l", "bosun.role=web")
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	if want := "container/web bosun.role=web\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	stdout, _, err = execute(t, "-H", host, "labels", "snapshot", "-o", "template", "--template", "{{.Name}}", "-l", "bosun.role=db")
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want no entities", stdout)
+	}
+}
+
+func TestSnapshotCmd_SaveRejectsCompose(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := execute(t, "labels", "snapshot", "--from-compose", "compose.yaml", "--save", "--history-dir", dir)
+	if err == nil || !strings.Contains(err.Error(), "--from-compose") {
+		t.Fatalf("snapshot --save --from-compose = %v, want an error", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("history = %v, want nothing saved", entries)
+	}
+}
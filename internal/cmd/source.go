@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/composelabels"
+	"github.com/simone-viozzi/bosun/internal/adapters/dockerlabels"
+	"github.com/simone-viozzi/bosun/internal/ports"
+)
+
+// newLabelSource returns a source reading the given compose files, or the Docker
+// daemon source when no files are given.
+func newLabelSource(conn *connectionOptions, composeFiles []string) (ports.LabelSource, error) {
+	if len(composeFiles) > 0 {
+		return composelabels.New(composeFiles...), nil
+	}
+	return newDockerLabelSource(conn)
+}
+
+// newMultiHostSource returns a source merging the daemons given with --host.
+// The name of each value, or else its endpoint or context, is recorded as the host in Meta.
+func newMultiHostSource(conn *connectionOptions) (*dockerlabels.MultiHostSource, error) {
+	src := &dockerlabels.MultiHostSource{}
+	for _, h := range conn.hosts {
+		name, cfg := conn.hostConfig(h)
+		source, err := dockerlabels.New(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure host %s: %w", name, err)
+		}
+		src.Hosts = append(src.Hosts, dockerlabels.Host{Name: name, Source: source})
+	}
+	return src, nil
+}
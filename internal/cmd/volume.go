@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/simone-viozzi/bosun/internal/adapters/dockervolumes"
+	"github.com/simone-viozzi/bosun/internal/app"
+	"github.com/spf13/cobra"
+)
+
+// NewVolumeCmd creates the volume subcommand
+func NewVolumeCmd(conn *connectionOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "volume",
+		Short: "Export and restore volumes with their labels",
+		Long:  "Exports volumes to tar archives that carry their labels, and restores them.",
+	}
+
+	cmd.AddCommand(NewVolumeExportCmd(conn))
+	cmd.AddCommand(NewVolumeRestoreCmd(conn))
+
+	return cmd
+}
+
+// newVolumeService wires the Docker volume and container adapters into a VolumeService
+func newVolumeService(conn *connectionOptions, helperImage string) (*app.VolumeService, error) {
+	volumes, err := newDockerVolumes(conn)
+	if err != nil {
+		return nil, err
+	}
+	volumes.HelperImage = helperImage
+	containers, err := newDockerContainers(conn)
+	if err != nil {
+		return nil, err
+	}
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &app.VolumeService{Volumes: volumes, Containers: containers, Source: source}, nil
+}
+
+// NewVolumeExportCmd creates the volume export subcommand
+func NewVolumeExportCmd(conn *connectionOptions) *cobra.Command {
+	var to, helperImage string
+
+	cmd := &cobra.Command{
+		Use:   "export <volume>",
+		Short: "Export a volume and its labels to a tar archive",
+		Long: "Writes the contents of a volume to a tar archive, preceded by a manifest " +
+			"holding its driver, options and labels.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := newVolumeService(conn, helperImage)
+			if err != nil {
+				return err
+			}
+			return runVolumeExport(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), svc, args[0], to)
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Archive file to write, or - for stdout")
+	cmd.Flags().StringVar(&helperImage, "helper-image", dockervolumes.DefaultHelperImage, "Image of the throwaway container used to read the volume")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func runVolumeExport(ctx context.Context, stdout, stderr io.Writer, svc *app.VolumeService, name, to string) (err error) {
+	if to == "-" {
+		_, err = svc.Export(ctx, name, stdout)
+		return err
+	}
+
+	// Write next to the destination and rename, so a failed export leaves no partial archive
+	f, err := os.CreateTemp(filepath.Dir(to), "."+filepath.Base(to)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	m, err := svc.Export(ctx, name, f)
+	if err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err = os.Rename(f.Name(), to); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	fmt.Fprintf(stderr, "Exported volume %s with %d labels to %s\n", m.Volume, len(m.Labels), to)
+	return nil
+}
+
+// volumeRestoreOptions holds the flags of the volume restore subcommand
+type volumeRestoreOptions struct {
+	from        string
+	force       bool
+	helperImage string
+}
+
+// NewVolumeRestoreCmd creates the volume restore subcommand
+func NewVolumeRestoreCmd(conn *connectionOptions) *cobra.Command {
+	var opts volumeRestoreOptions
+
+	cmd := &cobra.Command{
+		Use:   "restore [volume]",
+		Short: "Restore a volume and its labels from a tar archive",
+		Long: "Restores an archive written by 'bosun volume export' into the given volume, or into the volume it was exported from. " +
+			"A missing volume is created with the labels of the archive. An existing volume whose driver, options or labels differ " +
+			"is only removed and recreated with --force, and never while containers reference it, as Docker cannot relabel a volume. " +
+			"A volume used by containers is only overwritten with --force: its running containers are stopped during the restore " +
+			"and restarted afterwards.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := newVolumeService(conn, opts.helperImage)
+			if err != nil {
+				return err
+			}
+			var name string
+			if len(args) == 1 {
+				name = args[0]
+			}
+			cmd.SilenceUsage = true
+			return runVolumeRestore(cmd.Context(), cmd.OutOrStdout(), cmd.InOrStdin(), svc, name, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.from, "from", "", "Archive file to restore, or - for stdin")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Overwrite a volume that is used by containers or differs from the archive")
+	cmd.Flags().StringVar(&opts.helperImage, "helper-image", dockervolumes.DefaultHelperImage, "Image of the throwaway container used to write the volume")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func runVolumeRestore(ctx context.Context, w io.Writer, stdin io.Reader, svc *app.VolumeService, name string, opts volumeRestoreOptions) error {
+	r := stdin
+	if opts.from != "-" {
+		f, err := os.Open(opts.from)
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	res, err := svc.Restore(ctx, r, app.RestoreOptions{Name: name, Force: opts.force})
+	if errors.Is(err, app.ErrVolumeInUse) {
+		return fmt.Errorf("%w\nUse --force to stop its containers and overwrite it", err)
+	}
+	if errors.Is(err, app.ErrVolumeDiffers) {
+		return fmt.Errorf("%w\nUse --force to remove it and recreate it from the archive", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	action := "Restored"
+	if res.Created {
+		action = "Created and restored"
+	}
+	fmt.Fprintf(w, "%s volume %s from %s (exported %s)\n", action, res.Volume, res.Manifest.Volume, res.Manifest.ExportedAt.Format("2006-01-02 15:04:05 MST"))
+	if len(res.Stopped) > 0 {
+		fmt.Fprintf(w, "Restarted %d containers\n", len(res.Stopped))
+	}
+	return nil
+}
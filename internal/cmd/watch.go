@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+	"github.com/simone-viozzi/bosun/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+// watchOptions holds the flags of the watch subcommand
+type watchOptions struct {
+	includeStopped bool
+	projects       []string
+	imageLabels    bool
+	selector       string
+	detail         string
+}
+
+// NewWatchCmd creates the watch subcommand
+func NewWatchCmd(conn *connectionOptions) *cobra.Command {
+	var opts watchOptions
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Stream label changes as JSON lines",
+		Long: "Prints every Docker entity with Bosun labels as an 'added' change, then follows Docker events " +
+			"and prints one JSON object per line for each added, removed, labels-changed, state-changed or renamed entity.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			return runWatch(ctx, conn, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.includeStopped, "stopped", false, "Include stopped containers")
+	cmd.Flags().StringSliceVar(&opts.projects, "project", nil, "Only include entities of this compose project (repeatable)")
+	cmd.Flags().BoolVar(&opts.imageLabels, "image-labels", false, "Inherit labels from container images; container labels win")
+	addDetailFlag(cmd, &opts.detail)
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "", "Label selector, e.g. 'bosun.role=web,bosun.env!=dev,bosun.backup in (daily,weekly),!bosun.ignore'")
+
+	return cmd
+}
+
+func runWatch(ctx context.Context, conn *connectionOptions, opts watchOptions) error {
+	source, err := newDockerLabelSource(conn)
+	if err != nil {
+		return err
+	}
+
+	labelSelector, err := dselector.Parse(opts.selector)
+	if err != nil {
+		return err
+	}
+	detail, err := ports.ParseDetail(opts.detail)
+	if err != nil {
+		return err
+	}
+
+	selector := ports.Selector{
+		Prefixes:           []string{dlabels.DefaultLabelPrefix},
+		IncludeStopped:     opts.includeStopped,
+		ProjectFilter:      opts.projects,
+		InheritImageLabels: opts.imageLabels,
+		LabelSelector:      labelSelector,
+		Detail:             detail,
+	}
+	if err := selector.CheckLabelSelector(); err != nil {
+		return err
+	}
+
+	changes, err := source.Watch(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to watch labels: %w", err)
+	}
+
+	// One JSON object per line so the stream can be piped through jq
+	enc := json.NewEncoder(os.Stdout)
+	for c := range changes {
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"strconv"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// Backup is a stored archive of a volume.
+type Backup struct {
+	ID      string // target specific identifier
+	Volume  string
+	TakenAt time.Time
+	Size    int64
+}
+
+// Job is a volume with a backup schedule and whether it is due.
+type Job struct {
+	Volume   string
+	Schedule Schedule
+	Stop     bool      // stop the containers mounting the volume while archiving
+	Last     time.Time // zero if the volume was never backed up
+	Due      bool
+	Err      error // invalid schedule; the job is never due
+}
+
+// Plan returns a job for every volume in the snapshot carrying LabelSchedule,
+// in snapshot order. last holds the time of the latest backup per volume name.
+func Plan(snap dlabels.Snapshot, last map[string]time.Time, now time.Time) []Job {
+	var jobs []Job
+	for _, e := range snap.Entities {
+		if e.Kind != dlabels.KindVolume {
+			continue
+		}
+		spec, ok := e.Labels[LabelSchedule]
+		if !ok {
+			continue
+		}
+
+		job := Job{Volume: e.Name, Last: last[e.Name]}
+		job.Stop, _ = strconv.ParseBool(e.Labels[LabelStop])
+		job.Schedule, job.Err = ParseSchedule(spec)
+		if job.Err == nil {
+			job.Due = job.Schedule.Due(job.Last, now)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
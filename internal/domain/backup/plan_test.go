@@ -0,0 +1,72 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{spec: "daily", want: 24 * time.Hour},
+		{spec: "Weekly", want: 7 * 24 * time.Hour},
+		{spec: "6h", want: 6 * time.Hour},
+		{spec: "30s", wantErr: true},
+		{spec: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err == nil && s.Interval != tt.want {
+				t.Errorf("ParseSchedule(%q) = %v, want %v", tt.spec, s.Interval, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	snap := dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		{Kind: dlabels.KindContainer, Name: "web", Labels: map[string]string{LabelSchedule: "daily"}},
+		{Kind: dlabels.KindVolume, Name: "fresh", Labels: map[string]string{LabelSchedule: "daily"}},
+		{Kind: dlabels.KindVolume, Name: "stale", Labels: map[string]string{LabelSchedule: "daily", LabelStop: "true"}},
+		{Kind: dlabels.KindVolume, Name: "never", Labels: map[string]string{LabelSchedule: "weekly"}},
+		{Kind: dlabels.KindVolume, Name: "broken", Labels: map[string]string{LabelSchedule: "often"}},
+		{Kind: dlabels.KindVolume, Name: "unscheduled", Labels: map[string]string{"bosun.role": "cache"}},
+	}}
+	last := map[string]time.Time{
+		"fresh": now.Add(-time.Hour),
+		"stale": now.Add(-25 * time.Hour),
+	}
+
+	jobs := Plan(snap, last, now)
+	if len(jobs) != 4 {
+		t.Fatalf("expected 4 jobs, got %d: %+v", len(jobs), jobs)
+	}
+
+	want := []struct {
+		volume string
+		due    bool
+		stop   bool
+		err    bool
+	}{
+		{"fresh", false, false, false},
+		{"stale", true, true, false},
+		{"never", true, false, false},
+		{"broken", false, false, true},
+	}
+	for i, w := range want {
+		j := jobs[i]
+		if j.Volume != w.volume || j.Due != w.due || j.Stop != w.stop || (j.Err != nil) != w.err {
+			t.Errorf("job[%d] = %+v, want %+v", i, j, w)
+		}
+	}
+}
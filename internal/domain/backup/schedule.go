@@ -0,0 +1,55 @@
+// Package backup plans label-driven volume backups.
+package backup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// Volume labels that drive backups.
+const (
+	// LabelSchedule enables backups of a volume, e.g. "daily" or "6h".
+	LabelSchedule = dlabels.DefaultLabelPrefix + "backup"
+	// LabelStop stops the containers mounting the volume while it is archived.
+	LabelStop = dlabels.DefaultLabelPrefix + "backup.stop"
+)
+
+// namedSchedules maps the named schedules to their intervals.
+var namedSchedules = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+}
+
+// Schedule is how often a volume is backed up.
+type Schedule struct {
+	Spec     string // the label value, e.g. "daily"
+	Interval time.Duration
+}
+
+// ParseSchedule parses a named schedule (hourly, daily, weekly, monthly)
+// or a Go duration of at least one minute, e.g. "6h".
+func ParseSchedule(spec string) (Schedule, error) {
+	s := strings.ToLower(strings.TrimSpace(spec))
+	if d, ok := namedSchedules[s]; ok {
+		return Schedule{Spec: spec, Interval: d}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid backup schedule %q: expected hourly, daily, weekly, monthly or a duration", spec)
+	}
+	if d < time.Minute {
+		return Schedule{}, fmt.Errorf("invalid backup schedule %q: interval must be at least 1m", spec)
+	}
+	return Schedule{Spec: spec, Interval: d}, nil
+}
+
+// Due reports whether a backup is due at now given the time of the last one.
+// A volume that was never backed up is always due.
+func (s Schedule) Due(last, now time.Time) bool {
+	return last.IsZero() || !now.Before(last.Add(s.Interval))
+}
@@ -0,0 +1,92 @@
+// Package desired declares groups of containers and the state they should be
+// in, and plans the start, stop and restart actions that bring a snapshot there.
+package desired
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+)
+
+// State is the desired state of the containers of a group.
+type State string
+
+const (
+	StateRunning State = "running"
+	StateStopped State = "stopped"
+)
+
+// Group is a set of containers selected by labels and the state they should be in.
+type Group struct {
+	Name     string
+	Selector dselector.Selector
+	State    State // defaults to StateRunning
+	// Scale is the number of matching containers that should be running; 0
+	// means all of them. Only valid with StateRunning. Bosun does not create
+	// containers, so a group with fewer matching containers is reported.
+	Scale int
+	// RestartUnhealthy restarts running containers whose healthcheck reports
+	// unhealthy.
+	RestartUnhealthy bool
+}
+
+// Config is the desired state of every group. A container matching several
+// groups belongs to the first one.
+type Config struct {
+	Groups []Group
+}
+
+// Normalize fills in defaults and checks the config is consistent: named,
+// unique groups with a selector, a known state and a scale that applies to it.
+func (c Config) Normalize() (Config, error) {
+	seen := make(map[string]bool, len(c.Groups))
+	groups := make([]Group, 0, len(c.Groups))
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return c, fmt.Errorf("group without a name")
+		}
+		if seen[g.Name] {
+			return c, fmt.Errorf("group %s declared twice", g.Name)
+		}
+		seen[g.Name] = true
+
+		if g.Selector.Empty() {
+			return c, fmt.Errorf("group %s: a selector is required", g.Name)
+		}
+		if g.State == "" {
+			g.State = StateRunning
+		}
+		switch g.State {
+		case StateRunning:
+		case StateStopped:
+			if g.Scale != 0 {
+				return c, fmt.Errorf("group %s: scale only applies to running groups", g.Name)
+			}
+			if g.RestartUnhealthy {
+				return c, fmt.Errorf("group %s: restartUnhealthy only applies to running groups", g.Name)
+			}
+		default:
+			return c, fmt.Errorf("group %s: unknown state %q (want running or stopped)", g.Name, g.State)
+		}
+		if g.Scale < 0 {
+			return c, fmt.Errorf("group %s: scale must not be negative", g.Name)
+		}
+		groups = append(groups, g)
+	}
+	c.Groups = groups
+	return c, nil
+}
+
+// NeedsHealth reports whether planning needs the health status or restart
+// count of containers, which take an inspection per container.
+func (c Config) NeedsHealth() bool {
+	return slices.ContainsFunc(c.Groups, func(g Group) bool {
+		return g.RestartUnhealthy || slices.ContainsFunc(g.Selector, func(r dselector.Requirement) bool {
+			key := strings.TrimPrefix(r.Key, dselector.MetaPrefix)
+			return r.IsMeta() && (key == dlabels.MetaHealth || key == dlabels.MetaRestarts)
+		})
+	})
+}
@@ -0,0 +1,148 @@
+package desired
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// ActionType is what an action does to a container.
+type ActionType string
+
+const (
+	ActionStart   ActionType = "start"
+	ActionStop    ActionType = "stop"
+	ActionRestart ActionType = "restart"
+)
+
+// Action is a change to one container.
+type Action struct {
+	Type        ActionType `json:"type"`
+	Group       string     `json:"group"`
+	ContainerID string     `json:"containerId"`
+	Container   string     `json:"container"`
+	Reason      string     `json:"reason"`
+}
+
+func (a Action) String() string {
+	return fmt.Sprintf("%s: %s %s (%s)", a.Group, a.Type, a.Container, a.Reason)
+}
+
+// Problem is a difference from the desired state that no action can fix.
+type Problem struct {
+	Group   string `json:"group"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Group + ": " + p.Message
+}
+
+// Plan is the outcome of comparing a snapshot with a config.
+type Plan struct {
+	Actions  []Action  `json:"actions"`
+	Problems []Problem `json:"problems"`
+}
+
+// Empty reports whether the snapshot is in the desired state.
+func (p Plan) Empty() bool {
+	return len(p.Actions) == 0 && len(p.Problems) == 0
+}
+
+// up reports whether a container in state is running or about to be.
+func up(state string) bool {
+	return state == "running" || state == "restarting"
+}
+
+// MakePlan compares the containers of snap, which must carry their state in
+// Meta, with cfg. Groups are planned in config order and the containers of a
+// group by name. Scaling up starts the first stopped containers by name;
+// scaling down stops the last running ones.
+func MakePlan(cfg Config, snap dlabels.Snapshot) Plan {
+	var ctrs []dlabels.LabeledEntity
+	for _, e := range snap.Entities {
+		if e.Kind == dlabels.KindContainer {
+			ctrs = append(ctrs, e)
+		}
+	}
+	slices.SortFunc(ctrs, func(a, b dlabels.LabeledEntity) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	plan := Plan{Actions: []Action{}, Problems: []Problem{}}
+	owner := make(map[string]string) // container ID to the group it belongs to
+	for _, g := range cfg.Groups {
+		var members []dlabels.LabeledEntity
+		for _, c := range ctrs {
+			if !g.Selector.Matches(c) {
+				continue
+			}
+			if prev, ok := owner[c.ID]; ok {
+				plan.Problems = append(plan.Problems, Problem{Group: g.Name, Message: fmt.Sprintf("container %s also matches group %s, which takes precedence", c.Name, prev)})
+				continue
+			}
+			owner[c.ID] = g.Name
+			members = append(members, c)
+		}
+		planGroup(&plan, g, members)
+	}
+	return plan
+}
+
+// planGroup appends the actions and problems of group g with the given members, sorted by name.
+func planGroup(plan *Plan, g Group, members []dlabels.LabeledEntity) {
+	action := func(t ActionType, c dlabels.LabeledEntity, reason string) {
+		plan.Actions = append(plan.Actions, Action{Type: t, Group: g.Name, ContainerID: c.ID, Container: c.Name, Reason: reason})
+	}
+
+	var running, stopped []dlabels.LabeledEntity
+	for _, c := range members {
+		switch state := c.Meta[dlabels.MetaState]; {
+		case up(state):
+			running = append(running, c)
+		case state == "paused":
+			plan.Problems = append(plan.Problems, Problem{Group: g.Name, Message: fmt.Sprintf("container %s is paused", c.Name)})
+		default:
+			stopped = append(stopped, c)
+		}
+	}
+
+	if g.State == StateStopped {
+		for _, c := range running {
+			action(ActionStop, c, "group is stopped")
+		}
+		return
+	}
+
+	keep := running
+	switch {
+	case g.Scale == 0:
+		for _, c := range stopped {
+			action(ActionStart, c, "group is running")
+		}
+	case len(running) < g.Scale:
+		n := min(g.Scale-len(running), len(stopped))
+		for _, c := range stopped[:n] {
+			action(ActionStart, c, fmt.Sprintf("%d of %d running", len(running), g.Scale))
+		}
+		if len(running)+len(stopped) < g.Scale {
+			plan.Problems = append(plan.Problems, Problem{Group: g.Name, Message: fmt.Sprintf("scale is %d but only %d containers match", g.Scale, len(running)+len(stopped))})
+		}
+	case len(running) > g.Scale:
+		keep = running[:g.Scale]
+		extra := running[g.Scale:]
+		for i := len(extra) - 1; i >= 0; i-- {
+			action(ActionStop, extra[i], fmt.Sprintf("%d of %d running", len(running), g.Scale))
+		}
+	}
+
+	if g.RestartUnhealthy {
+		for _, c := range keep {
+			if c.Meta[dlabels.MetaHealth] == "unhealthy" {
+				action(ActionRestart, c, "unhealthy")
+			}
+		}
+	}
+}
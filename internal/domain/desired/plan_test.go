@@ -0,0 +1,161 @@
+package desired
+
+import (
+	"reflect"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
+)
+
+func ctr(name, role, state string) dlabels.LabeledEntity {
+	return dlabels.LabeledEntity{
+		Kind:   dlabels.KindContainer,
+		ID:     "id-" + name,
+		Name:   name,
+		Labels: map[string]string{"bosun.role": role},
+		Meta:   map[string]string{dlabels.MetaState: state},
+	}
+}
+
+func sel(t *testing.T, s string) dselector.Selector {
+	t.Helper()
+	out, err := dselector.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func summary(p Plan) []string {
+	var out []string
+	for _, a := range p.Actions {
+		out = append(out, string(a.Type)+" "+a.Container)
+	}
+	return out
+}
+
+func TestMakePlan(t *testing.T) {
+	unhealthy := ctr("api-2", "api", "running")
+	unhealthy.Meta[dlabels.MetaHealth] = "unhealthy"
+	snap := dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		ctr("worker-3", "worker", "exited"),
+		ctr("worker-1", "worker", "running"),
+		ctr("worker-2", "worker", "created"),
+		ctr("worker-4", "worker", "exited"),
+		ctr("maint-1", "maintenance", "running"),
+		ctr("maint-2", "maintenance", "exited"),
+		ctr("api-1", "api", "running"),
+		unhealthy,
+		ctr("web-1", "web", "exited"),
+		ctr("web-2", "web", "paused"),
+		{Kind: dlabels.KindVolume, ID: "v", Name: "v", Labels: map[string]string{"bosun.role": "worker"}},
+	}}
+
+	tests := []struct {
+		name     string
+		groups   []Group
+		actions  []string
+		problems int
+	}{
+		{
+			name:    "scale up starts the first stopped containers",
+			groups:  []Group{{Name: "workers", Selector: sel(t, "bosun.role=worker"), State: StateRunning, Scale: 3}},
+			actions: []string{"start worker-2", "start worker-3"},
+		},
+		{
+			name:     "scale beyond the matching containers",
+			groups:   []Group{{Name: "workers", Selector: sel(t, "bosun.role=worker"), State: StateRunning, Scale: 6}},
+			actions:  []string{"start worker-2", "start worker-3", "start worker-4"},
+			problems: 1,
+		},
+		{
+			name:    "scale down stops the last running containers",
+			groups:  []Group{{Name: "api", Selector: sel(t, "bosun.role=api"), State: StateRunning, Scale: 1}},
+			actions: []string{"stop api-2"},
+		},
+		{
+			name:    "stopped group",
+			groups:  []Group{{Name: "maintenance", Selector: sel(t, "bosun.role=maintenance"), State: StateStopped}},
+			actions: []string{"stop maint-1"},
+		},
+		{
+			name:    "restart unhealthy",
+			groups:  []Group{{Name: "api", Selector: sel(t, "bosun.role=api"), State: StateRunning, RestartUnhealthy: true}},
+			actions: []string{"restart api-2"},
+		},
+		{
+			name:     "paused containers are reported",
+			groups:   []Group{{Name: "web", Selector: sel(t, "bosun.role=web"), State: StateRunning}},
+			actions:  []string{"start web-1"},
+			problems: 1,
+		},
+		{
+			name: "first matching group wins",
+			groups: []Group{
+				{Name: "maintenance", Selector: sel(t, "bosun.role=maintenance"), State: StateStopped},
+				{Name: "all", Selector: sel(t, "bosun.role in (maintenance,api)"), State: StateRunning},
+			},
+			actions:  []string{"stop maint-1"},
+			problems: 2,
+		},
+		{
+			name:   "in the desired state",
+			groups: []Group{{Name: "api", Selector: sel(t, "bosun.role=api"), State: StateRunning}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := MakePlan(Config{Groups: tt.groups}, snap)
+			if got := summary(plan); !reflect.DeepEqual(got, tt.actions) {
+				t.Errorf("actions = %v, want %v", got, tt.actions)
+			}
+			if len(plan.Problems) != tt.problems {
+				t.Errorf("problems = %v, want %d", plan.Problems, tt.problems)
+			}
+			if plan.Empty() != (tt.actions == nil && tt.problems == 0) {
+				t.Errorf("Empty() = %v", plan.Empty())
+			}
+		})
+	}
+}
+
+func TestConfig_Normalize(t *testing.T) {
+	role := sel(t, "bosun.role=worker")
+	good := Config{Groups: []Group{{Name: "workers", Selector: role, Scale: 3}}}
+	got, err := good.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if got.Groups[0].State != StateRunning {
+		t.Errorf("State = %q, want running by default", got.Groups[0].State)
+	}
+
+	bad := []Config{
+		{Groups: []Group{{Selector: role}}},
+		{Groups: []Group{{Name: "a", Selector: role}, {Name: "a", Selector: role}}},
+		{Groups: []Group{{Name: "a"}}},
+		{Groups: []Group{{Name: "a", Selector: role, State: "paused"}}},
+		{Groups: []Group{{Name: "a", Selector: role, State: StateStopped, Scale: 1}}},
+		{Groups: []Group{{Name: "a", Selector: role, Scale: -1}}},
+	}
+	for _, c := range bad {
+		if _, err := c.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) succeeded, expected an error", c)
+		}
+	}
+}
+
+func TestConfig_NeedsHealth(t *testing.T) {
+	c := Config{Groups: []Group{
+		{Name: "a", Selector: sel(t, "bosun.role=api,meta:state=running")},
+		{Name: "b", Selector: sel(t, "bosun.tier,bosun.role!=db")},
+	}}
+	if c.NeedsHealth() {
+		t.Error("Expected no health needed")
+	}
+	c.Groups[1].Selector = sel(t, "meta:health=unhealthy")
+	if !c.NeedsHealth() {
+		t.Error("Expected health needed for a meta:health selector")
+	}
+}
@@ -0,0 +1,200 @@
+// Package graph builds the relationships between the entities of a snapshot:
+// containers mounting volumes, containers attached to networks, and compose
+// projects made of services made of containers.
+package graph
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// NodeKind is the kind of a graph node: an entity kind, or a compose project or service.
+type NodeKind string
+
+const (
+	NodeProject   NodeKind = "project"
+	NodeService   NodeKind = "service"
+	NodeContainer NodeKind = NodeKind(dlabels.KindContainer)
+	NodeVolume    NodeKind = NodeKind(dlabels.KindVolume)
+	NodeNetwork   NodeKind = NodeKind(dlabels.KindNetwork)
+)
+
+// NodeID identifies a node as "kind/name". Services are named "project/service".
+type NodeID string
+
+// ID returns the ID of the node of kind k named name.
+func ID(k NodeKind, name string) NodeID {
+	return NodeID(string(k) + "/" + name)
+}
+
+// ParseID splits "kind/name" into its parts. It reports false when s has no
+// kind or the kind is unknown.
+func ParseID(s string) (NodeID, bool) {
+	kind, name, ok := strings.Cut(s, "/")
+	if !ok || name == "" {
+		return "", false
+	}
+	switch NodeKind(kind) {
+	case NodeProject, NodeService, NodeContainer, NodeVolume, NodeNetwork:
+		return NodeID(s), true
+	}
+	return "", false
+}
+
+// Kind returns the kind part of the ID.
+func (id NodeID) Kind() NodeKind {
+	k, _, _ := strings.Cut(string(id), "/")
+	return NodeKind(k)
+}
+
+// Name returns the name part of the ID.
+func (id NodeID) Name() string {
+	_, n, _ := strings.Cut(string(id), "/")
+	return n
+}
+
+// Node is an entity of the snapshot, or a project, service, volume or network
+// only known from references to it.
+type Node struct {
+	ID     NodeID            `json:"id"`
+	Kind   NodeKind          `json:"kind"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Implicit is set for nodes not in the snapshot, e.g. a volume without
+	// labels mounted by a container that has some.
+	Implicit bool `json:"implicit,omitempty"`
+}
+
+// EdgeKind is the relationship an edge stands for.
+type EdgeKind string
+
+const (
+	EdgeContains EdgeKind = "contains" // project to service, service to container
+	EdgeMounts   EdgeKind = "mounts"   // container to volume
+	EdgeAttached EdgeKind = "attached" // container to network
+)
+
+// Edge points from a node to a node it depends on.
+type Edge struct {
+	From NodeID   `json:"from"`
+	To   NodeID   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	// Detail describes mounts as "DEST" or "DEST:ro".
+	Detail string `json:"detail,omitempty"`
+}
+
+// Graph is a directed graph whose edges point from dependents to dependencies:
+// a project depends on its services, a service on its containers, and a
+// container on the volumes and networks it uses.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+
+	index map[NodeID]int
+	in    map[NodeID][]int // edge indexes by To
+}
+
+// Build returns the graph of snap. Nodes are sorted by kind, then name; edges
+// by source, then target.
+func Build(snap dlabels.Snapshot) *Graph {
+	g := &Graph{index: make(map[NodeID]int)}
+	for _, e := range snap.Entities {
+		name := e.Name
+		if name == "" {
+			name = e.ID
+		}
+		g.addNode(Node{ID: ID(NodeKind(e.Kind), name), Kind: NodeKind(e.Kind), Name: name, Labels: e.Labels})
+	}
+
+	for _, e := range snap.Entities {
+		if e.Kind != dlabels.KindContainer {
+			continue
+		}
+		ctr := ID(NodeContainer, cmp.Or(e.Name, e.ID))
+		if project := e.Meta[dlabels.MetaComposeProject]; project != "" {
+			g.implicit(NodeProject, project)
+			if service := e.Meta[dlabels.MetaComposeService]; service != "" {
+				svc := g.implicit(NodeService, project+"/"+service)
+				g.addEdge(Edge{From: ID(NodeProject, project), To: svc, Kind: EdgeContains})
+				g.addEdge(Edge{From: svc, To: ctr, Kind: EdgeContains})
+			} else {
+				g.addEdge(Edge{From: ID(NodeProject, project), To: ctr, Kind: EdgeContains})
+			}
+		}
+		for _, m := range dlabels.ParseMounts(e.Meta[dlabels.MetaMounts]) {
+			if m.Volume == "" {
+				continue
+			}
+			detail := m.Destination
+			if m.ReadOnly {
+				detail += ":ro"
+			}
+			g.addEdge(Edge{From: ctr, To: g.implicit(NodeVolume, m.Volume), Kind: EdgeMounts, Detail: detail})
+		}
+		for _, n := range dlabels.SplitList(e.Meta[dlabels.MetaNetworks]) {
+			g.addEdge(Edge{From: ctr, To: g.implicit(NodeNetwork, n), Kind: EdgeAttached})
+		}
+	}
+
+	g.sort()
+	return g
+}
+
+// addNode adds n unless a node with its ID exists.
+func (g *Graph) addNode(n Node) {
+	if _, ok := g.index[n.ID]; ok {
+		return
+	}
+	g.index[n.ID] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, n)
+}
+
+// implicit returns the ID of the node of kind k named name, adding an implicit node if needed.
+func (g *Graph) implicit(k NodeKind, name string) NodeID {
+	id := ID(k, name)
+	g.addNode(Node{ID: id, Kind: k, Name: name, Implicit: true})
+	return id
+}
+
+// addEdge adds e unless an identical edge exists.
+func (g *Graph) addEdge(e Edge) {
+	if slices.Contains(g.Edges, e) {
+		return
+	}
+	g.Edges = append(g.Edges, e)
+}
+
+// kindOrder ranks node kinds from the outermost to the innermost.
+var kindOrder = map[NodeKind]int{NodeProject: 0, NodeService: 1, NodeContainer: 2, NodeVolume: 3, NodeNetwork: 4}
+
+func compareIDs(a, b NodeID) int {
+	return cmp.Or(cmp.Compare(kindOrder[a.Kind()], kindOrder[b.Kind()]), strings.Compare(a.Name(), b.Name()))
+}
+
+// sort orders nodes and edges and rebuilds the indexes.
+func (g *Graph) sort() {
+	slices.SortFunc(g.Nodes, func(a, b Node) int { return compareIDs(a.ID, b.ID) })
+	slices.SortFunc(g.Edges, func(a, b Edge) int {
+		return cmp.Or(compareIDs(a.From, b.From), compareIDs(a.To, b.To), strings.Compare(a.Detail, b.Detail))
+	})
+	g.index = make(map[NodeID]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		g.index[n.ID] = i
+	}
+	g.in = make(map[NodeID][]int)
+	for i, e := range g.Edges {
+		g.in[e.To] = append(g.in[e.To], i)
+	}
+}
+
+// Node returns the node with the given ID.
+func (g *Graph) Node(id NodeID) (Node, bool) {
+	i, ok := g.index[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
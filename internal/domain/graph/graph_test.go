@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// shopSnapshot is a compose project with a web and a db container sharing a
+// network, the db mounting a labeled volume and a bind mount
+func shopSnapshot() dlabels.Snapshot {
+	return dlabels.Snapshot{Entities: []dlabels.LabeledEntity{
+		{
+			Kind: dlabels.KindContainer, ID: "c1", Name: "shop-web-1",
+			Labels: map[string]string{"bosun.role": "web"},
+			Meta:   map[string]string{dlabels.MetaComposeProject: "shop", dlabels.MetaComposeService: "web", dlabels.MetaNetworks: "shop_default"},
+		},
+		{
+			Kind: dlabels.KindContainer, ID: "c2", Name: "shop-db-1",
+			Labels: map[string]string{"bosun.role": "db"},
+			Meta: map[string]string{
+				dlabels.MetaComposeProject: "shop", dlabels.MetaComposeService: "db",
+				dlabels.MetaMounts:   "/srv/init.sql:/docker-entrypoint-initdb.d/init.sql:ro,/run,shop_db-data:/var/lib/postgresql/data",
+				dlabels.MetaNetworks: "shop_backend,shop_default",
+			},
+		},
+		{
+			Kind: dlabels.KindContainer, ID: "c3", Name: "backup",
+			Labels: map[string]string{"bosun.role": "backup"},
+			Meta:   map[string]string{dlabels.MetaMounts: "shop_db-data:/data:ro"},
+		},
+		{Kind: dlabels.KindVolume, ID: "shop_db-data", Name: "shop_db-data", Labels: map[string]string{"bosun.backup": "daily"}},
+		{Kind: dlabels.KindNetwork, ID: "n1", Name: "shop_default", Labels: map[string]string{"bosun.net": "front"}},
+	}}
+}
+
+func ids(nodes []Node) []NodeID {
+	var out []NodeID
+	for _, n := range nodes {
+		out = append(out, n.ID)
+	}
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	g := Build(shopSnapshot())
+
+	wantNodes := []NodeID{
+		"project/shop",
+		"service/shop/db", "service/shop/web",
+		"container/backup", "container/shop-db-1", "container/shop-web-1",
+		"volume/shop_db-data",
+		"network/shop_backend", "network/shop_default",
+	}
+	if got := ids(g.Nodes); !reflect.DeepEqual(got, wantNodes) {
+		t.Errorf("nodes = %v, want %v", got, wantNodes)
+	}
+	for _, n := range g.Nodes {
+		wantImplicit := n.Kind == NodeProject || n.Kind == NodeService || n.ID == "network/shop_backend"
+		if n.Implicit != wantImplicit {
+			t.Errorf("%s implicit = %v, want %v", n.ID, n.Implicit, wantImplicit)
+		}
+	}
+
+	wantEdges := []Edge{
+		{From: "project/shop", To: "service/shop/db", Kind: EdgeContains},
+		{From: "project/shop", To: "service/shop/web", Kind: EdgeContains},
+		{From: "service/shop/db", To: "container/shop-db-1", Kind: EdgeContains},
+		{From: "service/shop/web", To: "container/shop-web-1", Kind: EdgeContains},
+		{From: "container/backup", To: "volume/shop_db-data", Kind: EdgeMounts, Detail: "/data:ro"},
+		{From: "container/shop-db-1", To: "volume/shop_db-data", Kind: EdgeMounts, Detail: "/var/lib/postgresql/data"},
+		{From: "container/shop-db-1", To: "network/shop_backend", Kind: EdgeAttached},
+		{From: "container/shop-db-1", To: "network/shop_default", Kind: EdgeAttached},
+		{From: "container/shop-web-1", To: "network/shop_default", Kind: EdgeAttached},
+	}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", g.Edges, wantEdges)
+	}
+}
+
+func TestDependentsAndImpact(t *testing.T) {
+	g := Build(shopSnapshot())
+
+	if got, want := ids(g.Dependents("volume/shop_db-data")), []NodeID{"container/backup", "container/shop-db-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(volume) = %v, want %v", got, want)
+	}
+	if got := g.Dependents("volume/missing"); got != nil {
+		t.Errorf("Dependents(missing) = %v, want none", got)
+	}
+
+	want := []NodeID{"project/shop", "service/shop/db", "service/shop/web", "container/shop-db-1", "container/shop-web-1"}
+	if got := ids(g.Impact("network/shop_default")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Impact(network) = %v, want %v", got, want)
+	}
+	want = []NodeID{"project/shop", "service/shop/db", "container/backup", "container/shop-db-1"}
+	if got := ids(g.Impact("volume/shop_db-data")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Impact(volume) = %v, want %v", got, want)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	if id, ok := ParseID("service/shop/web"); !ok || id.Kind() != NodeService || id.Name() != "shop/web" {
+		t.Errorf("ParseID(service/shop/web) = %q, %v", id, ok)
+	}
+	for _, s := range []string{"shop", "pod/x", "volume/"} {
+		if _, ok := ParseID(s); ok {
+			t.Errorf("ParseID(%q) succeeded, expected failure", s)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := Build(dlabels.Snapshot{Entities: []dlabels.LabeledEntity{{
+		Kind: dlabels.KindContainer, ID: "c1", Name: `odd"name`,
+		Meta: map[string]string{dlabels.MetaMounts: "data:/data:ro", dlabels.MetaNetworks: "net"},
+	}}})
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph bosun {
+  rankdir=LR;
+  "container/odd\"name" [label="odd\"name", shape=box];
+  "volume/data" [label="data", shape=cylinder, style=dashed];
+  "network/net" [label="net", shape=ellipse, style=dashed];
+  "container/odd\"name" -> "volume/data" [label="/data:ro"];
+  "container/odd\"name" -> "network/net" [style=dotted];
+}
+`
+	if b.String() != want {
+		t.Errorf("WriteDOT =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	g := Build(dlabels.Snapshot{Entities: []dlabels.LabeledEntity{{
+		Kind: dlabels.KindContainer, ID: "c1", Name: `odd"name`,
+		Meta: map[string]string{dlabels.MetaComposeProject: "shop", dlabels.MetaComposeService: "web", dlabels.MetaMounts: "data:/data:ro"},
+	}}})
+	var b strings.Builder
+	if err := g.WriteMermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `flowchart LR
+  n0[["shop"]]
+  n1(["shop/web"])
+  n2["odd#quot;name"]
+  n3[("data")]
+  n0 --> n1
+  n1 --> n2
+  n2 -->|"/data:ro"| n3
+  classDef implicit stroke-dasharray: 5 5
+  class n0,n1,n3 implicit
+`
+	if b.String() != want {
+		t.Errorf("WriteMermaid =\n%s\nwant\n%s", b.String(), want)
+	}
+}
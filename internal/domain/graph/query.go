@@ -0,0 +1,43 @@
+package graph
+
+import "slices"
+
+// Dependents returns the nodes with an edge to id, e.g. the containers mounting
+// a volume or attached to a network, sorted by kind and name.
+func (g *Graph) Dependents(id NodeID) []Node {
+	var out []Node
+	for _, i := range g.in[id] {
+		if n, ok := g.Node(g.Edges[i].From); ok && !slices.ContainsFunc(out, func(o Node) bool { return o.ID == n.ID }) {
+			out = append(out, n)
+		}
+	}
+	slices.SortFunc(out, func(a, b Node) int { return compareIDs(a.ID, b.ID) })
+	return out
+}
+
+// Impact returns the nodes that depend on id directly or transitively, i.e.
+// what breaks if id is removed: for a network, the containers attached to it,
+// their services and their projects. The result is sorted by kind and name and
+// does not contain id itself.
+func (g *Graph) Impact(id NodeID) []Node {
+	seen := map[NodeID]bool{id: true}
+	queue := []NodeID{id}
+	var out []Node
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, i := range g.in[cur] {
+			from := g.Edges[i].From
+			if seen[from] {
+				continue
+			}
+			seen[from] = true
+			queue = append(queue, from)
+			if n, ok := g.Node(from); ok {
+				out = append(out, n)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b Node) int { return compareIDs(a.ID, b.ID) })
+	return out
+}
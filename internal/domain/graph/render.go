@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotShapes are the Graphviz node shapes per kind.
+var dotShapes = map[NodeKind]string{
+	NodeProject:   "folder",
+	NodeService:   "component",
+	NodeContainer: "box",
+	NodeVolume:    "cylinder",
+	NodeNetwork:   "ellipse",
+}
+
+// WriteDOT writes g in the Graphviz DOT language. Implicit nodes are dashed
+// and mount edges are labeled with the mount destination.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph bosun {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s, shape=%s", dotQuote(n.Name), dotShapes[n.Kind])
+		if n.Implicit {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(string(n.ID)), attrs)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Detail != "" {
+			attrs = append(attrs, "label="+dotQuote(e.Detail))
+		}
+		if e.Kind == EdgeAttached {
+			attrs = append(attrs, "style=dotted")
+		}
+		fmt.Fprintf(bw, "  %s -> %s", dotQuote(string(e.From)), dotQuote(string(e.To)))
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// mermaidShapes are the opening and closing delimiters of Mermaid node shapes per kind.
+var mermaidShapes = map[NodeKind][2]string{
+	NodeProject:   {"[[", "]]"},
+	NodeService:   {"([", "])"},
+	NodeContainer: {"[", "]"},
+	NodeVolume:    {"[(", ")]"},
+	NodeNetwork:   {"((", "))"},
+}
+
+// WriteMermaid writes g as a Mermaid flowchart. Nodes get generated IDs, as
+// Mermaid IDs cannot hold every character of a name. Implicit nodes have the
+// "implicit" class and are dashed.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	ids := make(map[NodeID]string, len(g.Nodes))
+	var implicit []string
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		shape := mermaidShapes[n.Kind]
+		fmt.Fprintf(bw, "  %s%s%s%s\n", id, shape[0], mermaidQuote(n.Name), shape[1])
+		if n.Implicit {
+			implicit = append(implicit, id)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == EdgeAttached {
+			arrow = "-.->"
+		}
+		if e.Detail != "" {
+			arrow += "|" + mermaidQuote(e.Detail) + "|"
+		}
+		fmt.Fprintf(bw, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	if len(implicit) > 0 {
+		fmt.Fprintln(bw, "  classDef implicit stroke-dasharray: 5 5")
+		fmt.Fprintf(bw, "  class %s implicit\n", strings.Join(implicit, ","))
+	}
+	return bw.Flush()
+}
+
+// mermaidQuote returns s as a Mermaid quoted label, using entity codes for
+// characters Mermaid cannot hold in one.
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+}
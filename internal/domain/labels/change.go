@@ -0,0 +1,22 @@
+package labels
+
+import "time"
+
+// ChangeType describes what happened to a labeled entity.
+type ChangeType string
+
+const (
+	ChangeAdded         ChangeType = "added"
+	ChangeRemoved       ChangeType = "removed"
+	ChangeLabelsChanged ChangeType = "labels-changed"
+	ChangeStateChanged  ChangeType = "state-changed"
+	ChangeRenamed       ChangeType = "renamed"
+)
+
+// Change is a single incremental update to the set of labeled entities.
+type Change struct {
+	Type   ChangeType    `json:"type"`
+	Entity LabeledEntity `json:"entity"`          // current entity; last known entity for ChangeRemoved
+	State  string        `json:"state,omitempty"` // container state after the change, e.g. "running", "exited"
+	At     time.Time     `json:"at"`
+}
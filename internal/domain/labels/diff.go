@@ -0,0 +1,146 @@
+package labels
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// EntityKey identifies an entity across snapshots.
+type EntityKey struct {
+	Kind Kind
+	ID   string
+}
+
+// Key returns the identity of the entity.
+func (e LabeledEntity) Key() EntityKey {
+	return EntityKey{Kind: e.Kind, ID: e.ID}
+}
+
+// kindOrder ranks kinds for display: containers, then volumes, then networks.
+var kindOrder = map[Kind]int{
+	KindContainer: 0,
+	KindVolume:    1,
+	KindNetwork:   2,
+}
+
+// SortEntities sorts entities by Kind (container < volume < network), then by Name and ID.
+func SortEntities(entities []LabeledEntity) {
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, b := entities[i], entities[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+}
+
+// KeyChangeType describes how a single label or meta key changed.
+type KeyChangeType string
+
+const (
+	KeyAdded    KeyChangeType = "added"
+	KeyRemoved  KeyChangeType = "removed"
+	KeyModified KeyChangeType = "modified"
+)
+
+// KeyChange is a change of a single label or meta key.
+type KeyChange struct {
+	Key  string        `json:"key"`
+	Type KeyChangeType `json:"type"`
+	Old  string        `json:"old,omitempty"` // empty for KeyAdded
+	New  string        `json:"new,omitempty"` // empty for KeyRemoved
+}
+
+// EntityDiff lists the label and meta changes of an entity present in both snapshots.
+type EntityDiff struct {
+	Kind   Kind        `json:"kind"`
+	ID     string      `json:"id"`
+	Name   string      `json:"name"` // name in the newer snapshot
+	Labels []KeyChange `json:"labels,omitempty"`
+	Meta   []KeyChange `json:"meta,omitempty"`
+}
+
+// SnapshotDiff is the result of comparing two snapshots.
+type SnapshotDiff struct {
+	Added   []LabeledEntity `json:"added"`
+	Removed []LabeledEntity `json:"removed"`
+	Changed []EntityDiff    `json:"changed"`
+}
+
+// Empty reports whether the two snapshots had the same entities, labels and meta.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two snapshots by (Kind, ID). Entities only in newer are Added,
+// entities only in older are Removed, and entities in both with different
+// labels or meta are Changed. All lists are in SortEntities order and key
+// changes are sorted by key.
+func Diff(older, newer Snapshot) SnapshotDiff {
+	oldByKey := make(map[EntityKey]LabeledEntity, len(older.Entities))
+	for _, e := range older.Entities {
+		oldByKey[e.Key()] = e
+	}
+
+	var d SnapshotDiff
+	var changed []LabeledEntity
+	changes := make(map[EntityKey]EntityDiff)
+	for _, e := range newer.Entities {
+		prev, ok := oldByKey[e.Key()]
+		if !ok {
+			d.Added = append(d.Added, e)
+			continue
+		}
+		delete(oldByKey, e.Key())
+
+		ed := EntityDiff{
+			Kind:   e.Kind,
+			ID:     e.ID,
+			Name:   e.Name,
+			Labels: DiffMaps(prev.Labels, e.Labels),
+			Meta:   DiffMaps(prev.Meta, e.Meta),
+		}
+		if len(ed.Labels) > 0 || len(ed.Meta) > 0 {
+			changed = append(changed, e)
+			changes[e.Key()] = ed
+		}
+	}
+	for _, e := range oldByKey {
+		d.Removed = append(d.Removed, e)
+	}
+
+	SortEntities(d.Added)
+	SortEntities(d.Removed)
+	SortEntities(changed)
+	for _, e := range changed {
+		d.Changed = append(d.Changed, changes[e.Key()])
+	}
+	return d
+}
+
+// DiffMaps returns the key changes from older to newer, sorted by key.
+func DiffMaps(older, newer map[string]string) []KeyChange {
+	var out []KeyChange
+	for k, nv := range newer {
+		ov, ok := older[k]
+		switch {
+		case !ok:
+			out = append(out, KeyChange{Key: k, Type: KeyAdded, New: nv})
+		case ov != nv:
+			out = append(out, KeyChange{Key: k, Type: KeyModified, Old: ov, New: nv})
+		}
+	}
+	for k, ov := range older {
+		if _, ok := newer[k]; !ok {
+			out = append(out, KeyChange{Key: k, Type: KeyRemoved, Old: ov})
+		}
+	}
+	slices.SortFunc(out, func(a, b KeyChange) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return out
+}
@@ -0,0 +1,113 @@
+package labels
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	older := Snapshot{
+		TakenAt: time.Now().Add(-time.Hour),
+		Entities: []LabeledEntity{
+			{Kind: KindContainer, ID: "c1", Name: "web", Labels: map[string]string{"bosun.role": "web", "bosun.env": "dev"}, Meta: map[string]string{"image": "nginx:1.25"}},
+			{Kind: KindContainer, ID: "c2", Name: "old-worker", Labels: map[string]string{"bosun.role": "worker"}},
+			{Kind: KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}, Meta: map[string]string{"driver": "local"}},
+		},
+	}
+	newer := Snapshot{
+		TakenAt: time.Now(),
+		Entities: []LabeledEntity{
+			{Kind: KindNetwork, ID: "n1", Name: "front", Labels: map[string]string{"bosun.zone": "dmz"}},
+			{Kind: KindContainer, ID: "c1", Name: "web", Labels: map[string]string{"bosun.role": "api", "bosun.tier": "1"}, Meta: map[string]string{"image": "nginx:1.27"}},
+			{Kind: KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}, Meta: map[string]string{"driver": "local"}},
+			{Kind: KindContainer, ID: "c3", Name: "new-worker", Labels: map[string]string{"bosun.role": "worker"}},
+		},
+	}
+
+	d := Diff(older, newer)
+
+	if len(d.Added) != 2 || d.Added[0].ID != "c3" || d.Added[1].ID != "n1" {
+		t.Errorf("Added = %+v, expected c3 then n1", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].ID != "c2" {
+		t.Errorf("Removed = %+v, expected c2", d.Removed)
+	}
+	if len(d.Changed) != 1 {
+		t.Fatalf("expected 1 changed entity, got %d", len(d.Changed))
+	}
+
+	c := d.Changed[0]
+	if c.Kind != KindContainer || c.ID != "c1" {
+		t.Errorf("Changed entity = %s/%s, expected container/c1", c.Kind, c.ID)
+	}
+	wantLabels := []KeyChange{
+		{Key: "bosun.env", Type: KeyRemoved, Old: "dev"},
+		{Key: "bosun.role", Type: KeyModified, Old: "web", New: "api"},
+		{Key: "bosun.tier", Type: KeyAdded, New: "1"},
+	}
+	if !reflect.DeepEqual(c.Labels, wantLabels) {
+		t.Errorf("Labels = %+v, expected %+v", c.Labels, wantLabels)
+	}
+	wantMeta := []KeyChange{{Key: "image", Type: KeyModified, Old: "nginx:1.25", New: "nginx:1.27"}}
+	if !reflect.DeepEqual(c.Meta, wantMeta) {
+		t.Errorf("Meta = %+v, expected %+v", c.Meta, wantMeta)
+	}
+}
+
+func TestDiff_JSON(t *testing.T) {
+	d := SnapshotDiff{
+		Removed: []LabeledEntity{{Kind: KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}}},
+		Changed: []EntityDiff{{Kind: KindContainer, ID: "c1", Name: "web", Labels: []KeyChange{{Key: "bosun.role", Type: KeyModified, Old: "web", New: "api"}}}},
+	}
+	got, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"added":null,` +
+		`"removed":[{"kind":"volume","id":"data","name":"data","labels":{"bosun.backup":"daily"}}],` +
+		`"changed":[{"kind":"container","id":"c1","name":"web","labels":[{"key":"bosun.role","type":"modified","old":"web","new":"api"}]}]}`
+	if string(got) != want {
+		t.Errorf("JSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiff_Identical(t *testing.T) {
+	s := Snapshot{Entities: []LabeledEntity{
+		{Kind: KindVolume, ID: "data", Name: "data", Labels: map[string]string{"bosun.backup": "daily"}},
+	}}
+	if d := Diff(s, s); !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+}
+
+func TestDiff_SameIDDifferentKind(t *testing.T) {
+	older := Snapshot{Entities: []LabeledEntity{{Kind: KindVolume, ID: "shared", Name: "shared"}}}
+	newer := Snapshot{Entities: []LabeledEntity{{Kind: KindNetwork, ID: "shared", Name: "shared"}}}
+
+	d := Diff(older, newer)
+	if len(d.Added) != 1 || len(d.Removed) != 1 || len(d.Changed) != 0 {
+		t.Errorf("expected one added and one removed entity, got %+v", d)
+	}
+}
+
+func TestSortEntities(t *testing.T) {
+	entities := []LabeledEntity{
+		{Kind: KindNetwork, Name: "net-b"},
+		{Kind: KindContainer, Name: "ctr-b"},
+		{Kind: KindVolume, Name: "vol-a"},
+		{Kind: KindContainer, Name: "ctr-a", ID: "2"},
+		{Kind: KindContainer, Name: "ctr-a", ID: "1"},
+	}
+	SortEntities(entities)
+
+	var got []string
+	for _, e := range entities {
+		got = append(got, e.Name+e.ID)
+	}
+	want := []string{"ctr-a1", "ctr-a2", "ctr-b", "vol-a", "net-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortEntities order = %v, want %v", got, want)
+	}
+}
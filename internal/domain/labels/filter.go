@@ -0,0 +1,101 @@
+package labels
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Filter narrows entities to the kinds, compose projects and label prefixes a
+// consumer selected. Empty fields select everything.
+type Filter struct {
+	Prefixes []string
+	Kinds    []Kind
+	Projects []string // matched against MetaComposeProject
+
+	// Match, if set, must also accept the entity after its labels are filtered.
+	Match func(LabeledEntity) bool
+}
+
+// Apply returns e with only the labels under Prefixes, and false if e is not
+// selected or keeps no label. The project is read from MetaComposeProject, which
+// sources resolve from whichever compose label the backend sets.
+func (f Filter) Apply(e LabeledEntity) (LabeledEntity, bool) {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, e.Kind) {
+		return e, false
+	}
+	if len(f.Projects) > 0 && !slices.Contains(f.Projects, e.Meta[MetaComposeProject]) {
+		return e, false
+	}
+	if len(f.Prefixes) == 0 {
+		return e, len(e.Labels) > 0 && (f.Match == nil || f.Match(e))
+	}
+
+	kept := make(map[string]string)
+	for k, v := range e.Labels {
+		for _, p := range f.Prefixes {
+			if strings.HasPrefix(k, p) {
+				kept[k] = v
+				break
+			}
+		}
+	}
+	e.Labels = kept
+	return e, len(kept) > 0 && (f.Match == nil || f.Match(e))
+}
+
+// View follows a stream of changes through a Filter. It remembers the filtered
+// entities seen so far, so that a change upstream becomes the change a consumer
+// of the filtered stream observes: an entity gaining its first selected label is
+// added, one losing its last is removed, and changes to unselected labels vanish.
+type View struct {
+	filter Filter
+	known  map[EntityKey]LabeledEntity
+}
+
+// NewView returns an empty view through f.
+func NewView(f Filter) *View {
+	return &View{filter: f, known: make(map[EntityKey]LabeledEntity)}
+}
+
+// Entities returns the filtered entities currently in the view, sorted.
+func (v *View) Entities() []LabeledEntity {
+	out := slices.Collect(maps.Values(v.known))
+	SortEntities(out)
+	return out
+}
+
+// Apply records c and returns the filtered change, if any.
+func (v *View) Apply(c Change) (Change, bool) {
+	key := c.Entity.Key()
+	prev, known := v.known[key]
+
+	cur, ok := LabeledEntity{}, false
+	if c.Type != ChangeRemoved {
+		cur, ok = v.filter.Apply(c.Entity)
+	}
+
+	switch {
+	case !ok && !known:
+		return Change{}, false
+	case !ok:
+		delete(v.known, key)
+		return Change{Type: ChangeRemoved, Entity: prev, State: c.State, At: c.At}, true
+	}
+
+	v.known[key] = cur
+	out := Change{Entity: cur, State: c.State, At: c.At}
+	switch {
+	case !known:
+		out.Type = ChangeAdded
+	case !maps.Equal(prev.Labels, cur.Labels):
+		out.Type = ChangeLabelsChanged
+	case prev.Name != cur.Name:
+		out.Type = ChangeRenamed
+	case c.Type == ChangeStateChanged:
+		out.Type = ChangeStateChanged
+	default:
+		return Change{}, false
+	}
+	return out, true
+}
@@ -0,0 +1,101 @@
+package labels
+
+import "testing"
+
+func TestFilter_Apply(t *testing.T) {
+	e := LabeledEntity{
+		Kind: KindVolume,
+		ID:   "data",
+		Labels: map[string]string{
+			"bosun.backup":      "daily",
+			"traefik.enable":    "true",
+			LabelComposeProject: "shop",
+		},
+		Meta: map[string]string{MetaComposeProject: "shop"},
+	}
+
+	tests := []struct {
+		name       string
+		filter     Filter
+		wantOK     bool
+		wantLabels int
+	}{
+		{"empty filter", Filter{}, true, 3},
+		{"prefix", Filter{Prefixes: []string{"bosun."}}, true, 1},
+		{"prefix without match", Filter{Prefixes: []string{"acme."}}, false, 0},
+		{"kind", Filter{Kinds: []Kind{KindContainer}}, false, 0},
+		{"project", Filter{Projects: []string{"shop"}, Prefixes: []string{"bosun."}}, true, 1},
+		{"other project", Filter{Projects: []string{"blog"}}, false, 0},
+		{"match sees filtered labels", Filter{Prefixes: []string{"bosun."}, Match: func(e LabeledEntity) bool {
+			return len(e.Labels) == 1 && e.Labels["bosun.backup"] == "daily"
+		}}, true, 1},
+		{"match rejects", Filter{Match: func(LabeledEntity) bool { return false }}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.filter.Apply(e)
+			if ok != tt.wantOK {
+				t.Fatalf("Apply() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && len(got.Labels) != tt.wantLabels {
+				t.Errorf("Apply() labels = %v, want %d labels", got.Labels, tt.wantLabels)
+			}
+		})
+	}
+	if len(e.Labels) != 3 {
+		t.Error("Apply must not modify its input")
+	}
+}
+
+func TestFilter_ApplyProjectFromMeta(t *testing.T) {
+	// Podman entities carry the project in another label; sources resolve it into Meta
+	e := LabeledEntity{
+		Kind:   KindContainer,
+		ID:     "web",
+		Labels: map[string]string{"bosun.role": "web", "io.podman.compose.project": "shop"},
+		Meta:   map[string]string{MetaComposeProject: "shop"},
+	}
+	if _, ok := (Filter{Projects: []string{"shop"}}).Apply(e); !ok {
+		t.Error("expected the project in Meta to match")
+	}
+	delete(e.Meta, MetaComposeProject)
+	e.Labels[LabelComposeProject] = "shop"
+	if _, ok := (Filter{Projects: []string{"shop"}}).Apply(e); ok {
+		t.Error("expected the project label alone not to match")
+	}
+}
+
+func TestView_Apply(t *testing.T) {
+	v := NewView(Filter{Prefixes: []string{"bosun."}})
+	web := func(labels map[string]string) LabeledEntity {
+		return LabeledEntity{Kind: KindContainer, ID: "c1", Name: "web", Labels: labels}
+	}
+
+	steps := []struct {
+		name   string
+		in     Change
+		want   ChangeType
+		wantOK bool
+	}{
+		{"unselected labels only", Change{Type: ChangeAdded, Entity: web(map[string]string{"traefik.enable": "true"})}, "", false},
+		{"first selected label adds", Change{Type: ChangeLabelsChanged, Entity: web(map[string]string{"traefik.enable": "true", "bosun.role": "web"})}, ChangeAdded, true},
+		{"unselected label change vanishes", Change{Type: ChangeLabelsChanged, Entity: web(map[string]string{"traefik.enable": "false", "bosun.role": "web"})}, "", false},
+		{"selected label change", Change{Type: ChangeLabelsChanged, Entity: web(map[string]string{"bosun.role": "api"})}, ChangeLabelsChanged, true},
+		{"state change passes", Change{Type: ChangeStateChanged, Entity: web(map[string]string{"bosun.role": "api"}), State: "paused"}, ChangeStateChanged, true},
+		{"rename passes", Change{Type: ChangeRenamed, Entity: LabeledEntity{Kind: KindContainer, ID: "c1", Name: "web-old", Labels: map[string]string{"bosun.role": "api"}}}, ChangeRenamed, true},
+		{"last selected label removes", Change{Type: ChangeLabelsChanged, Entity: web(map[string]string{"traefik.enable": "true"})}, ChangeRemoved, true},
+		{"removal of unknown entity vanishes", Change{Type: ChangeRemoved, Entity: web(nil)}, "", false},
+	}
+	for _, s := range steps {
+		got, ok := v.Apply(s.in)
+		if ok != s.wantOK || got.Type != s.want {
+			t.Fatalf("%s: Apply() = %q, %v, want %q, %v", s.name, got.Type, ok, s.want, s.wantOK)
+		}
+		if ok && got.Type == ChangeRemoved && got.Entity.Labels["bosun.role"] != "api" {
+			t.Errorf("%s: removed entity = %+v, want the last known filtered entity", s.name, got.Entity)
+		}
+	}
+	if len(v.Entities()) != 0 {
+		t.Errorf("Entities() = %v, want none", v.Entities())
+	}
+}
@@ -0,0 +1,29 @@
+//go:build ignore
+
+// gen_schema writes the JSON Schema of the current snapshot wire format to
+// <dir>/snapshot.v<N>.schema.json. Run it with go generate.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: go run gen_schema.go DIR")
+		os.Exit(2)
+	}
+	b, err := dlabels.SnapshotJSONSchema()
+	if err == nil {
+		name := fmt.Sprintf("snapshot.v%d.schema.json", dlabels.SchemaVersion)
+		err = os.WriteFile(filepath.Join(os.Args[1], name), b, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
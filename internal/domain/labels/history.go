@@ -0,0 +1,52 @@
+package labels
+
+import "time"
+
+// SnapshotInfo describes a snapshot kept in a history.
+type SnapshotInfo struct {
+	ID      string // store specific identifier
+	TakenAt time.Time
+	Size    int64 // stored size in bytes
+}
+
+// RetentionPolicy decides which snapshots of a history are dropped.
+// The zero value keeps everything.
+type RetentionPolicy struct {
+	KeepLast int           // never drop the most recent KeepLast snapshots
+	MaxAge   time.Duration // drop snapshots older than this; 0 ignores age
+}
+
+// Expired returns the snapshots of history, sorted oldest first, that the policy drops at now.
+func (p RetentionPolicy) Expired(history []SnapshotInfo, now time.Time) []SnapshotInfo {
+	if p.KeepLast <= 0 && p.MaxAge <= 0 {
+		return nil
+	}
+
+	candidates := history
+	if p.KeepLast > 0 {
+		candidates = history[:max(len(history)-p.KeepLast, 0)]
+	}
+	if p.MaxAge <= 0 {
+		return candidates
+	}
+
+	cutoff := now.Add(-p.MaxAge)
+	var out []SnapshotInfo
+	for _, s := range candidates {
+		if s.TakenAt.Before(cutoff) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// SnapshotAt returns the latest snapshot of history, sorted oldest first,
+// taken at or before t, i.e. the one describing the state at t.
+func SnapshotAt(history []SnapshotInfo, t time.Time) (SnapshotInfo, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].TakenAt.After(t) {
+			return history[i], true
+		}
+	}
+	return SnapshotInfo{}, false
+}
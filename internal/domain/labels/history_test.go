@@ -0,0 +1,72 @@
+package labels
+
+import (
+	"testing"
+	"time"
+)
+
+func historyOf(base time.Time, days ...int) []SnapshotInfo {
+	var out []SnapshotInfo
+	for _, d := range days {
+		at := base.AddDate(0, 0, d)
+		out = append(out, SnapshotInfo{ID: at.Format("0102"), TakenAt: at})
+	}
+	return out
+}
+
+func ids(infos []SnapshotInfo) []string {
+	var out []string
+	for _, s := range infos {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func TestRetentionPolicy_Expired(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := historyOf(base, 0, 1, 2, 3, 4)
+	now := base.AddDate(0, 0, 5)
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"zero keeps all", RetentionPolicy{}, nil},
+		{"keep last", RetentionPolicy{KeepLast: 2}, []string{"0301", "0302", "0303"}},
+		{"keep more than stored", RetentionPolicy{KeepLast: 10}, nil},
+		{"max age", RetentionPolicy{MaxAge: 72 * time.Hour}, []string{"0301", "0302"}},
+		{"keep last wins over age", RetentionPolicy{KeepLast: 4, MaxAge: 24 * time.Hour}, []string{"0301"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(tt.policy.Expired(history, now))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expired() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expired() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := historyOf(base, 0, 2, 4)
+
+	if _, ok := SnapshotAt(history, base.Add(-time.Second)); ok {
+		t.Error("Expected no snapshot before the first one")
+	}
+	if s, ok := SnapshotAt(history, base.AddDate(0, 0, 2)); !ok || s.ID != "0303" {
+		t.Errorf("SnapshotAt(exact) = %v, %v, want 0303", s.ID, ok)
+	}
+	if s, ok := SnapshotAt(history, base.AddDate(0, 0, 3)); !ok || s.ID != "0303" {
+		t.Errorf("SnapshotAt(between) = %v, %v, want 0303", s.ID, ok)
+	}
+	if s, ok := SnapshotAt(history, base.AddDate(1, 0, 0)); !ok || s.ID != "0305" {
+		t.Errorf("SnapshotAt(later) = %v, %v, want 0305", s.ID, ok)
+	}
+}
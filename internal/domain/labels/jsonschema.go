@@ -0,0 +1,66 @@
+package labels
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//go:generate go run gen_schema.go ../../../docs/schema
+
+// SchemaID is the URL the JSON Schema of each snapshot version is published at.
+const SchemaID = "https://raw.githubusercontent.com/simone-viozzi/bosun/main/docs/schema/snapshot.v%d.schema.json"
+
+// SnapshotJSONSchema returns the JSON Schema (draft 2020-12) of the current
+// snapshot wire format. It is derived from the json tags of the wire types,
+// so it cannot drift from what MarshalJSON writes.
+func SnapshotJSONSchema() ([]byte, error) {
+	root := typeSchema(reflect.TypeFor[wireSnapshot]())
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = fmt.Sprintf(SchemaID, SchemaVersion)
+	root["title"] = "Bosun label snapshot"
+	root["properties"].(map[string]any)["schemaVersion"] = map[string]any{"const": SchemaVersion}
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// typeSchema maps a Go type of the wire format onto a JSON Schema. Fields
+// without omitempty are required.
+func typeSchema(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[Kind]():
+		return map[string]any{"type": "string", "enum": []Kind{KindContainer, KindVolume, KindNetwork}}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		required := []string{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			props[name] = typeSchema(f.Type)
+			if opts != "omitempty" {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	panic(fmt.Sprintf("no JSON Schema for %s", t))
+}
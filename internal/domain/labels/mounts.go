@@ -0,0 +1,100 @@
+package labels
+
+import (
+	"cmp"
+	"strings"
+)
+
+// Mount is one entry of the MetaMounts value.
+type Mount struct {
+	Volume      string // volume name; empty for bind and tmpfs mounts
+	Source      string // host path of a bind mount
+	Destination string
+	ReadOnly    bool
+}
+
+// String renders m in the --volume notation: "NAME:DEST" for volumes,
+// "SOURCE:DEST" for bind mounts, ":ro" appended when read-only, and "DEST"
+// alone for tmpfs. Commas and backslashes are escaped with a backslash, so
+// that a path containing a comma does not split the MetaMounts list.
+func (m Mount) String() string {
+	s := escapeMount(m.Destination)
+	if src := cmp.Or(m.Volume, m.Source); src != "" {
+		s = escapeMount(src) + ":" + s
+	}
+	if m.ReadOnly {
+		s += ":ro"
+	}
+	return s
+}
+
+// FormatMounts joins mounts into a MetaMounts value, in the given order.
+func FormatMounts(mounts []Mount) string {
+	out := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		out = append(out, m.String())
+	}
+	return strings.Join(out, ",")
+}
+
+// ParseMounts parses a MetaMounts value written by FormatMounts. Volume names
+// never start with "/", which tells them apart from bind mount sources.
+func ParseMounts(s string) []Mount {
+	var out []Mount
+	for _, item := range splitMounts(s) {
+		parts := strings.Split(item, ":")
+		var m Mount
+		if n := len(parts); n > 1 && parts[n-1] == "ro" {
+			m.ReadOnly = true
+			parts = parts[:n-1]
+		}
+		switch len(parts) {
+		case 1:
+			m.Destination = parts[0]
+		default:
+			src := parts[0]
+			m.Destination = strings.Join(parts[1:], ":")
+			if strings.HasPrefix(src, "/") {
+				m.Source = src
+			} else {
+				m.Volume = src
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+var mountEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`)
+
+func escapeMount(s string) string {
+	return mountEscaper.Replace(s)
+}
+
+// splitMounts splits s on unescaped commas and unescapes the items, dropping
+// empty ones like SplitList.
+func splitMounts(s string) []string {
+	var (
+		out []string
+		cur strings.Builder
+	)
+	flush := func() {
+		if v := strings.TrimSpace(cur.String()); v != "" {
+			out = append(out, v)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+		case c == ',':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,36 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMounts(t *testing.T) {
+	got := ParseMounts("/srv/conf:/etc/conf:ro,/run,db-data:/var/lib/data,cache:/cache:ro")
+	want := []Mount{
+		{Source: "/srv/conf", Destination: "/etc/conf", ReadOnly: true},
+		{Destination: "/run"},
+		{Volume: "db-data", Destination: "/var/lib/data"},
+		{Volume: "cache", Destination: "/cache", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMounts = %+v, want %+v", got, want)
+	}
+	if got := ParseMounts(""); got != nil {
+		t.Errorf("ParseMounts(\"\") = %v, want nil", got)
+	}
+}
+
+func TestFormatMounts_EscapesCommas(t *testing.T) {
+	mounts := []Mount{
+		{Source: `/srv/a,b\c`, Destination: "/data", ReadOnly: true},
+		{Volume: "db", Destination: "/var/lib/x,y"},
+	}
+	s := FormatMounts(mounts)
+	if want := `/srv/a\,b\\c:/data:ro,db:/var/lib/x\,y`; s != want {
+		t.Errorf("FormatMounts = %q, want %q", s, want)
+	}
+	if got := ParseMounts(s); !reflect.DeepEqual(got, mounts) {
+		t.Errorf("ParseMounts(FormatMounts) = %+v, want %+v", got, mounts)
+	}
+}
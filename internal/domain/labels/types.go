@@ -1,6 +1,9 @@
 package labels
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // DefaultLabelPrefix is the standard prefix for Bosun-managed labels.
 const DefaultLabelPrefix = "bosun."
@@ -9,6 +12,37 @@ const DefaultLabelPrefix = "bosun."
 // LabelInstance is the label key for instance identification.
 const LabelInstance = DefaultLabelPrefix + "instance"
 
+// LabelComposeProject is set by Docker Compose on every entity it creates.
+const LabelComposeProject = "com.docker.compose.project"
+
+// Meta keys of LabeledEntity set by the label sources at every detail level.
+const (
+	MetaComposeProject = "compose.project"
+	MetaComposeService = "compose.service"
+	MetaImage          = "image"
+	MetaDriver         = "driver"
+	MetaInstance       = "instance"
+)
+
+// Meta keys added by ports.DetailExtended; networks always have a scope.
+// Mounts and networks are comma-separated lists, see SplitList.
+const (
+	MetaState      = "state"
+	MetaStatus     = "status"
+	MetaPorts      = "ports"
+	MetaMounts     = "mounts"
+	MetaNetworks   = "networks"
+	MetaCreated    = "created"
+	MetaMountpoint = "mountpoint"
+	MetaScope      = "scope"
+)
+
+// Meta keys added by ports.DetailFull.
+const (
+	MetaHealth   = "health"
+	MetaRestarts = "restarts"
+)
+
 type Kind string
 
 const (
@@ -17,15 +51,36 @@ const (
 	KindNetwork   Kind = "network"
 )
 
+// Valid reports whether k is one of the known kinds.
+func (k Kind) Valid() bool {
+	switch k {
+	case KindContainer, KindVolume, KindNetwork:
+		return true
+	}
+	return false
+}
+
 type LabeledEntity struct {
-	Kind   Kind
-	ID     string
-	Name   string
-	Labels map[string]string
-	Meta   map[string]string // e.g., "compose.project", "compose.service", "image", "networks"
+	Kind   Kind              `json:"kind"`
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Meta   map[string]string `json:"meta,omitempty"` // e.g., "compose.project", "compose.service", "image", "networks"
+}
+
+// SplitList splits a comma-separated Meta value, dropping empty items.
+func SplitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
 
+// Snapshot is encoded in the versioned wire format described in wire.go.
 type Snapshot struct {
-	Entities []LabeledEntity
-	TakenAt  time.Time
+	Entities []LabeledEntity `json:"entities"`
+	TakenAt  time.Time       `json:"takenAt"`
 }
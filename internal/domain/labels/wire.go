@@ -0,0 +1,81 @@
+package labels
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the snapshot wire format written by
+// Snapshot.MarshalJSON. Bump it when a field is renamed or removed or its
+// meaning changes, and teach UnmarshalJSON to read the previous version.
+//
+// Versions:
+//
+//	0  Go field names (Entities, TakenAt, Kind, ...), no schemaVersion field
+//	1  lowercase names and schemaVersion
+const SchemaVersion = 1
+
+// wireSnapshot is the current wire format of a Snapshot.
+type wireSnapshot struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	TakenAt       time.Time       `json:"takenAt"`
+	Entities      []LabeledEntity `json:"entities"`
+}
+
+// snapshotV0 is the unversioned format written before SchemaVersion existed.
+type snapshotV0 struct {
+	Entities []struct {
+		Kind   Kind              `json:"Kind"`
+		ID     string            `json:"ID"`
+		Name   string            `json:"Name"`
+		Labels map[string]string `json:"Labels"`
+		Meta   map[string]string `json:"Meta"`
+	} `json:"Entities"`
+	TakenAt time.Time `json:"TakenAt"`
+}
+
+// MarshalJSON encodes s in the current wire format. Entities is always an array.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	w := wireSnapshot{SchemaVersion: SchemaVersion, TakenAt: s.TakenAt, Entities: s.Entities}
+	if w.Entities == nil {
+		w.Entities = []LabeledEntity{}
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON decodes any wire format up to SchemaVersion. A document
+// without schemaVersion is read as version 0.
+func (s *Snapshot) UnmarshalJSON(b []byte) error {
+	var head struct {
+		SchemaVersion *int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return err
+	}
+
+	version := 0
+	if head.SchemaVersion != nil {
+		version = *head.SchemaVersion
+	}
+	switch version {
+	case 0:
+		var v0 snapshotV0
+		if err := json.Unmarshal(b, &v0); err != nil {
+			return err
+		}
+		*s = Snapshot{TakenAt: v0.TakenAt}
+		for _, e := range v0.Entities {
+			s.Entities = append(s.Entities, LabeledEntity(e))
+		}
+		return nil
+	case SchemaVersion:
+		var w wireSnapshot
+		if err := json.Unmarshal(b, &w); err != nil {
+			return err
+		}
+		*s = Snapshot{Entities: w.Entities, TakenAt: w.TakenAt}
+		return nil
+	}
+	return fmt.Errorf("unsupported snapshot schema version %d (newest supported is %d)", version, SchemaVersion)
+}
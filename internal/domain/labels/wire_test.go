@@ -0,0 +1,85 @@
+package labels
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_JSONRoundTrip(t *testing.T) {
+	snap := Snapshot{
+		TakenAt: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		Entities: []LabeledEntity{{
+			Kind:   KindVolume,
+			ID:     "data",
+			Name:   "data",
+			Labels: map[string]string{"bosun.backup": "daily"},
+			Meta:   map[string]string{"driver": "local"},
+		}},
+	}
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"schemaVersion":1,"takenAt":"2025-03-10T12:00:00Z","entities":[{"kind":"volume","id":"data","name":"data","labels":{"bosun.backup":"daily"},"meta":{"driver":"local"}}]}`
+	if string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+
+	var got Snapshot
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, snap) {
+		t.Errorf("round trip = %+v, want %+v", got, snap)
+	}
+
+	empty, _ := json.Marshal(Snapshot{})
+	if !strings.Contains(string(empty), `"entities":[]`) {
+		t.Errorf("empty snapshot = %s, expected an empty entities array", empty)
+	}
+}
+
+func TestSnapshot_UnmarshalVersion0(t *testing.T) {
+	v0 := `{"Entities":[{"Kind":"container","ID":"abc","Name":"web","Labels":{"bosun.role":"web"},"Meta":null}],"TakenAt":"2025-03-10T12:00:00Z"}`
+
+	var got Snapshot
+	if err := json.Unmarshal([]byte(v0), &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := Snapshot{
+		TakenAt:  time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		Entities: []LabeledEntity{{Kind: KindContainer, ID: "abc", Name: "web", Labels: map[string]string{"bosun.role": "web"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal(v0) = %+v, want %+v", got, want)
+	}
+}
+
+func TestSnapshot_UnmarshalFutureVersion(t *testing.T) {
+	var got Snapshot
+	err := json.Unmarshal([]byte(`{"schemaVersion":99,"entities":[]}`), &got)
+	if err == nil || !strings.Contains(err.Error(), "unsupported snapshot schema version 99") {
+		t.Errorf("Unmarshal(v99) error = %v", err)
+	}
+}
+
+func TestSnapshotJSONSchema_Published(t *testing.T) {
+	got, err := SnapshotJSONSchema()
+	if err != nil {
+		t.Fatalf("SnapshotJSONSchema failed: %v", err)
+	}
+	published, err := os.ReadFile(fmt.Sprintf("../../../docs/schema/snapshot.v%d.schema.json", SchemaVersion))
+	if err != nil {
+		t.Fatalf("failed to read published schema: %v", err)
+	}
+	if !bytes.Equal(got, published) {
+		t.Error("docs/schema/snapshot.v1.schema.json is out of date, run go generate ./internal/domain/labels")
+	}
+}
@@ -0,0 +1,266 @@
+// Package lifecycle orders the containers of a label selection for starting
+// and stopping. Dependencies come from the bosun.depends-on and bosun.order
+// labels and form a DAG; containers start in dependency order and stop in
+// reverse.
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+const (
+	// LabelDependsOn lists, comma separated, the containers a container needs
+	// running first: compose services of its own project or container names.
+	LabelDependsOn = dlabels.DefaultLabelPrefix + "depends-on"
+	// LabelOrder is an integer; a container starts after every selected
+	// container with a lower order. Containers without it are ordered only by
+	// their dependencies.
+	LabelOrder = dlabels.DefaultLabelPrefix + "order"
+	// LabelStopTimeout is how long to wait for a container to exit before it
+	// is killed, as a duration ("30s") or whole seconds ("30").
+	LabelStopTimeout = dlabels.DefaultLabelPrefix + "stop-timeout"
+)
+
+// Unit is a container to start or stop.
+type Unit struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	// StopTimeout is set from LabelStopTimeout; nil keeps the container's own.
+	StopTimeout *time.Duration `json:"stopTimeout,omitempty"`
+}
+
+// Order is the selected containers in batches: every container depends only
+// on containers of earlier batches. Containers within a batch are sorted by name.
+type Order struct {
+	Batches [][]Unit `json:"batches"`
+	// Outside lists the dependencies on containers that are not selected and
+	// therefore not ordered, e.g. "web depends on db, which is not selected".
+	Outside []string `json:"outside,omitempty"`
+}
+
+// Start returns the containers in start order.
+func (o Order) Start() []Unit {
+	var out []Unit
+	for _, b := range o.Batches {
+		out = append(out, b...)
+	}
+	return out
+}
+
+// Stop returns the containers in stop order: later batches first.
+func (o Order) Stop() []Unit {
+	var out []Unit
+	for _, b := range slices.Backward(o.Batches) {
+		out = append(out, b...)
+	}
+	return out
+}
+
+// Len returns the number of containers.
+func (o Order) Len() int {
+	n := 0
+	for _, b := range o.Batches {
+		n += len(b)
+	}
+	return n
+}
+
+// edge records why one container must start after another.
+type edge struct {
+	to     int
+	reason string
+}
+
+// node is a selected container while resolving.
+type node struct {
+	unit  Unit
+	order *int
+	deps  []edge
+}
+
+// Resolve orders the selected containers. all is every known container, so
+// that dependencies on containers outside the selection are recognized rather
+// than reported as unknown. It fails with every invalid label value, unknown
+// dependency and, once those are fixed, the first dependency cycle found.
+func Resolve(selected, all []dlabels.LabeledEntity) (Order, error) {
+	selected = slices.Clone(selected)
+	slices.SortFunc(selected, func(a, b dlabels.LabeledEntity) int { return strings.Compare(a.Name, b.Name) })
+	index := make(map[string]int, len(selected))
+	nodes := make([]*node, len(selected))
+	var errs []error
+	for i, e := range selected {
+		index[e.ID] = i
+		n, err := newNode(e)
+		errs = append(errs, err...)
+		nodes[i] = n
+	}
+
+	var out Order
+	for i, e := range selected {
+		for _, ref := range dlabels.SplitList(e.Labels[LabelDependsOn]) {
+			targets := resolveRef(e, ref, all)
+			if len(targets) == 0 {
+				errs = append(errs, fmt.Errorf("container %s: %s: no container or service %q", e.Name, LabelDependsOn, ref))
+				continue
+			}
+			for _, t := range targets {
+				j, ok := index[t.ID]
+				switch {
+				case !ok:
+					out.Outside = append(out.Outside, fmt.Sprintf("%s depends on %s, which is not selected", e.Name, t.Name))
+				case j == i:
+					errs = append(errs, fmt.Errorf("container %s: %s: depends on itself", e.Name, LabelDependsOn))
+				default:
+					nodes[i].deps = append(nodes[i].deps, edge{to: j, reason: fmt.Sprintf("%s=%s", LabelDependsOn, ref)})
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Order{}, errors.Join(errs...)
+	}
+
+	for i, n := range nodes {
+		for j, m := range nodes {
+			if n.order != nil && m.order != nil && *m.order < *n.order {
+				nodes[i].deps = append(nodes[i].deps, edge{to: j, reason: fmt.Sprintf("%s %d > %d", LabelOrder, *n.order, *m.order)})
+			}
+		}
+	}
+
+	batches, err := levels(nodes)
+	if err != nil {
+		return Order{}, err
+	}
+	out.Batches = batches
+	slices.Sort(out.Outside)
+	out.Outside = slices.Compact(out.Outside)
+	return out, nil
+}
+
+// newNode reads the unit of e and its order, reporting invalid label values.
+func newNode(e dlabels.LabeledEntity) (*node, []error) {
+	unit, err := UnitOf(e)
+	n := &node{unit: unit}
+	var errs []error
+	if v, ok := e.Labels[LabelOrder]; ok {
+		o, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %s: %q is not an integer", e.Name, LabelOrder, v))
+		} else {
+			n.order = &o
+		}
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return n, errs
+}
+
+// UnitOf returns the unit of container e, failing on an invalid LabelStopTimeout.
+func UnitOf(e dlabels.LabeledEntity) (Unit, error) {
+	u := Unit{ID: e.ID, Name: e.Name, Running: e.Meta[dlabels.MetaState] == "running"}
+	if v, ok := e.Labels[LabelStopTimeout]; ok {
+		d, err := ParseStopTimeout(v)
+		if err != nil {
+			return u, fmt.Errorf("container %s: %s: %w", e.Name, LabelStopTimeout, err)
+		}
+		u.StopTimeout = &d
+	}
+	return u, nil
+}
+
+// ParseStopTimeout parses a LabelStopTimeout value: a duration or whole seconds.
+func ParseStopTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		secs, serr := strconv.Atoi(s)
+		if serr != nil {
+			return 0, fmt.Errorf("%q is not a duration or a number of seconds", s)
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+	return d, nil
+}
+
+// resolveRef returns the containers ref names from e: the replicas of the
+// compose service ref in e's project, or else the containers named ref.
+func resolveRef(e dlabels.LabeledEntity, ref string, all []dlabels.LabeledEntity) []dlabels.LabeledEntity {
+	var out []dlabels.LabeledEntity
+	if project := e.Meta[dlabels.MetaComposeProject]; project != "" {
+		for _, c := range all {
+			if c.Meta[dlabels.MetaComposeProject] == project && c.Meta[dlabels.MetaComposeService] == ref {
+				out = append(out, c)
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	for _, c := range all {
+		if c.Name == ref {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// levels sorts the nodes topologically into batches, or reports a cycle.
+func levels(nodes []*node) ([][]Unit, error) {
+	done := make([]bool, len(nodes))
+	var batches [][]Unit
+	for remaining := len(nodes); remaining > 0; {
+		var ready []int
+		for i, n := range nodes {
+			if !done[i] && !slices.ContainsFunc(n.deps, func(d edge) bool { return !done[d.to] }) {
+				ready = append(ready, i)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, cycleError(nodes, done)
+		}
+		batch := make([]Unit, 0, len(ready))
+		for _, i := range ready {
+			done[i] = true
+			batch = append(batch, nodes[i].unit)
+		}
+		batches = append(batches, batch)
+		remaining -= len(ready)
+	}
+	return batches, nil
+}
+
+// cycleError follows unresolved dependencies from the first unresolved node
+// until one repeats and describes that cycle.
+func cycleError(nodes []*node, done []bool) error {
+	start := slices.Index(done, false)
+	pos := map[int]int{}
+	var path []edge
+	for i := start; ; {
+		if p, ok := pos[i]; ok {
+			path = path[p:]
+			break
+		}
+		pos[i] = len(path)
+		d := nodes[i].deps[slices.IndexFunc(nodes[i].deps, func(d edge) bool { return !done[d.to] })]
+		path = append(path, edge{to: d.to, reason: fmt.Sprintf("%s needs %s (%s)", nodes[i].unit.Name, nodes[d.to].unit.Name, d.reason)})
+		i = d.to
+	}
+	steps := make([]string, len(path))
+	for k, e := range path {
+		steps[k] = e.reason
+	}
+	return fmt.Errorf("dependency cycle: %s", strings.Join(steps, ", "))
+}
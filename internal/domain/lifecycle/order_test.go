@@ -0,0 +1,241 @@
+package lifecycle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// ctr returns a running container of compose project "shop" with the given
+// service and labels as key=value pairs
+func ctr(name, service string, labels ...string) dlabels.LabeledEntity {
+	e := dlabels.LabeledEntity{
+		Kind:   dlabels.KindContainer,
+		ID:     "id-" + name,
+		Name:   name,
+		Labels: map[string]string{},
+		Meta:   map[string]string{dlabels.MetaState: "running"},
+	}
+	if service != "" {
+		e.Meta[dlabels.MetaComposeProject] = "shop"
+		e.Meta[dlabels.MetaComposeService] = service
+	}
+	for _, kv := range labels {
+		k, v, _ := strings.Cut(kv, "=")
+		e.Labels[k] = v
+	}
+	return e
+}
+
+func batchNames(o Order) [][]string {
+	var out [][]string
+	for _, b := range o.Batches {
+		var names []string
+		for _, u := range b {
+			names = append(names, u.Name)
+		}
+		out = append(out, names)
+	}
+	return out
+}
+
+func unitNames(units []Unit) []string {
+	var out []string
+	for _, u := range units {
+		out = append(out, u.Name)
+	}
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected []dlabels.LabeledEntity
+		others   []dlabels.LabeledEntity // known but not selected
+		want     [][]string
+		outside  []string
+	}{
+		{
+			name:     "no dependencies",
+			selected: []dlabels.LabeledEntity{ctr("b", ""), ctr("a", "")},
+			want:     [][]string{{"a", "b"}},
+		},
+		{
+			name: "compose services with replicas",
+			selected: []dlabels.LabeledEntity{
+				ctr("shop-web-1", "web", "bosun.depends-on=api"),
+				ctr("shop-api-1", "api", "bosun.depends-on=db, cache"),
+				ctr("shop-api-2", "api", "bosun.depends-on=db,cache"),
+				ctr("shop-db-1", "db"),
+				ctr("shop-cache-1", "cache"),
+			},
+			want: [][]string{{"shop-cache-1", "shop-db-1"}, {"shop-api-1", "shop-api-2"}, {"shop-web-1"}},
+		},
+		{
+			name: "container names",
+			selected: []dlabels.LabeledEntity{
+				ctr("app", "", "bosun.depends-on=postgres"),
+				ctr("postgres", ""),
+			},
+			want: [][]string{{"postgres"}, {"app"}},
+		},
+		{
+			name: "order tiers",
+			selected: []dlabels.LabeledEntity{
+				ctr("proxy", "", "bosun.order=10"),
+				ctr("app", "", "bosun.order=5"),
+				ctr("db", "", "bosun.order=1"),
+				ctr("metrics", ""),
+			},
+			want: [][]string{{"db", "metrics"}, {"app"}, {"proxy"}},
+		},
+		{
+			name: "order and dependencies combined",
+			selected: []dlabels.LabeledEntity{
+				ctr("web", "", "bosun.order=2"),
+				ctr("migrate", "", "bosun.depends-on=db"),
+				ctr("db", "", "bosun.order=1"),
+				ctr("api", "", "bosun.order=2", "bosun.depends-on=migrate"),
+			},
+			want: [][]string{{"db"}, {"migrate", "web"}, {"api"}},
+		},
+		{
+			name:     "dependency outside the selection",
+			selected: []dlabels.LabeledEntity{ctr("shop-web-1", "web", "bosun.depends-on=db")},
+			others:   []dlabels.LabeledEntity{ctr("shop-db-1", "db")},
+			want:     [][]string{{"shop-web-1"}},
+			outside:  []string{"shop-web-1 depends on shop-db-1, which is not selected"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := append(append([]dlabels.LabeledEntity{}, tt.selected...), tt.others...)
+			got, err := Resolve(tt.selected, all)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := batchNames(got); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("batches %v, want %v", names, tt.want)
+			}
+			if !reflect.DeepEqual(got.Outside, tt.outside) {
+				t.Errorf("outside %q, want %q", got.Outside, tt.outside)
+			}
+		})
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected []dlabels.LabeledEntity
+		want     []string
+	}{
+		{
+			name:     "unknown dependency",
+			selected: []dlabels.LabeledEntity{ctr("web", "", "bosun.depends-on=dbb")},
+			want:     []string{`container web: bosun.depends-on: no container or service "dbb"`},
+		},
+		{
+			name: "invalid values are all reported",
+			selected: []dlabels.LabeledEntity{
+				ctr("a", "", "bosun.order=first"),
+				ctr("b", "", "bosun.stop-timeout=soon"),
+				ctr("c", "", "bosun.stop-timeout=-5s"),
+			},
+			want: []string{
+				`container a: bosun.order: "first" is not an integer`,
+				`container b: bosun.stop-timeout: "soon" is not a duration or a number of seconds`,
+				`container c: bosun.stop-timeout: "-5s" is negative`,
+			},
+		},
+		{
+			name:     "self dependency",
+			selected: []dlabels.LabeledEntity{ctr("web", "", "bosun.depends-on=web")},
+			want:     []string{"container web: bosun.depends-on: depends on itself"},
+		},
+		{
+			name: "cycle",
+			selected: []dlabels.LabeledEntity{
+				ctr("api", "", "bosun.depends-on=db"),
+				ctr("db", "", "bosun.depends-on=web"),
+				ctr("web", "", "bosun.depends-on=api"),
+				ctr("zz", "", "bosun.depends-on=api"),
+			},
+			want: []string{"dependency cycle: api needs db (bosun.depends-on=db), db needs web (bosun.depends-on=web), web needs api (bosun.depends-on=api)"},
+		},
+		{
+			name: "order contradicting a dependency",
+			selected: []dlabels.LabeledEntity{
+				ctr("api", "", "bosun.order=1", "bosun.depends-on=db"),
+				ctr("db", "", "bosun.order=2"),
+			},
+			want: []string{"dependency cycle: api needs db (bosun.depends-on=db), db needs api (bosun.order 2 > 1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(tt.selected, tt.selected)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", err, strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestOrder_StartStop(t *testing.T) {
+	stopped := ctr("db", "")
+	stopped.Meta[dlabels.MetaState] = "exited"
+	all := []dlabels.LabeledEntity{
+		ctr("web", "", "bosun.depends-on=api", "bosun.stop-timeout=30"),
+		ctr("api", "", "bosun.depends-on=db", "bosun.stop-timeout=1m30s"),
+		stopped,
+	}
+	order, err := Resolve(all, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := unitNames(order.Start()), []string{"db", "api", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("start order %v, want %v", got, want)
+	}
+	stop := order.Stop()
+	if got, want := unitNames(stop), []string{"web", "api", "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop order %v, want %v", got, want)
+	}
+	if order.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", order.Len())
+	}
+	if *stop[0].StopTimeout != 30*time.Second || *stop[1].StopTimeout != 90*time.Second || stop[2].StopTimeout != nil {
+		t.Errorf("unexpected stop timeouts: %v %v %v", stop[0].StopTimeout, stop[1].StopTimeout, stop[2].StopTimeout)
+	}
+	if !stop[0].Running || stop[2].Running {
+		t.Errorf("unexpected running states: %+v", stop)
+	}
+}
+
+func TestParseStopTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30", 30 * time.Second, false},
+		{"0", 0, false},
+		{"2m", 2 * time.Minute, false},
+		{" 1500ms ", 1500 * time.Millisecond, false},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStopTimeout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStopTimeout(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package recreate edits the labels of existing containers. Docker labels are
+// immutable, so a container is replaced by a copy with the same configuration
+// and the edited labels; this package describes the edit and the differences
+// between a container and its replacement.
+package recreate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Edit sets and removes labels.
+type Edit struct {
+	Set   map[string]string `json:"set,omitempty"`
+	Unset []string          `json:"unset,omitempty"`
+}
+
+// ParseSet parses key=value arguments into an Edit setting them. Values may be
+// empty and may contain "=".
+func ParseSet(args []string) (Edit, error) {
+	e := Edit{Set: make(map[string]string, len(args))}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return Edit{}, fmt.Errorf("invalid label %q (want key=value)", arg)
+		}
+		if err := checkKey(key); err != nil {
+			return Edit{}, err
+		}
+		if _, dup := e.Set[key]; dup {
+			return Edit{}, fmt.Errorf("label %s given more than once", key)
+		}
+		e.Set[key] = value
+	}
+	return e, nil
+}
+
+// ParseUnset parses label keys into an Edit removing them.
+func ParseUnset(args []string) (Edit, error) {
+	var e Edit
+	for _, key := range args {
+		if strings.Contains(key, "=") {
+			return Edit{}, fmt.Errorf("invalid label key %q (unset takes keys, not key=value)", key)
+		}
+		if err := checkKey(key); err != nil {
+			return Edit{}, err
+		}
+		if !slices.Contains(e.Unset, key) {
+			e.Unset = append(e.Unset, key)
+		}
+	}
+	return e, nil
+}
+
+func checkKey(key string) error {
+	if key == "" || strings.ContainsFunc(key, func(r rune) bool { return r <= ' ' }) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+// Empty reports whether the edit changes nothing.
+func (e Edit) Empty() bool {
+	return len(e.Set) == 0 && len(e.Unset) == 0
+}
+
+// Apply returns a copy of labels with the edit applied.
+func (e Edit) Apply(labels map[string]string) map[string]string {
+	out := maps.Clone(labels)
+	if out == nil {
+		out = make(map[string]string, len(e.Set))
+	}
+	maps.Copy(out, e.Set)
+	for _, key := range e.Unset {
+		delete(out, key)
+	}
+	return out
+}
+
+// FieldChange is a difference between the configuration of a container and
+// its replacement. Old and New are JSON values; Old is empty for an added
+// field and New for a removed one.
+type FieldChange struct {
+	Path string `json:"path"` // e.g. Config.Labels["bosun.role"]
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+func (c FieldChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff compares the JSON encodings of old and new field by field and returns
+// the changed leaves sorted by path. Null and missing fields are equal.
+func Diff(old, new any) ([]FieldChange, error) {
+	before, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+	var out []FieldChange
+	for path, o := range before {
+		if n := after[path]; n != o {
+			out = append(out, FieldChange{Path: path, Old: o, New: n})
+		}
+	}
+	for path, n := range after {
+		if _, ok := before[path]; !ok {
+			out = append(out, FieldChange{Path: path, New: n})
+		}
+	}
+	slices.SortFunc(out, func(a, b FieldChange) int { return strings.Compare(a.Path, b.Path) })
+	return out, nil
+}
+
+// flatten maps the path of every non-null leaf of the JSON encoding of v to
+// its JSON value. Empty objects and arrays are leaves.
+func flatten(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	walk(tree, "", out)
+	return out, nil
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func walk(v any, path string, out map[string]string) {
+	switch v := v.(type) {
+	case nil:
+	case map[string]any:
+		if len(v) == 0 {
+			out[path] = "{}"
+		}
+		for key, child := range v {
+			switch {
+			case identifier.MatchString(key) && path == "":
+				walk(child, key, out)
+			case identifier.MatchString(key):
+				walk(child, path+"."+key, out)
+			default:
+				walk(child, path+"["+strconv.Quote(key)+"]", out)
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			out[path] = "[]"
+		}
+		for i, child := range v {
+			walk(child, fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	default:
+		data, _ := json.Marshal(v)
+		out[path] = string(data)
+	}
+}
+
+// Result describes the recreation of a container.
+type Result struct {
+	Container string `json:"container"`
+	OldID     string `json:"oldId"`
+	// NewID is the ID of the replacement; empty for a dry run or when the
+	// labels were already as edited and nothing was recreated.
+	NewID string `json:"newId,omitempty"`
+	// Changes between the container and its replacement: the edited labels
+	// and any adjustment the replacement needs.
+	Changes []FieldChange `json:"changes"`
+	// Notes explain adjustments and anything left behind, e.g. an old
+	// container that could not be removed.
+	Notes []string `json:"notes,omitempty"`
+}
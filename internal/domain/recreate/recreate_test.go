@@ -0,0 +1,98 @@
+package recreate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	e, err := ParseSet([]string{"bosun.role=api", "bosun.note=a=b", "bosun.empty="})
+	if err != nil {
+		t.Fatalf("ParseSet failed: %v", err)
+	}
+	want := map[string]string{"bosun.role": "api", "bosun.note": "a=b", "bosun.empty": ""}
+	if !reflect.DeepEqual(e.Set, want) {
+		t.Errorf("Set = %v, want %v", e.Set, want)
+	}
+
+	for _, args := range [][]string{{"bosun.role"}, {"=api"}, {"bad key=1"}, {"a=1", "a=2"}} {
+		if _, err := ParseSet(args); err == nil {
+			t.Errorf("ParseSet(%q) succeeded", args)
+		}
+	}
+}
+
+func TestParseUnset(t *testing.T) {
+	e, err := ParseUnset([]string{"bosun.role", "bosun.tier", "bosun.role"})
+	if err != nil {
+		t.Fatalf("ParseUnset failed: %v", err)
+	}
+	if want := []string{"bosun.role", "bosun.tier"}; !reflect.DeepEqual(e.Unset, want) {
+		t.Errorf("Unset = %v, want %v", e.Unset, want)
+	}
+	for _, args := range [][]string{{"bosun.role=api"}, {""}} {
+		if _, err := ParseUnset(args); err == nil {
+			t.Errorf("ParseUnset(%q) succeeded", args)
+		}
+	}
+}
+
+func TestEdit_Apply(t *testing.T) {
+	labels := map[string]string{"bosun.role": "api", "bosun.tier": "back"}
+	e := Edit{Set: map[string]string{"bosun.role": "web"}, Unset: []string{"bosun.tier", "missing"}}
+	got := e.Apply(labels)
+	if want := map[string]string{"bosun.role": "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply = %v, want %v", got, want)
+	}
+	if labels["bosun.role"] != "api" || len(labels) != 2 {
+		t.Errorf("Apply modified its input: %v", labels)
+	}
+	if got := (Edit{Set: map[string]string{"a": "1"}}).Apply(nil); got["a"] != "1" {
+		t.Errorf("Apply(nil) = %v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type config struct {
+		Image    string
+		Hostname string `json:",omitempty"`
+		Labels   map[string]string
+		Cmd      []string
+		Env      []string
+	}
+	old := config{
+		Image:    "nginx",
+		Hostname: "abc",
+		Labels:   map[string]string{"bosun.role": "api", "bosun.tier": "back", "keep": "1"},
+		Cmd:      []string{"serve"},
+	}
+	new := config{
+		Image:  "nginx",
+		Labels: map[string]string{"bosun.role": "web", "keep": "1", "bosun.new": ""},
+		Cmd:    []string{"serve", "-v"},
+		Env:    []string{},
+	}
+	got, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	var lines []string
+	for _, c := range got {
+		lines = append(lines, c.String())
+	}
+	want := []string{
+		`+ Cmd[1]: "-v"`,
+		`+ Env: []`,
+		`- Hostname: "abc"`,
+		`+ Labels["bosun.new"]: ""`,
+		`~ Labels["bosun.role"]: "api" -> "web"`,
+		`- Labels["bosun.tier"]: "back"`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Diff =\n%v\nwant\n%v", lines, want)
+	}
+
+	if got, _ := Diff(old, old); len(got) != 0 {
+		t.Errorf("Diff of equal values = %v", got)
+	}
+}
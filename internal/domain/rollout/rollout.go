@@ -0,0 +1,96 @@
+// Package rollout plans rolling restarts: the running containers of a
+// selection are restarted in batches of at most max-unavailable containers,
+// and each batch must become ready before the next one starts.
+package rollout
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+)
+
+// MaxUnavailable bounds how many containers are restarted at once: a count,
+// or a percentage of the running containers.
+type MaxUnavailable struct {
+	N       int
+	Percent bool
+}
+
+// ParseMaxUnavailable parses a positive count ("2") or percentage ("25%").
+func ParseMaxUnavailable(s string) (MaxUnavailable, error) {
+	num, percent := strings.CutSuffix(strings.TrimSpace(s), "%")
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 || (percent && n > 100) {
+		return MaxUnavailable{}, fmt.Errorf("invalid max unavailable %q (want a positive count or a percentage, e.g. 1 or 25%%)", s)
+	}
+	return MaxUnavailable{N: n, Percent: percent}, nil
+}
+
+func (m MaxUnavailable) String() string {
+	if m.Percent {
+		return strconv.Itoa(m.N) + "%"
+	}
+	return strconv.Itoa(m.N)
+}
+
+// Of returns the batch size for total containers. A percentage rounds down
+// but is at least one.
+func (m MaxUnavailable) Of(total int) int {
+	n := m.N
+	if m.Percent {
+		n = total * m.N / 100
+	}
+	return max(n, 1)
+}
+
+// Batches splits units into consecutive batches of at most size.
+func Batches(units []dlifecycle.Unit, size int) [][]dlifecycle.Unit {
+	size = max(size, 1)
+	var out [][]dlifecycle.Unit
+	for len(units) > 0 {
+		n := min(size, len(units))
+		out = append(out, units[:n:n])
+		units = units[n:]
+	}
+	return out
+}
+
+// Status is the observed state of a container: its Docker state, e.g.
+// "running" or "exited", and its health status, empty without a healthcheck.
+type Status struct {
+	State    string `json:"state"`
+	Health   string `json:"health,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+}
+
+func (s Status) String() string {
+	if s.Health == "" {
+		return s.State
+	}
+	return s.State + ", " + s.Health
+}
+
+// Check reports whether a restarted container is ready: healthy, or running
+// when it has no healthcheck. It returns an error when the container can no
+// longer become ready because it exited or its healthcheck failed.
+func Check(s Status) (ready bool, err error) {
+	switch s.State {
+	case "running":
+	case "created", "restarting":
+		return false, nil
+	case "exited", "dead":
+		return false, fmt.Errorf("%s with code %d", s.State, s.ExitCode)
+	default:
+		return false, fmt.Errorf("unexpected state %s", s.State)
+	}
+	switch s.Health {
+	case "", "none", "healthy":
+		return true, nil
+	case "unhealthy":
+		return false, errors.New("unhealthy")
+	}
+	return false, nil
+}
@@ -0,0 +1,98 @@
+package rollout
+
+import (
+	"reflect"
+	"testing"
+
+	dlifecycle "github.com/simone-viozzi/bosun/internal/domain/lifecycle"
+)
+
+func TestParseMaxUnavailable(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    MaxUnavailable
+		wantErr bool
+	}{
+		{"1", MaxUnavailable{N: 1}, false},
+		{" 3 ", MaxUnavailable{N: 3}, false},
+		{"25%", MaxUnavailable{N: 25, Percent: true}, false},
+		{"100%", MaxUnavailable{N: 100, Percent: true}, false},
+		{"0", MaxUnavailable{}, true},
+		{"-1", MaxUnavailable{}, true},
+		{"101%", MaxUnavailable{}, true},
+		{"half", MaxUnavailable{}, true},
+		{"", MaxUnavailable{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMaxUnavailable(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMaxUnavailable(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMaxUnavailable_Of(t *testing.T) {
+	tests := []struct {
+		m     MaxUnavailable
+		total int
+		want  int
+	}{
+		{MaxUnavailable{N: 2}, 5, 2},
+		{MaxUnavailable{N: 25, Percent: true}, 8, 2},
+		{MaxUnavailable{N: 25, Percent: true}, 3, 1}, // rounds down to 0, at least 1
+		{MaxUnavailable{N: 100, Percent: true}, 4, 4},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Of(tt.total); got != tt.want {
+			t.Errorf("%v.Of(%d) = %d, want %d", tt.m, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestBatches(t *testing.T) {
+	var units []dlifecycle.Unit
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		units = append(units, dlifecycle.Unit{Name: name})
+	}
+	var got [][]string
+	for _, b := range Batches(units, 2) {
+		var names []string
+		for _, u := range b {
+			names = append(names, u.Name)
+		}
+		got = append(got, names)
+	}
+	if want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Batches = %v, want %v", got, want)
+	}
+	if got := Batches(nil, 2); got != nil {
+		t.Errorf("Batches(nil) = %v, want nil", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		status    Status
+		wantReady bool
+		wantErr   string
+	}{
+		{Status{State: "running"}, true, ""},
+		{Status{State: "running", Health: "healthy"}, true, ""},
+		{Status{State: "running", Health: "starting"}, false, ""},
+		{Status{State: "running", Health: "unhealthy"}, false, "unhealthy"},
+		{Status{State: "restarting"}, false, ""},
+		{Status{State: "created"}, false, ""},
+		{Status{State: "exited", ExitCode: 137}, false, "exited with code 137"},
+		{Status{State: "paused"}, false, "unexpected state paused"},
+	}
+	for _, tt := range tests {
+		ready, err := Check(tt.status)
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if ready != tt.wantReady || gotErr != tt.wantErr {
+			t.Errorf("Check(%v) = %v, %q; want %v, %q", tt.status, ready, gotErr, tt.wantReady, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package schema declares the expected Bosun labels and validates snapshots against them.
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// Type is the value type of a label.
+type Type string
+
+const (
+	TypeString   Type = "string"
+	TypeInt      Type = "int"
+	TypeBool     Type = "bool"
+	TypeDuration Type = "duration"
+)
+
+// Severity is how serious a violation is. Only errors fail a lint run.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityIgnore  Severity = "ignore"
+)
+
+// KeySpec declares a single label key.
+type KeySpec struct {
+	Key         string
+	Type        Type           // defaults to TypeString
+	Allowed     []string       // optional set of allowed values
+	Required    bool           // must be present on every entity of Kinds
+	Kinds       []dlabels.Kind // entity kinds the key applies to; empty means all
+	Description string
+}
+
+// AppliesTo reports whether the key may be set on entities of kind k.
+func (k KeySpec) AppliesTo(kind dlabels.Kind) bool {
+	return len(k.Kinds) == 0 || slices.Contains(k.Kinds, kind)
+}
+
+// Schema is the set of declared label keys under a common prefix.
+type Schema struct {
+	Prefix      string   // keys under this prefix must be declared; defaults to DefaultLabelPrefix
+	UnknownKeys Severity // severity of undeclared keys under Prefix; defaults to SeverityError
+	Keys        []KeySpec
+}
+
+// Lookup returns the spec for key, if declared.
+func (s Schema) Lookup(key string) (KeySpec, bool) {
+	for _, k := range s.Keys {
+		if k.Key == key {
+			return k, true
+		}
+	}
+	return KeySpec{}, false
+}
+
+// Prefixes returns the label prefixes a snapshot must be taken with to see
+// every key the schema checks: the schema prefix plus any declared key outside it.
+func (s Schema) Prefixes() []string {
+	out := []string{s.Prefix}
+	for _, k := range s.Keys {
+		if !strings.HasPrefix(k.Key, s.Prefix) {
+			out = append(out, k.Key)
+		}
+	}
+	return out
+}
+
+// Normalize fills in defaults and checks the schema is consistent: known types,
+// kinds and severities, no duplicate keys, and allowed values of the right type.
+func (s Schema) Normalize() (Schema, error) {
+	if s.Prefix == "" {
+		s.Prefix = dlabels.DefaultLabelPrefix
+	}
+	switch s.UnknownKeys {
+	case "":
+		s.UnknownKeys = SeverityError
+	case SeverityError, SeverityWarning, SeverityIgnore:
+	default:
+		return s, fmt.Errorf("unknown severity %q for unknown keys", s.UnknownKeys)
+	}
+
+	seen := make(map[string]bool, len(s.Keys))
+	keys := make([]KeySpec, 0, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Key == "" {
+			return s, fmt.Errorf("key spec without a key")
+		}
+		if seen[k.Key] {
+			return s, fmt.Errorf("key %s declared twice", k.Key)
+		}
+		seen[k.Key] = true
+
+		if k.Type == "" {
+			k.Type = TypeString
+		}
+		if !slices.Contains([]Type{TypeString, TypeInt, TypeBool, TypeDuration}, k.Type) {
+			return s, fmt.Errorf("key %s: unknown type %q", k.Key, k.Type)
+		}
+		for _, kind := range k.Kinds {
+			if !kind.Valid() {
+				return s, fmt.Errorf("key %s: unknown kind %q", k.Key, kind)
+			}
+		}
+		for _, v := range k.Allowed {
+			if err := checkType(k.Type, v); err != nil {
+				return s, fmt.Errorf("key %s: allowed value %q: %w", k.Key, v, err)
+			}
+		}
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b KeySpec) int { return strings.Compare(a.Key, b.Key) })
+	s.Keys = keys
+	return s, nil
+}
+
+// checkType reports whether v parses as type t.
+func checkType(t Type, v string) error {
+	var err error
+	switch t {
+	case TypeInt:
+		_, err = strconv.Atoi(v)
+	case TypeBool:
+		_, err = strconv.ParseBool(v)
+	case TypeDuration:
+		_, err = time.ParseDuration(v)
+	}
+	if err != nil {
+		return fmt.Errorf("not a valid %s", t)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// Violation is a single schema problem on an entity.
+type Violation struct {
+	Kind     dlabels.Kind `json:"kind"`
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Key      string       `json:"key"`
+	Severity Severity     `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s %s: %s: %s", v.Severity, v.Kind, v.Name, v.Key, v.Message)
+}
+
+// HasErrors reports whether any violation has SeverityError.
+func HasErrors(vs []Violation) bool {
+	for _, v := range vs {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every entity of the snapshot against the schema and returns
+// the violations in snapshot order, sorted by key within an entity. The schema
+// is expected to be normalized.
+func (s Schema) Validate(snap dlabels.Snapshot) []Violation {
+	var out []Violation
+	for _, e := range snap.Entities {
+		out = append(out, s.ValidateEntity(e)...)
+	}
+	return out
+}
+
+// ValidateEntity checks a single entity against the schema.
+func (s Schema) ValidateEntity(e dlabels.LabeledEntity) []Violation {
+	var out []Violation
+	add := func(key string, sev Severity, format string, args ...any) {
+		out = append(out, Violation{
+			Kind:     e.Kind,
+			ID:       e.ID,
+			Name:     e.Name,
+			Key:      key,
+			Severity: sev,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(e.Labels)) {
+		value := e.Labels[key]
+		spec, ok := s.Lookup(key)
+		if !ok {
+			if strings.HasPrefix(key, s.Prefix) && s.UnknownKeys != SeverityIgnore {
+				if hint := s.suggest(key); hint != "" {
+					add(key, s.UnknownKeys, "unknown key (did you mean %s?)", hint)
+				} else {
+					add(key, s.UnknownKeys, "unknown key")
+				}
+			}
+			continue
+		}
+		if !spec.AppliesTo(e.Kind) {
+			add(key, SeverityError, "not allowed on %s (allowed on %s)", e.Kind, joinKinds(spec.Kinds))
+			continue
+		}
+		if err := checkType(spec.Type, value); err != nil {
+			add(key, SeverityError, "value %q is %s", value, err)
+			continue
+		}
+		if len(spec.Allowed) > 0 && !slices.Contains(spec.Allowed, value) {
+			add(key, SeverityError, "value %q not in allowed values [%s]", value, strings.Join(spec.Allowed, ", "))
+		}
+	}
+
+	for _, spec := range s.Keys {
+		if !spec.Required || !spec.AppliesTo(e.Kind) {
+			continue
+		}
+		if _, ok := e.Labels[spec.Key]; !ok {
+			add(spec.Key, SeverityError, "required key missing")
+		}
+	}
+
+	slices.SortStableFunc(out, func(a, b Violation) int { return strings.Compare(a.Key, b.Key) })
+	return out
+}
+
+// suggest returns the declared key closest to key if it is a likely typo.
+func (s Schema) suggest(key string) string {
+	best, bestDist := "", 3
+	for _, spec := range s.Keys {
+		if d := levenshtein(key, spec.Key); d < bestDist {
+			best, bestDist = spec.Key, d
+		}
+	}
+	return best
+}
+
+func joinKinds(kinds []dlabels.Kind) string {
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = string(k)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func testSchema(t *testing.T) Schema {
+	t.Helper()
+	s, err := Schema{
+		Keys: []KeySpec{
+			{Key: "bosun.backup", Allowed: []string{"daily", "weekly"}, Kinds: []dlabels.Kind{dlabels.KindVolume}},
+			{Key: "bosun.role", Required: true, Kinds: []dlabels.Kind{dlabels.KindContainer}},
+			{Key: "bosun.replicas", Type: TypeInt},
+			{Key: "bosun.stop-timeout", Type: TypeDuration},
+		},
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	s := testSchema(t)
+
+	tests := []struct {
+		name   string
+		entity dlabels.LabeledEntity
+		want   []string // "key: message prefix"
+	}{
+		{
+			name:   "valid volume",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindVolume, Labels: map[string]string{"bosun.backup": "daily"}},
+		},
+		{
+			name:   "typo gets a suggestion",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindVolume, Labels: map[string]string{"bosun.backpu": "daily"}},
+			want:   []string{"bosun.backpu: unknown key (did you mean bosun.backup?)"},
+		},
+		{
+			name:   "value not allowed",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindVolume, Labels: map[string]string{"bosun.backup": "hourly"}},
+			want:   []string{`bosun.backup: value "hourly" not in allowed values`},
+		},
+		{
+			name:   "wrong kind",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindNetwork, Labels: map[string]string{"bosun.backup": "daily"}},
+			want:   []string{"bosun.backup: not allowed on network"},
+		},
+		{
+			name:   "required missing and bad types",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindContainer, Labels: map[string]string{"bosun.replicas": "three", "bosun.stop-timeout": "10"}},
+			want: []string{
+				`bosun.replicas: value "three" is not a valid int`,
+				"bosun.role: required key missing",
+				`bosun.stop-timeout: value "10" is not a valid duration`,
+			},
+		},
+		{
+			name:   "keys outside the prefix are ignored",
+			entity: dlabels.LabeledEntity{Kind: dlabels.KindContainer, Labels: map[string]string{"bosun.role": "web", "traefik.enable": "true"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.ValidateEntity(tt.entity)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d violations %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, v := range got {
+				if s := v.Key + ": " + v.Message; !strings.HasPrefix(s, tt.want[i]) {
+					t.Errorf("violation[%d] = %q, want prefix %q", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_UnknownKeySeverity(t *testing.T) {
+	s := testSchema(t)
+	e := dlabels.LabeledEntity{Kind: dlabels.KindVolume, Labels: map[string]string{"bosun.extra": "x"}}
+
+	s.UnknownKeys = SeverityWarning
+	vs := s.Validate(dlabels.Snapshot{Entities: []dlabels.LabeledEntity{e}})
+	if len(vs) != 1 || vs[0].Severity != SeverityWarning || HasErrors(vs) {
+		t.Errorf("expected a single warning, got %v", vs)
+	}
+
+	s.UnknownKeys = SeverityIgnore
+	if vs := s.ValidateEntity(e); len(vs) != 0 {
+		t.Errorf("expected no violations, got %v", vs)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  Schema
+		wantErr string
+	}{
+		{name: "unknown type", schema: Schema{Keys: []KeySpec{{Key: "bosun.a", Type: "float"}}}, wantErr: "unknown type"},
+		{name: "unknown kind", schema: Schema{Keys: []KeySpec{{Key: "bosun.a", Kinds: []dlabels.Kind{"pod"}}}}, wantErr: "unknown kind"},
+		{name: "duplicate key", schema: Schema{Keys: []KeySpec{{Key: "bosun.a"}, {Key: "bosun.a"}}}, wantErr: "declared twice"},
+		{name: "allowed value of wrong type", schema: Schema{Keys: []KeySpec{{Key: "bosun.a", Type: TypeBool, Allowed: []string{"maybe"}}}}, wantErr: "allowed value"},
+		{name: "bad severity", schema: Schema{UnknownKeys: "fatal"}, wantErr: "unknown severity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.schema.Normalize()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Normalize() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	s, err := Schema{Keys: []KeySpec{{Key: "bosun.b"}, {Key: "bosun.a"}}}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if s.Prefix != dlabels.DefaultLabelPrefix || s.UnknownKeys != SeverityError {
+		t.Errorf("defaults not applied: %+v", s)
+	}
+	if s.Keys[0].Key != "bosun.a" || s.Keys[0].Type != TypeString {
+		t.Errorf("keys not sorted or typed: %+v", s.Keys)
+	}
+}
+
+func TestViolation_JSON(t *testing.T) {
+	v := Violation{Kind: dlabels.KindVolume, ID: "v1", Name: "data", Key: "bosun.backup", Severity: SeverityError, Message: "not allowed"}
+	got, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kind":"volume","id":"v1","name":"data","key":"bosun.backup","severity":"error","message":"not allowed"}`
+	if string(got) != want {
+		t.Errorf("JSON = %s, want %s", got, want)
+	}
+}
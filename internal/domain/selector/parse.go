@@ -0,0 +1,153 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Parse parses a comma-separated list of requirements:
+//
+//	key                  the key exists
+//	!key                 the key does not exist
+//	key=value, key==value
+//	key!=value
+//	key in (v1,v2)
+//	key notin (v1,v2)
+//
+// Whitespace around tokens is ignored. The empty string parses to the empty selector.
+func Parse(s string) (Selector, error) {
+	p := &parser{in: s}
+	var sel Selector
+	p.skipSpace()
+	if p.done() {
+		return sel, nil
+	}
+	for {
+		r, err := p.requirement()
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel = append(sel, r)
+
+		p.skipSpace()
+		if p.done() {
+			return sel, nil
+		}
+		if !p.accept(",") {
+			return nil, fmt.Errorf("invalid selector %q: expected ',' at offset %d", s, p.pos)
+		}
+	}
+}
+
+type parser struct {
+	in  string
+	pos int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.in) }
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.in[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes tok if the input continues with it.
+func (p *parser) accept(tok string) bool {
+	if strings.HasPrefix(p.in[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// isWordByte reports whether c may appear in a key or value.
+func isWordByte(c byte) bool {
+	return c == '.' || c == '-' || c == '_' || c == '/' || c == ':' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// word consumes a key or value, possibly empty.
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() && isWordByte(p.in[p.pos]) {
+		p.pos++
+	}
+	return p.in[start:p.pos]
+}
+
+func (p *parser) key() (string, error) {
+	p.skipSpace()
+	k := p.word()
+	if k == "" {
+		return "", fmt.Errorf("expected a key at offset %d", p.pos)
+	}
+	if strings.Contains(strings.TrimPrefix(k, MetaPrefix), ":") {
+		return "", fmt.Errorf("invalid key %q", k)
+	}
+	return k, nil
+}
+
+func (p *parser) requirement() (Requirement, error) {
+	p.skipSpace()
+	if p.accept("!") {
+		k, err := p.key()
+		return Requirement{Key: k, Op: DoesNotExist}, err
+	}
+
+	k, err := p.key()
+	if err != nil {
+		return Requirement{}, err
+	}
+	p.skipSpace()
+
+	switch {
+	case p.done() || strings.HasPrefix(p.in[p.pos:], ","):
+		return Requirement{Key: k, Op: Exists}, nil
+	case p.accept("!="):
+		return p.value(k, NotEquals)
+	case p.accept("=="), p.accept("="):
+		return p.value(k, Equals)
+	}
+
+	at := p.pos
+	op := Operator(p.word())
+	if op != In && op != NotIn {
+		return Requirement{}, fmt.Errorf("expected an operator after %q at offset %d", k, at)
+	}
+	values, err := p.set()
+	if err != nil {
+		return Requirement{}, err
+	}
+	return Requirement{Key: k, Op: op, Values: values}, nil
+}
+
+func (p *parser) value(k string, op Operator) (Requirement, error) {
+	p.skipSpace()
+	return Requirement{Key: k, Op: op, Values: []string{p.word()}}, nil
+}
+
+// set consumes a parenthesized, comma-separated list of at least one value.
+func (p *parser) set() ([]string, error) {
+	p.skipSpace()
+	if !p.accept("(") {
+		return nil, fmt.Errorf("expected '(' at offset %d", p.pos)
+	}
+	var values []string
+	for {
+		p.skipSpace()
+		v := p.word()
+		if v == "" {
+			return nil, fmt.Errorf("expected a value at offset %d", p.pos)
+		}
+		values = append(values, v)
+		p.skipSpace()
+		if p.accept(")") {
+			return values, nil
+		}
+		if !p.accept(",") {
+			return nil, fmt.Errorf("expected ',' or ')' at offset %d", p.pos)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package selector implements Kubernetes-style label selector expressions,
+// e.g. "bosun.role=web,bosun.env!=dev,bosun.backup in (daily,weekly),!bosun.ignore".
+package selector
+
+import (
+	"slices"
+	"strings"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// MetaPrefix marks a key that is looked up in LabeledEntity.Meta instead of
+// Labels, e.g. "meta:compose.project=shop". Label keys cannot contain ':'.
+const MetaPrefix = "meta:"
+
+// Operator is the comparison of a requirement.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a single condition on a key.
+type Requirement struct {
+	Key    string
+	Op     Operator
+	Values []string // one value for Equals and NotEquals, a set for In and NotIn
+}
+
+// Selector is the AND of its requirements. The empty selector matches everything.
+type Selector []Requirement
+
+// Empty reports whether the selector has no requirements.
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// Matches reports whether e satisfies every requirement.
+func (s Selector) Matches(e dlabels.LabeledEntity) bool {
+	for _, r := range s {
+		if !r.Matches(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether e satisfies the requirement. As in Kubernetes,
+// NotEquals and NotIn also match entities without the key.
+func (r Requirement) Matches(e dlabels.LabeledEntity) bool {
+	src, key := e.Labels, r.Key
+	if k, ok := strings.CutPrefix(key, MetaPrefix); ok {
+		src, key = e.Meta, k
+	}
+	v, ok := src[key]
+
+	switch r.Op {
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	case Equals:
+		return ok && v == r.Values[0]
+	case NotEquals:
+		return !ok || v != r.Values[0]
+	case In:
+		return ok && slices.Contains(r.Values, v)
+	case NotIn:
+		return !ok || !slices.Contains(r.Values, v)
+	}
+	return false
+}
+
+// IsMeta reports whether the requirement applies to Meta rather than Labels.
+func (r Requirement) IsMeta() bool {
+	return strings.HasPrefix(r.Key, MetaPrefix)
+}
+
+// String formats the requirement in the syntax accepted by Parse.
+func (r Requirement) String() string {
+	switch r.Op {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return r.Key + " " + string(r.Op) + " (" + strings.Join(r.Values, ",") + ")"
+	}
+	return r.Key + string(r.Op) + r.Values[0]
+}
+
+// String formats the selector in the syntax accepted by Parse.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
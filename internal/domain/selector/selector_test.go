@@ -0,0 +1,97 @@
+package selector
+
+import (
+	"reflect"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse(" bosun.role=web, bosun.env != dev,bosun.backup in (daily, weekly),bosun.tier notin (db),!bosun.ignore,bosun.enabled,a==b,meta:compose.project=shop")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := Selector{
+		{Key: "bosun.role", Op: Equals, Values: []string{"web"}},
+		{Key: "bosun.env", Op: NotEquals, Values: []string{"dev"}},
+		{Key: "bosun.backup", Op: In, Values: []string{"daily", "weekly"}},
+		{Key: "bosun.tier", Op: NotIn, Values: []string{"db"}},
+		{Key: "bosun.ignore", Op: DoesNotExist},
+		{Key: "bosun.enabled", Op: Exists},
+		{Key: "a", Op: Equals, Values: []string{"b"}},
+		{Key: "meta:compose.project", Op: Equals, Values: []string{"shop"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	// String round-trips
+	again, err := Parse(got.String())
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("Parse(%q) = %+v, %v", got.String(), again, err)
+	}
+
+	if sel, err := Parse("  "); err != nil || !sel.Empty() {
+		t.Errorf("Parse(blank) = %v, %v, want empty selector", sel, err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{
+		"bosun.a=b,",
+		",bosun.a",
+		"!",
+		"bosun.a in daily",
+		"bosun.a in ()",
+		"bosun.a in (x,",
+		"bosun.a like x",
+		"bosun.a=b c",
+		"a:b=c",
+		"bosun.a=(x)",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) expected error", in)
+		}
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	e := dlabels.LabeledEntity{
+		Kind:   dlabels.KindContainer,
+		Name:   "shop-web-1",
+		Labels: map[string]string{"bosun.role": "web", "bosun.backup": "daily"},
+		Meta:   map[string]string{"compose.project": "shop"},
+	}
+
+	tests := []struct {
+		sel  string
+		want bool
+	}{
+		{"", true},
+		{"bosun.role=web", true},
+		{"bosun.role=db", false},
+		{"bosun.env!=dev", true},
+		{"bosun.role!=web", false},
+		{"bosun.backup in (daily,weekly)", true},
+		{"bosun.backup in (weekly)", false},
+		{"bosun.env in (dev)", false},
+		{"bosun.env notin (dev)", true},
+		{"bosun.backup notin (daily)", false},
+		{"bosun.backup", true},
+		{"!bosun.backup", false},
+		{"!bosun.ignore", true},
+		{"meta:compose.project=shop", true},
+		{"compose.project=shop", false},
+		{"bosun.role=web,!bosun.ignore,meta:compose.project in (blog)", false},
+	}
+	for _, tt := range tests {
+		sel, err := Parse(tt.sel)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.sel, err)
+		}
+		if got := sel.Matches(e); got != tt.want {
+			t.Errorf("Parse(%q).Matches() = %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package volume describes portable volume archives.
+package volume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Archive layout: a tar stream whose first entry is the manifest, followed by
+// the volume contents rooted at ContentDir.
+const (
+	ManifestName = "bosun-manifest.json"
+	ContentDir   = "volume"
+)
+
+// ManifestVersion is the manifest format written by this version of Bosun.
+const ManifestVersion = 1
+
+// Volume is the recreatable definition of a Docker volume.
+type Volume struct {
+	Name    string
+	Driver  string
+	Labels  map[string]string
+	Options map[string]string // driver options
+}
+
+// Manifest describes the volume an archive was exported from.
+type Manifest struct {
+	Version    int               `json:"version"`
+	Volume     string            `json:"volume"`
+	Driver     string            `json:"driver,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+	ExportedAt time.Time         `json:"exportedAt"`
+}
+
+// NewManifest returns the manifest of v exported at the given time.
+func NewManifest(v Volume, at time.Time) Manifest {
+	return Manifest{
+		Version:    ManifestVersion,
+		Volume:     v.Name,
+		Driver:     v.Driver,
+		Labels:     v.Labels,
+		Options:    v.Options,
+		ExportedAt: at.UTC(),
+	}
+}
+
+// VolumeAs returns the definition to recreate the volume under name,
+// or under its original name if name is empty.
+func (m Manifest) VolumeAs(name string) Volume {
+	if name == "" {
+		name = m.Volume
+	}
+	return Volume{Name: name, Driver: m.Driver, Labels: m.Labels, Options: m.Options}
+}
+
+// ParseManifest decodes and checks a manifest.
+func ParseManifest(b []byte) (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	switch {
+	case m.Version < 1 || m.Version > ManifestVersion:
+		return Manifest{}, fmt.Errorf("unsupported manifest version %d", m.Version)
+	case m.Volume == "":
+		return Manifest{}, errors.New("invalid manifest: missing volume name")
+	}
+	return m, nil
+}
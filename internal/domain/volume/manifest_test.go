@@ -0,0 +1,51 @@
+package volume
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	v := Volume{Name: "db", Driver: "local", Labels: map[string]string{"bosun.backup": "daily"}}
+
+	b, err := json.Marshal(NewManifest(v, at))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	m, err := ParseManifest(b)
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if !m.ExportedAt.Equal(at) || m.ExportedAt.Location() != time.UTC {
+		t.Errorf("ExportedAt = %v, want %v in UTC", m.ExportedAt, at)
+	}
+
+	got := m.VolumeAs("")
+	if got.Name != "db" || got.Driver != "local" || got.Labels["bosun.backup"] != "daily" {
+		t.Errorf("VolumeAs(\"\") = %+v", got)
+	}
+	if got := m.VolumeAs("db-copy"); got.Name != "db-copy" || got.Labels["bosun.backup"] != "daily" {
+		t.Errorf("VolumeAs(\"db-copy\") = %+v", got)
+	}
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"not json", "tar"},
+		{"missing version", `{"volume":"db"}`},
+		{"future version", `{"version":99,"volume":"db"}`},
+		{"missing volume", `{"version":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseManifest([]byte(tt.in)); err == nil {
+				t.Errorf("Expected error for %s", tt.in)
+			}
+		})
+	}
+}
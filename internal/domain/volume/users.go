@@ -0,0 +1,28 @@
+package volume
+
+import dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+
+// User is a container that mounts a volume.
+type User struct {
+	ID      string
+	Name    string
+	Running bool
+}
+
+// UsersOf returns the containers among entities that mount the volume name,
+// according to the mounts listed in their Meta by ports.DetailExtended.
+func UsersOf(entities []dlabels.LabeledEntity, name string) []User {
+	var out []User
+	for _, e := range entities {
+		if e.Kind != dlabels.KindContainer {
+			continue
+		}
+		for _, m := range dlabels.ParseMounts(e.Meta[dlabels.MetaMounts]) {
+			if m.Volume == name {
+				out = append(out, User{ID: e.ID, Name: e.Name, Running: e.Meta[dlabels.MetaState] == "running"})
+				break
+			}
+		}
+	}
+	return out
+}
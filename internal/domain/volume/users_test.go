@@ -0,0 +1,31 @@
+package volume
+
+import (
+	"reflect"
+	"testing"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+func TestUsersOf(t *testing.T) {
+	ctr := func(name, state, mounts string) dlabels.LabeledEntity {
+		return dlabels.LabeledEntity{Kind: dlabels.KindContainer, ID: name + "-id", Name: name,
+			Meta: map[string]string{dlabels.MetaState: state, dlabels.MetaMounts: mounts}}
+	}
+	entities := []dlabels.LabeledEntity{
+		ctr("app", "running", "/srv/app:/etc/app:ro,db:/data"),
+		ctr("backup", "exited", "db:/backup/db:ro"),
+		ctr("other", "running", "db-copy:/data,/run"),
+		ctr("bind", "running", "/var/lib/db:/db"),
+		ctr("comma", "running", `/srv/a\,db:/data`), // one bind mount whose source has a comma
+		{Kind: dlabels.KindVolume, ID: "db", Name: "db", Meta: map[string]string{dlabels.MetaMounts: "db:/data"}},
+	}
+
+	want := []User{{ID: "app-id", Name: "app", Running: true}, {ID: "backup-id", Name: "backup"}}
+	if got := UsersOf(entities, "db"); !reflect.DeepEqual(got, want) {
+		t.Errorf("UsersOf(db) = %+v, want %+v", got, want)
+	}
+	if got := UsersOf(entities, "logs"); got != nil {
+		t.Errorf("UsersOf(logs) = %+v, want none", got)
+	}
+}
@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+	"io"
+	"time"
+
+	dbackup "github.com/simone-viozzi/bosun/internal/domain/backup"
+)
+
+// BackupTarget stores volume archives.
+type BackupTarget interface {
+	// Save stores the tar stream read from r as a backup of volume taken at the given time.
+	Save(ctx context.Context, volume string, at time.Time, r io.Reader) (dbackup.Backup, error)
+	// List returns the backups of volume, or of every volume if it is empty, oldest first.
+	List(ctx context.Context, volume string) ([]dbackup.Backup, error)
+}
+
+// VolumeArchiver reads the contents of a volume.
+type VolumeArchiver interface {
+	// Archive writes the contents of volume to w as a tar stream.
+	Archive(ctx context.Context, volume string, w io.Writer) error
+}
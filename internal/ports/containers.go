@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	drecreate "github.com/simone-viozzi/bosun/internal/domain/recreate"
+	drollout "github.com/simone-viozzi/bosun/internal/domain/rollout"
+)
+
+// ContainerController starts and stops containers.
+type ContainerController interface {
+	// ContainersUsingVolume returns the IDs of the running containers that mount volume.
+	ContainersUsingVolume(ctx context.Context, volume string) ([]string, error)
+	Stop(ctx context.Context, id string) error
+	// StopTimeout stops a container, killing it if it has not exited after timeout.
+	StopTimeout(ctx context.Context, id string, timeout time.Duration) error
+	Start(ctx context.Context, id string) error
+}
+
+// ContainerInspector reports the current state of containers.
+type ContainerInspector interface {
+	Status(ctx context.Context, id string) (drollout.Status, error)
+}
+
+// ContainerRecreator replaces containers with copies carrying edited labels.
+type ContainerRecreator interface {
+	// Recreate replaces the container with the given name or ID by a copy
+	// whose labels are edited by edit, restoring the original if the copy
+	// fails to start. With dryRun it only reports the changes.
+	Recreate(ctx context.Context, ref string, edit drecreate.Edit, dryRun bool) (drecreate.Result, error)
+}
@@ -2,16 +2,98 @@ package ports
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
 )
 
 type Selector struct {
 	Prefixes       []string
 	IncludeStopped bool
-	ProjectFilter  []string // optional filter by compose project
+	ProjectFilter  []string       // optional filter by compose project; matches any listed project
+	Kinds          []dlabels.Kind // optional filter by entity kind; empty means all kinds
+
+	// LabelSelector is evaluated against the labels kept by Prefixes and the
+	// entity Meta; entities that do not match are dropped. Empty matches all.
+	// CheckLabelSelector rejects label keys that Prefixes does not keep.
+	LabelSelector dselector.Selector
+
+	// InheritImageLabels merges the labels of each container's image underneath
+	// the container's own labels and records the origin of every label in Meta.
+	InheritImageLabels bool
+
+	// Detail selects how much metadata sources add to Meta.
+	Detail Detail
+}
+
+// Detail is the amount of metadata reported per entity. Each level includes
+// the previous ones.
+type Detail int
+
+const (
+	// DetailBasic reports the image and compose project and service.
+	DetailBasic Detail = iota
+	// DetailExtended adds what the list calls return: container state, status,
+	// ports, mounts, networks and creation time, volume mountpoint and scope.
+	DetailExtended
+	// DetailFull adds the health status and restart count, which take one
+	// ContainerInspect call per container.
+	DetailFull
+)
+
+var detailNames = []string{"basic", "extended", "full"}
+
+func (d Detail) String() string {
+	if d < 0 || int(d) >= len(detailNames) {
+		return fmt.Sprintf("Detail(%d)", int(d))
+	}
+	return detailNames[d]
+}
+
+// DetailNames returns the names accepted by ParseDetail, lowest level first.
+func DetailNames() []string {
+	return slices.Clone(detailNames)
+}
+
+// ParseDetail parses a detail level name. The empty string is DetailBasic.
+func ParseDetail(s string) (Detail, error) {
+	if s == "" {
+		return DetailBasic, nil
+	}
+	if i := slices.Index(detailNames, s); i >= 0 {
+		return Detail(i), nil
+	}
+	return DetailBasic, fmt.Errorf("unknown detail level %q (want one of %v)", s, detailNames)
+}
+
+// CheckLabelSelector returns an error for a LabelSelector requirement on a
+// label key that no prefix keeps. The selector only sees the kept labels, so
+// such a requirement would match no entity, or every one when negated.
+func (s Selector) CheckLabelSelector() error {
+	for _, r := range s.LabelSelector {
+		if r.IsMeta() || slices.ContainsFunc(s.Prefixes, func(p string) bool { return strings.HasPrefix(r.Key, p) }) {
+			continue
+		}
+		return fmt.Errorf("selector key %q is outside the label prefixes %q; use %s keys for metadata, e.g. %scompose.service",
+			r.Key, s.Prefixes, dselector.MetaPrefix, dselector.MetaPrefix)
+	}
+	return nil
+}
+
+// WantsKind reports whether entities of kind k are selected.
+func (s Selector) WantsKind(k dlabels.Kind) bool {
+	return len(s.Kinds) == 0 || slices.Contains(s.Kinds, k)
 }
 
 type LabelSource interface {
 	Snapshot(ctx context.Context, sel Selector) (dlabels.Snapshot, error)
 }
+
+// LabelWatcher streams incremental label changes. The returned channel first
+// carries the current entities as ChangeAdded and is closed when ctx is done.
+type LabelWatcher interface {
+	Watch(ctx context.Context, sel Selector) (<-chan dlabels.Change, error)
+}
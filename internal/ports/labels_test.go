@@ -6,6 +6,7 @@ import (
 	"time"
 
 	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+	dselector "github.com/simone-viozzi/bosun/internal/domain/selector"
 )
 
 // mockLabelSource implements LabelSource for testing
@@ -47,3 +48,51 @@ func TestInterfaces(t *testing.T) {
 		t.Errorf("Expected snapshot to have 1 entity")
 	}
 }
+
+func TestParseDetail(t *testing.T) {
+	for _, name := range append(DetailNames(), "") {
+		d, err := ParseDetail(name)
+		if err != nil {
+			t.Fatalf("ParseDetail(%q) failed: %v", name, err)
+		}
+		if name != "" && d.String() != name {
+			t.Errorf("ParseDetail(%q).String() = %q", name, d.String())
+		}
+	}
+	if d, _ := ParseDetail(""); d != DetailBasic {
+		t.Errorf("ParseDetail(\"\") = %v, want basic", d)
+	}
+	if _, err := ParseDetail("verbose"); err == nil {
+		t.Error("Expected an error for an unknown detail level")
+	}
+}
+
+func TestSelectorCheckLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		selector string
+		wantErr  bool
+	}{
+		{"kept key", []string{"bosun."}, "bosun.role=web", false},
+		{"meta key", []string{"bosun."}, "meta:compose.service=web,meta:state=running", false},
+		{"any prefix", []string{"bosun.", "com.docker."}, "com.docker.compose.service=web", false},
+		{"every label", []string{""}, "traefik.enable=true", false},
+		{"empty", []string{"bosun."}, "", false},
+		{"other key", []string{"bosun."}, "com.docker.compose.service=web", true},
+		{"negated other key", []string{"bosun."}, "bosun.role=web,!foo", true},
+		{"no prefixes", nil, "bosun.role", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls, err := dselector.Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Selector{Prefixes: tt.prefixes, LabelSelector: ls}.CheckLabelSelector()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckLabelSelector() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	dlabels "github.com/simone-viozzi/bosun/internal/domain/labels"
+)
+
+// SnapshotStore keeps a history of label snapshots.
+type SnapshotStore interface {
+	Save(ctx context.Context, snap dlabels.Snapshot) (dlabels.SnapshotInfo, error)
+	// List returns the stored snapshots, oldest first.
+	List(ctx context.Context) ([]dlabels.SnapshotInfo, error)
+	Get(ctx context.Context, id string) (dlabels.Snapshot, error)
+	// Prune deletes the snapshots expired by the policy and returns them.
+	Prune(ctx context.Context, policy dlabels.RetentionPolicy) ([]dlabels.SnapshotInfo, error)
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+	"io"
+
+	dvolume "github.com/simone-viozzi/bosun/internal/domain/volume"
+)
+
+// VolumeStore creates, removes and fills volumes.
+type VolumeStore interface {
+	VolumeArchiver
+	// Inspect returns the definition of the named volume; ok is false if it does not exist.
+	Inspect(ctx context.Context, name string) (v dvolume.Volume, ok bool, err error)
+	Create(ctx context.Context, v dvolume.Volume) error
+	Remove(ctx context.Context, name string) error
+	// InUse reports whether any container, running or not, references the volume.
+	InUse(ctx context.Context, name string) (bool, error)
+	// Extract replaces the contents of the volume with the tar stream read from r,
+	// whose entries are rooted at dvolume.ContentDir.
+	Extract(ctx context.Context, name string, r io.Reader) error
+}
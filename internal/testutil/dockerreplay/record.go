@@ -0,0 +1,112 @@
+package dockerreplay
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/docker/docker/client"
+)
+
+// Recorder is an http.RoundTripper that forwards requests to Transport and
+// records every exchange. A response is recorded once its body is read to the
+// end or closed, so streamed responses such as events keep what was read.
+type Recorder struct {
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	apiVersion   string
+	server       string
+	interactions []Interaction
+}
+
+// NewRecordingClient creates a Docker client from opts whose exchanges are
+// recorded by the returned Recorder. The client connects like one created
+// from opts alone, e.g. client.FromEnv.
+func NewRecordingClient(opts ...client.Opt) (*client.Client, *Recorder, error) {
+	base, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec := &Recorder{Transport: base.HTTPClient().Transport}
+	cli, err := client.NewClientWithOpts(append(slices.Clone(opts), client.WithHTTPClient(&http.Client{Transport: rec}))...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cli, rec, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	in := Interaction{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  canonicalQuery(req.URL),
+		Status: resp.StatusCode,
+		Header: recordHeader(resp.Header),
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte) {
+		in.setBody(body)
+		r.add(in, resp.Header.Get("Server"))
+	}}
+	return resp, nil
+}
+
+func (r *Recorder) add(in Interaction, server string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m := versionPrefix.FindStringSubmatch(in.Path); m != nil {
+		r.apiVersion = m[1]
+	}
+	if server != "" {
+		r.server = server
+	}
+	r.interactions = append(r.interactions, in)
+}
+
+// APIVersion returns the API version of the recorded request paths, or ""
+// before any versioned request was made.
+func (r *Recorder) APIVersion() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.apiVersion
+}
+
+// Fixture returns the exchanges recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Fixture{APIVersion: r.apiVersion, Server: r.server, Interactions: slices.Clone(r.interactions)}
+}
+
+// recordingBody keeps what is read from a response body and reports it once.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() { b.done(bytes.Clone(b.buf.Bytes())) })
+}
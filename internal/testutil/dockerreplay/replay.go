@@ -0,0 +1,76 @@
+package dockerreplay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/client"
+)
+
+// Replayer is an http.RoundTripper serving the responses of a fixture.
+// Requests match by method, path and query. Repeated requests get the
+// recorded responses in order, then the last one again.
+type Replayer struct {
+	mu     sync.Mutex
+	byKey  map[string][]Interaction
+	served map[string]int
+}
+
+// NewReplayer returns a Replayer for f.
+func NewReplayer(f *Fixture) *Replayer {
+	r := &Replayer{byKey: make(map[string][]Interaction), served: make(map[string]int)}
+	for _, in := range f.Interactions {
+		key := requestKey(in.Method, in.Path, in.Query)
+		r.byKey[key] = append(r.byKey[key], in)
+	}
+	return r
+}
+
+// NewReplayClient returns a Docker client that is served by the fixture,
+// pinned to its API version.
+func NewReplayClient(f *Fixture) (*client.Client, error) {
+	return client.NewClientWithOpts(
+		client.WithHost("unix:///var/run/docker.sock"),
+		client.WithVersion(f.APIVersion),
+		client.WithHTTPClient(&http.Client{Transport: NewReplayer(f)}),
+	)
+}
+
+// RoundTrip implements http.RoundTripper. Requests missing from the fixture
+// fail, which shows up as an error from the client call.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	key := requestKey(req.Method, req.URL.Path, canonicalQuery(req.URL))
+	r.mu.Lock()
+	recorded := r.byKey[key]
+	i := r.served[key]
+	r.served[key]++
+	r.mu.Unlock()
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("dockerreplay: no recorded response for %s", key)
+	}
+	in := recorded[min(i, len(recorded)-1)]
+
+	body := in.body()
+	header := make(http.Header, len(in.Header))
+	for k, v := range in.Header {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(in.Status) + " " + http.StatusText(in.Status),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package dockerreplay_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/simone-viozzi/bosun/internal/testutil/dockerreplay"
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+type listing struct {
+	containers []container.Summary
+	volumes    volume.ListResponse
+	inspect    container.InspectResponse
+}
+
+func list(ctx context.Context, t *testing.T, cli *client.Client, id string) listing {
+	t.Helper()
+	var l listing
+	var err error
+	f := filters.NewArgs(filters.Arg("label", "bosun.role"), filters.Arg("status", "running"))
+	if l.containers, err = cli.ContainerList(ctx, container.ListOptions{All: true, Filters: f}); err != nil {
+		t.Fatal(err)
+	}
+	if l.volumes, err = cli.VolumeList(ctx, volume.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if l.inspect, err = cli.ContainerInspect(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	e := fakedocker.New()
+	id, err := e.AddContainer(fakedocker.ContainerSpec{Name: "web", Running: true, Labels: map[string]string{"bosun.role": "web"}, Mounts: []string{"data:/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, rec, err := dockerreplay.NewRecordingClient(client.WithHost(e.ServeUnix(t)), client.WithVersion("1.44"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	recorded := list(ctx, t, cli, id)
+
+	// Stream a few events, then stop reading
+	evCtx, evCancel := context.WithCancel(ctx)
+	msgs, errs := cli.Events(evCtx, events.ListOptions{Filters: filters.NewArgs(filters.Arg("type", "container"))})
+	if err := e.ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		select {
+		case <-msgs:
+		case err := <-errs:
+			t.Fatal(err)
+		}
+	}
+	evCancel()
+	<-errs
+
+	if v := rec.APIVersion(); v != "1.44" {
+		t.Errorf("recorded API version %q, want 1.44", v)
+	}
+	path := filepath.Join(t.TempDir(), "1.44", "fixture.json")
+	if err := rec.Fixture().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	fixture, err := dockerreplay.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := dockerreplay.NewReplayClient(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	if got := list(ctx, t, replay, id); !reflect.DeepEqual(got, recorded) {
+		t.Errorf("replayed %+v\nrecorded %+v", got, recorded)
+	}
+
+	msgs, errs = replay.Events(ctx, events.ListOptions{Filters: filters.NewArgs(filters.Arg("type", "container"))})
+	var actions []events.Action
+	for len(actions) < 3 {
+		select {
+		case msg := <-msgs:
+			actions = append(actions, msg.Action)
+		case err := <-errs:
+			t.Fatalf("replayed events: %v after %v", err, actions)
+		}
+	}
+	if want := []events.Action{"kill", "die", "stop"}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("replayed events %v, want %v", actions, want)
+	}
+
+	_, err = replay.NetworkList(ctx, network.ListOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no recorded response for GET /v1.44/networks") {
+		t.Errorf("unrecorded request: got %v", err)
+	}
+}
@@ -0,0 +1,590 @@
+package fakedocker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// notFoundError satisfies the errdefs not-found interface, so
+// client.IsErrNotFound recognizes it.
+type notFoundError struct{ msg string }
+
+func (e notFoundError) Error() string { return e.msg }
+func (notFoundError) NotFound()       {}
+
+func notFound(format string, args ...any) error {
+	return notFoundError{fmt.Sprintf(format, args...)}
+}
+
+// conflictError is returned when the current state forbids an operation,
+// e.g. removing a running container.
+type conflictError struct{ msg string }
+
+func (e conflictError) Error() string { return e.msg }
+func (conflictError) Conflict()       {}
+
+// invalidError marks a malformed request, like Docker's invalid-parameter errors.
+type invalidError struct{ msg string }
+
+func (e invalidError) Error() string   { return e.msg }
+func (invalidError) InvalidParameter() {}
+
+// Ping implements the Docker client method.
+func (e *Engine) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{APIVersion: APIVersion, OSType: "linux"}, nil
+}
+
+// ServerVersion implements the Docker client method.
+func (e *Engine) ServerVersion(ctx context.Context) (types.Version, error) {
+	return e.Version, nil
+}
+
+// ContainerList implements the Docker client method. It honors All and the
+// id, name, label, status, volume and network filters.
+func (e *Engine) ContainerList(ctx context.Context, opts container.ListOptions) ([]container.Summary, error) {
+	if err := validate(opts.Filters, "id", "name", "label", "status", "volume", "network"); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := []container.Summary{}
+	// Docker lists the newest containers first
+	for _, c := range slices.Backward(e.containers) {
+		if !opts.All && c.state != container.StateRunning && !opts.Filters.Contains("status") {
+			continue
+		}
+		if !e.matchContainer(c, opts.Filters) {
+			continue
+		}
+		out = append(out, e.summary(c))
+	}
+	return out, nil
+}
+
+func (e *Engine) matchContainer(c *ctr, f filters.Args) bool {
+	if f.Contains("id") && !slices.ContainsFunc(f.Get("id"), func(v string) bool { return strings.HasPrefix(c.id, v) }) {
+		return false
+	}
+	if f.Contains("name") && !f.Match("name", "/"+c.name) {
+		return false
+	}
+	if !f.MatchKVList("label", c.labels) {
+		return false
+	}
+	if f.Contains("status") && !f.ExactMatch("status", string(c.state)) {
+		return false
+	}
+	if f.Contains("volume") && !slices.ContainsFunc(c.mounts, func(m container.MountPoint) bool {
+		return f.ExactMatch("volume", m.Name) || f.ExactMatch("volume", m.Destination)
+	}) {
+		return false
+	}
+	if f.Contains("network") && !slices.ContainsFunc(c.networks, func(name string) bool {
+		n, err := e.findNetwork(name)
+		return f.ExactMatch("network", name) || (err == nil && f.ExactMatch("network", n.ID))
+	}) {
+		return false
+	}
+	return true
+}
+
+func (e *Engine) summary(c *ctr) container.Summary {
+	s := container.Summary{
+		ID:              c.id,
+		Names:           []string{"/" + c.name},
+		Image:           c.image,
+		ImageID:         c.imageID,
+		Command:         "sh",
+		Created:         c.created.Unix(),
+		Ports:           slices.Clone(c.ports),
+		Labels:          maps.Clone(c.labels),
+		State:           c.state,
+		Status:          c.status(),
+		Mounts:          slices.Clone(c.mounts),
+		NetworkSettings: &container.NetworkSettingsSummary{Networks: make(map[string]*network.EndpointSettings)},
+	}
+	s.HostConfig.NetworkMode = string(c.hostConfig.NetworkMode)
+	for _, name := range c.networks {
+		ep := &network.EndpointSettings{}
+		if n, err := e.findNetwork(name); err == nil {
+			ep.NetworkID = n.ID
+		}
+		s.NetworkSettings.Networks[name] = ep
+	}
+	return s
+}
+
+// ContainerInspect implements the Docker client method.
+func (e *Engine) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.InspectResponse{}, err
+	}
+	state := &container.State{
+		Status:   c.state,
+		Running:  c.state == container.StateRunning,
+		Paused:   c.state == container.StatePaused,
+		ExitCode: c.exitCode,
+	}
+	if c.health != "" {
+		state.Health = &container.Health{Status: c.health}
+	}
+	cfg := *c.config
+	cfg.Labels = maps.Clone(c.labels)
+	hc := *c.hostConfig
+	resp := container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:           c.id,
+			Created:      c.created.UTC().Format(time.RFC3339Nano),
+			Name:         "/" + c.name,
+			Image:        c.imageID,
+			State:        state,
+			RestartCount: c.restarts,
+			HostConfig:   &hc,
+		},
+		Mounts:          slices.Clone(c.mounts),
+		Config:          &cfg,
+		NetworkSettings: &container.NetworkSettings{Networks: make(map[string]*network.EndpointSettings)},
+	}
+	for _, name := range c.networks {
+		ep := &network.EndpointSettings{}
+		if n, err := e.findNetwork(name); err == nil {
+			ep.NetworkID = n.ID
+		}
+		resp.NetworkSettings.Networks[name] = ep
+	}
+	return resp, nil
+}
+
+// ContainerCreate implements the Docker client method. Binds and
+// HostConfig.Mounts of type volume or bind become mounts, and the networks of
+// networkingConfig, or else NetworkMode, become attachments.
+func (e *Engine) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if config == nil {
+		config = &container.Config{}
+	}
+	if hostConfig == nil {
+		hostConfig = &container.HostConfig{}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := newID()
+	name := strings.TrimPrefix(containerName, "/")
+	if name == "" {
+		name = "fake_" + id[:12]
+	}
+	if e.hasName(name) {
+		return container.CreateResponse{}, conflictError{fmt.Sprintf("Conflict. The container name \"/%s\" is already in use", name)}
+	}
+
+	cfg := *config
+	hc := *hostConfig
+	cfg.Image = cmp.Or(cfg.Image, "busybox:latest")
+	c := &ctr{
+		id:         id,
+		name:       name,
+		image:      cfg.Image,
+		imageID:    imageIDOf(cfg.Image),
+		labels:     maps.Clone(cfg.Labels),
+		state:      container.StateCreated,
+		created:    e.now(),
+		config:     &cfg,
+		hostConfig: &hc,
+	}
+	for _, b := range hc.Binds {
+		m, err := e.parseBind(b)
+		if err != nil {
+			return container.CreateResponse{}, err
+		}
+		c.mounts = append(c.mounts, m)
+	}
+	for _, m := range hc.Mounts {
+		spec := m.Source + ":" + m.Target
+		if m.ReadOnly {
+			spec += ":ro"
+		}
+		mp, err := e.parseBind(spec)
+		if err != nil {
+			return container.CreateResponse{}, err
+		}
+		c.mounts = append(c.mounts, mp)
+	}
+
+	if networkingConfig != nil && len(networkingConfig.EndpointsConfig) > 0 {
+		c.networks = sortedKeys(networkingConfig.EndpointsConfig)
+	} else if mode := string(hc.NetworkMode); mode != "" && mode != "default" {
+		c.networks = []string{mode}
+	} else {
+		c.networks = []string{"bridge"}
+	}
+	for _, n := range c.networks {
+		if _, err := e.findNetwork(n); err != nil {
+			return container.CreateResponse{}, err
+		}
+	}
+
+	e.containers = append(e.containers, c)
+	e.emit(events.ContainerEventType, events.ActionCreate, c.id, c.attributes())
+	return container.CreateResponse{ID: c.id}, nil
+}
+
+func (e *Engine) hasName(name string) bool {
+	return slices.ContainsFunc(e.containers, func(c *ctr) bool { return c.name == name })
+}
+
+// ContainerStart implements the Docker client method.
+func (e *Engine) ContainerStart(ctx context.Context, containerID string, opts container.StartOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if c.state == container.StateRunning {
+		return nil
+	}
+	if c.state == container.StateExited && c.exitCode != 0 {
+		c.restarts++
+	}
+	c.state, c.exitCode = container.StateRunning, 0
+	if c.health != "" {
+		c.health = container.Starting
+	}
+	for _, n := range c.networks {
+		if nw, err := e.findNetwork(n); err == nil {
+			e.emit(events.NetworkEventType, events.ActionConnect, nw.ID, map[string]string{"name": nw.Name, "container": c.id})
+		}
+	}
+	e.emit(events.ContainerEventType, events.ActionStart, c.id, c.attributes())
+	return nil
+}
+
+// ContainerStop implements the Docker client method.
+func (e *Engine) ContainerStop(ctx context.Context, containerID string, opts container.StopOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	e.stop(c)
+	return nil
+}
+
+// stop moves a running container to exited with the kill, die and stop events of Docker.
+func (e *Engine) stop(c *ctr) {
+	if c.state != container.StateRunning && c.state != container.StatePaused {
+		return
+	}
+	c.state, c.exitCode = container.StateExited, 0
+	e.emit(events.ContainerEventType, events.ActionKill, c.id, c.attributes())
+	attrs := c.attributes()
+	attrs["exitCode"] = "0"
+	e.emit(events.ContainerEventType, events.ActionDie, c.id, attrs)
+	e.emit(events.ContainerEventType, events.ActionStop, c.id, c.attributes())
+}
+
+// ContainerRestart implements the Docker client method. It counts as a restart.
+func (e *Engine) ContainerRestart(ctx context.Context, containerID string, opts container.StopOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	e.stop(c)
+	c.state = container.StateRunning
+	c.restarts++
+	if c.health != "" {
+		c.health = container.Starting
+	}
+	e.emit(events.ContainerEventType, events.ActionStart, c.id, c.attributes())
+	e.emit(events.ContainerEventType, events.ActionRestart, c.id, c.attributes())
+	return nil
+}
+
+// ContainerRemove implements the Docker client method. Running containers
+// need Force; RemoveVolumes is ignored.
+func (e *Engine) ContainerRemove(ctx context.Context, containerID string, opts container.RemoveOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if c.state == container.StateRunning && !opts.Force {
+		return conflictError{fmt.Sprintf("cannot remove container %s: container is running: stop the container before removing or force remove", c.name)}
+	}
+	e.stop(c)
+	e.containers = slices.DeleteFunc(e.containers, func(o *ctr) bool { return o == c })
+	e.emit(events.ContainerEventType, events.ActionDestroy, c.id, c.attributes())
+	return nil
+}
+
+// ContainerRename implements the Docker client method.
+func (e *Engine) ContainerRename(ctx context.Context, containerID, newName string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	newName = strings.TrimPrefix(newName, "/")
+	if e.hasName(newName) {
+		return conflictError{fmt.Sprintf("Conflict. The container name \"/%s\" is already in use", newName)}
+	}
+	old := c.name
+	c.name = newName
+	attrs := c.attributes()
+	attrs["oldName"] = "/" + old
+	e.emit(events.ContainerEventType, events.ActionRename, c.id, attrs)
+	return nil
+}
+
+// VolumeList implements the Docker client method. It honors the name, label,
+// driver and dangling filters.
+func (e *Engine) VolumeList(ctx context.Context, opts volume.ListOptions) (volume.ListResponse, error) {
+	if err := validate(opts.Filters, "name", "label", "driver", "dangling"); err != nil {
+		return volume.ListResponse{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := volume.ListResponse{Volumes: []*volume.Volume{}, Warnings: []string{}}
+	for _, name := range sortedKeys(e.volumes) {
+		v := e.volumes[name]
+		f := opts.Filters
+		if f.Contains("name") && !f.Match("name", v.Name) {
+			continue
+		}
+		if !f.MatchKVList("label", v.Labels) {
+			continue
+		}
+		if f.Contains("driver") && !f.ExactMatch("driver", v.Driver) {
+			continue
+		}
+		if f.Contains("dangling") {
+			dangling, err := f.GetBoolOrDefault("dangling", false)
+			if err != nil {
+				return out, err
+			}
+			if dangling == e.volumeInUse(v.Name) {
+				continue
+			}
+		}
+		cp := *v
+		cp.Labels = maps.Clone(v.Labels)
+		out.Volumes = append(out.Volumes, &cp)
+	}
+	return out, nil
+}
+
+func (e *Engine) volumeInUse(name string) bool {
+	return slices.ContainsFunc(e.containers, func(c *ctr) bool {
+		return slices.ContainsFunc(c.mounts, func(m container.MountPoint) bool { return m.Name == name })
+	})
+}
+
+// VolumeInspect implements the Docker client method.
+func (e *Engine) VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v, ok := e.volumes[volumeID]
+	if !ok {
+		return volume.Volume{}, notFound("get %s: no such volume", volumeID)
+	}
+	cp := *v
+	cp.Labels = maps.Clone(v.Labels)
+	return cp, nil
+}
+
+// VolumeCreate implements the Docker client method. Creating an existing
+// volume returns it unchanged, like Docker.
+func (e *Engine) VolumeCreate(ctx context.Context, opts volume.CreateOptions) (volume.Volume, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if v, ok := e.volumes[opts.Name]; ok {
+		return *v, nil
+	}
+	return *e.createVolume(opts), nil
+}
+
+// VolumeRemove implements the Docker client method. Volumes referenced by a
+// container cannot be removed, even with force.
+func (e *Engine) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.volumes[volumeID]; !ok {
+		if force {
+			return nil
+		}
+		return notFound("get %s: no such volume", volumeID)
+	}
+	if e.volumeInUse(volumeID) {
+		return conflictError{fmt.Sprintf("remove %s: volume is in use", volumeID)}
+	}
+	delete(e.volumes, volumeID)
+	e.emit(events.VolumeEventType, events.ActionDestroy, volumeID, nil)
+	return nil
+}
+
+// NetworkList implements the Docker client method. It honors the id, name,
+// label, driver and scope filters.
+func (e *Engine) NetworkList(ctx context.Context, opts network.ListOptions) ([]network.Summary, error) {
+	if err := validate(opts.Filters, "id", "name", "label", "driver", "scope"); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := []network.Summary{}
+	for _, n := range e.sortedNetworks() {
+		f := opts.Filters
+		if f.Contains("id") && !slices.ContainsFunc(f.Get("id"), func(v string) bool { return strings.HasPrefix(n.ID, v) }) {
+			continue
+		}
+		if f.Contains("name") && !f.Match("name", n.Name) {
+			continue
+		}
+		if !f.MatchKVList("label", n.Labels) {
+			continue
+		}
+		if f.Contains("driver") && !f.ExactMatch("driver", n.Driver) {
+			continue
+		}
+		if f.Contains("scope") && !f.ExactMatch("scope", n.Scope) {
+			continue
+		}
+		out = append(out, e.networkInspect(n))
+	}
+	return out, nil
+}
+
+func (e *Engine) sortedNetworks() []*network.Inspect {
+	nets := slices.Collect(maps.Values(e.networks))
+	slices.SortFunc(nets, func(a, b *network.Inspect) int { return strings.Compare(a.Name, b.Name) })
+	return nets
+}
+
+// networkInspect returns a copy of n with the containers attached to it.
+func (e *Engine) networkInspect(n *network.Inspect) network.Inspect {
+	cp := *n
+	cp.Labels = maps.Clone(n.Labels)
+	cp.Containers = make(map[string]network.EndpointResource)
+	for _, c := range e.containers {
+		if slices.Contains(c.networks, n.Name) && c.state == container.StateRunning {
+			cp.Containers[c.id] = network.EndpointResource{Name: c.name}
+		}
+	}
+	return cp
+}
+
+// NetworkInspect implements the Docker client method.
+func (e *Engine) NetworkInspect(ctx context.Context, networkID string, opts network.InspectOptions) (network.Inspect, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return network.Inspect{}, err
+	}
+	return e.networkInspect(n), nil
+}
+
+// NetworkCreate implements the Docker client method.
+func (e *Engine) NetworkCreate(ctx context.Context, name string, opts network.CreateOptions) (network.CreateResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.findNetwork(name); err == nil {
+		return network.CreateResponse{}, conflictError{fmt.Sprintf("network with name %s already exists", name)}
+	}
+	return network.CreateResponse{ID: e.createNetwork(name, opts).ID}, nil
+}
+
+// NetworkConnect implements the Docker client method. Endpoint settings are
+// ignored; stopped containers are attached when they start.
+func (e *Engine) NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(c.networks, n.Name) {
+		return conflictError{fmt.Sprintf("endpoint with name %s already exists in network %s", c.name, n.Name)}
+	}
+	c.networks = append(c.networks, n.Name)
+	if c.state == container.StateRunning {
+		e.emit(events.NetworkEventType, events.ActionConnect, n.ID, map[string]string{"name": n.Name, "container": c.id})
+	}
+	return nil
+}
+
+// NetworkRemove implements the Docker client method. Networks with attached
+// containers cannot be removed.
+func (e *Engine) NetworkRemove(ctx context.Context, networkID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(e.containers, func(c *ctr) bool { return slices.Contains(c.networks, n.Name) }) {
+		return conflictError{fmt.Sprintf("error while removing network: network %s has active endpoints", n.Name)}
+	}
+	delete(e.networks, n.ID)
+	e.emit(events.NetworkEventType, events.ActionDestroy, n.ID, map[string]string{"name": n.Name, "type": n.Driver})
+	return nil
+}
+
+// ImageInspect implements the Docker client method, by image ID or
+// reference. Only images given labels with SetImageLabels exist.
+func (e *Engine) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := imageID
+	labels, ok := e.images[id]
+	if !ok {
+		id = imageIDOf(imageID)
+		if labels, ok = e.images[id]; !ok {
+			return image.InspectResponse{}, notFound("No such image: %s", imageID)
+		}
+	}
+	resp := image.InspectResponse{ID: id}
+	resp.Config = imageConfig(labels)
+	return resp, nil
+}
+
+// Events implements the Docker client method. It honors the type, event,
+// container and label filters. The channels are closed when ctx is done, after
+// sending ctx.Err().
+func (e *Engine) Events(ctx context.Context, opts events.ListOptions) (<-chan events.Message, <-chan error) {
+	msgs := make(chan events.Message)
+	errs := make(chan error, 1)
+	if err := validate(opts.Filters, "type", "event", "container", "label"); err != nil {
+		errs <- err
+		return msgs, errs
+	}
+	sub := e.subscribe(opts.Filters)
+	go sub.run(ctx, msgs, errs, func() { e.unsubscribe(sub) })
+	return msgs, errs
+}
@@ -0,0 +1,374 @@
+// Package fakedocker is an in-memory Docker engine for hermetic tests. An
+// Engine keeps containers, volumes, networks and image labels, honors list
+// options and filters, and emits events. Tests use it directly as a Go Docker
+// client, or serve it on a unix socket speaking a subset of the Docker API so
+// code connecting through client.Client, NewFromEnv or the CLI runs against it.
+package fakedocker
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// APIVersion is the Docker API version the engine reports.
+const APIVersion = "1.51"
+
+// ctr is the state of a fake container.
+type ctr struct {
+	id       string
+	name     string
+	image    string
+	imageID  string
+	labels   map[string]string
+	state    container.ContainerState
+	exitCode int
+	health   container.HealthStatus
+	restarts int
+	created  time.Time
+	mounts   []container.MountPoint
+	networks []string
+	ports    []container.Port
+
+	config     *container.Config
+	hostConfig *container.HostConfig
+}
+
+// Engine is an in-memory Docker engine. It is safe for concurrent use.
+type Engine struct {
+	// Now is the clock used for creation times and events; defaults to time.Now.
+	Now func() time.Time
+	// Version is returned by ServerVersion and /version.
+	Version types.Version
+
+	mu         sync.Mutex
+	containers []*ctr // in creation order
+	volumes    map[string]*volume.Volume
+	networks   map[string]*network.Inspect  // by ID
+	images     map[string]map[string]string // labels by image ID
+	subs       map[*subscriber]struct{}
+}
+
+// New returns an empty engine with Docker's bridge, host and none networks.
+func New() *Engine {
+	e := &Engine{
+		Version: types.Version{
+			Version:    "28.5.0",
+			APIVersion: APIVersion,
+			Os:         "linux",
+			Arch:       "amd64",
+			Components: []types.ComponentVersion{{Name: "Engine", Version: "28.5.0"}},
+		},
+		volumes:  make(map[string]*volume.Volume),
+		networks: make(map[string]*network.Inspect),
+		images:   make(map[string]map[string]string),
+		subs:     make(map[*subscriber]struct{}),
+	}
+	for _, n := range []struct{ name, driver string }{{"bridge", "bridge"}, {"host", "host"}, {"none", "null"}} {
+		id := newID()
+		e.networks[id] = &network.Inspect{ID: id, Name: n.name, Driver: n.driver, Scope: "local", Labels: map[string]string{}}
+	}
+	return e
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+// newID returns a random 64 hex digit ID like Docker's.
+func newID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ContainerSpec describes a container created with AddContainer.
+type ContainerSpec struct {
+	Name    string
+	Image   string // defaults to "busybox:latest"
+	Labels  map[string]string
+	Running bool // start the container after creating it
+	// Mounts in the --volume notation: "VOLUME:DEST", "/HOST:DEST", with an
+	// optional ":ro" suffix. Missing volumes are created.
+	Mounts []string
+	// Networks to attach to; missing networks are created. Defaults to bridge.
+	Networks []string
+	Ports    []container.Port
+}
+
+// AddContainer creates a container from spec, emitting the same events as
+// Docker, and returns its ID.
+func (e *Engine) AddContainer(spec ContainerSpec) (string, error) {
+	cfg := &container.Config{Image: spec.Image, Labels: spec.Labels}
+	hc := &container.HostConfig{Binds: spec.Mounts}
+	var nc *network.NetworkingConfig
+	if len(spec.Networks) > 0 {
+		e.mu.Lock()
+		for _, n := range spec.Networks {
+			if _, err := e.findNetwork(n); err != nil {
+				e.createNetwork(n, network.CreateOptions{})
+			}
+		}
+		e.mu.Unlock()
+		hc.NetworkMode = container.NetworkMode(spec.Networks[0])
+		nc = &network.NetworkingConfig{EndpointsConfig: make(map[string]*network.EndpointSettings)}
+		for _, n := range spec.Networks {
+			nc.EndpointsConfig[n] = &network.EndpointSettings{}
+		}
+	}
+	resp, err := e.ContainerCreate(context.Background(), cfg, hc, nc, nil, spec.Name)
+	if err != nil {
+		return "", err
+	}
+	e.mu.Lock()
+	e.find(resp.ID).ports = spec.Ports
+	e.mu.Unlock()
+	if spec.Running {
+		if err := e.ContainerStart(context.Background(), resp.ID, container.StartOptions{}); err != nil {
+			return "", err
+		}
+	}
+	return resp.ID, nil
+}
+
+// SetLabels replaces the labels of the container, volume or network with the
+// given ID or name and emits an "update" event. Docker cannot relabel, but
+// tests can use it to simulate a recreated entity without a new ID.
+func (e *Engine) SetLabels(idOrName string, labels map[string]string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	labels = maps.Clone(labels)
+	if c, err := e.findContainer(idOrName); err == nil {
+		c.labels = labels
+		c.config.Labels = labels
+		e.emit(events.ContainerEventType, events.ActionUpdate, c.id, c.attributes())
+		return nil
+	}
+	if v, ok := e.volumes[idOrName]; ok {
+		v.Labels = labels
+		e.emit(events.VolumeEventType, events.ActionUpdate, v.Name, nil)
+		return nil
+	}
+	if n, err := e.findNetwork(idOrName); err == nil {
+		n.Labels = labels
+		e.emit(events.NetworkEventType, events.ActionUpdate, n.ID, map[string]string{"name": n.Name})
+		return nil
+	}
+	return notFound("no such object: %s", idOrName)
+}
+
+// SetHealth sets the health status of a container and emits a health_status event.
+func (e *Engine) SetHealth(idOrName string, status container.HealthStatus) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(idOrName)
+	if err != nil {
+		return err
+	}
+	c.health = status
+	e.emit(events.ContainerEventType, events.Action(string(events.ActionHealthStatus)+": "+string(status)), c.id, c.attributes())
+	return nil
+}
+
+// Exit stops a running container as if its process exited with code,
+// emitting a die event. It counts as a restart the next time it is started.
+func (e *Engine) Exit(idOrName string, code int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, err := e.findContainer(idOrName)
+	if err != nil {
+		return err
+	}
+	if c.state != container.StateRunning {
+		return fmt.Errorf("container %s is not running", c.name)
+	}
+	c.state, c.exitCode = container.StateExited, code
+	attrs := c.attributes()
+	attrs["exitCode"] = fmt.Sprint(code)
+	e.emit(events.ContainerEventType, events.ActionDie, c.id, attrs)
+	return nil
+}
+
+// SetImageLabels sets the labels of the image ref, as returned by ImageInspect
+// for containers created from it.
+func (e *Engine) SetImageLabels(ref string, labels map[string]string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.images[imageIDOf(ref)] = maps.Clone(labels)
+}
+
+// find returns the container with exactly the given ID, or nil.
+func (e *Engine) find(id string) *ctr {
+	for _, c := range e.containers {
+		if c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// findContainer resolves a full ID, name or unique ID prefix like Docker.
+func (e *Engine) findContainer(ref string) (*ctr, error) {
+	name := strings.TrimPrefix(ref, "/")
+	var byPrefix []*ctr
+	for _, c := range e.containers {
+		if c.id == ref || c.name == name {
+			return c, nil
+		}
+		if strings.HasPrefix(c.id, ref) {
+			byPrefix = append(byPrefix, c)
+		}
+	}
+	if len(byPrefix) == 1 {
+		return byPrefix[0], nil
+	}
+	return nil, notFound("No such container: %s", ref)
+}
+
+// findNetwork resolves a network ID, name or unique ID prefix.
+func (e *Engine) findNetwork(ref string) (*network.Inspect, error) {
+	var byPrefix []*network.Inspect
+	for _, n := range e.networks {
+		if n.ID == ref || n.Name == ref {
+			return n, nil
+		}
+		if strings.HasPrefix(n.ID, ref) {
+			byPrefix = append(byPrefix, n)
+		}
+	}
+	if len(byPrefix) == 1 {
+		return byPrefix[0], nil
+	}
+	return nil, notFound("network %s not found", ref)
+}
+
+// createNetwork adds a network and emits its create event.
+func (e *Engine) createNetwork(name string, opts network.CreateOptions) *network.Inspect {
+	driver := cmp.Or(opts.Driver, "bridge")
+	n := &network.Inspect{
+		ID:      newID(),
+		Name:    name,
+		Driver:  driver,
+		Scope:   cmp.Or(opts.Scope, "local"),
+		Created: e.now(),
+		Labels:  maps.Clone(opts.Labels),
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	e.networks[n.ID] = n
+	e.emit(events.NetworkEventType, events.ActionCreate, n.ID, map[string]string{"name": name, "type": driver})
+	return n
+}
+
+// createVolume adds a volume and emits its create event.
+func (e *Engine) createVolume(opts volume.CreateOptions) *volume.Volume {
+	name := cmp.Or(opts.Name, newID())
+	driver := cmp.Or(opts.Driver, "local")
+	v := &volume.Volume{
+		Name:       name,
+		Driver:     driver,
+		Mountpoint: "/var/lib/docker/volumes/" + name + "/_data",
+		Scope:      "local",
+		CreatedAt:  e.now().UTC().Format(time.RFC3339),
+		Labels:     maps.Clone(opts.Labels),
+		Options:    maps.Clone(opts.DriverOpts),
+	}
+	if v.Labels == nil {
+		v.Labels = map[string]string{}
+	}
+	e.volumes[name] = v
+	e.emit(events.VolumeEventType, events.ActionCreate, name, map[string]string{"driver": driver})
+	return v
+}
+
+// parseBind turns a --volume value into a mount point, creating a missing named volume.
+func (e *Engine) parseBind(bind string) (container.MountPoint, error) {
+	parts := strings.Split(bind, ":")
+	m := container.MountPoint{RW: true}
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		m.RW = parts[n-1] == "rw"
+		parts = parts[:n-1]
+	}
+	if len(parts) != 2 || parts[1] == "" {
+		return m, fmt.Errorf("invalid volume specification: %q", bind)
+	}
+	m.Destination = parts[1]
+	if strings.HasPrefix(parts[0], "/") {
+		m.Type, m.Source = mount.TypeBind, parts[0]
+		return m, nil
+	}
+	v, ok := e.volumes[parts[0]]
+	if !ok {
+		v = e.createVolume(volume.CreateOptions{Name: parts[0]})
+	}
+	m.Type, m.Name, m.Source, m.Driver = mount.TypeVolume, v.Name, v.Mountpoint, v.Driver
+	return m, nil
+}
+
+// attributes returns the event attributes of c: its labels, name and image.
+func (c *ctr) attributes() map[string]string {
+	attrs := maps.Clone(c.labels)
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	attrs["name"] = c.name
+	attrs["image"] = c.image
+	return attrs
+}
+
+// status renders the human-readable status of docker ps.
+func (c *ctr) status() string {
+	switch c.state {
+	case container.StateRunning:
+		if c.health != "" && c.health != container.NoHealthcheck {
+			return "Up (" + string(c.health) + ")"
+		}
+		return "Up"
+	case container.StatePaused:
+		return "Up (Paused)"
+	case container.StateExited:
+		return fmt.Sprintf("Exited (%d)", c.exitCode)
+	}
+	return capitalize(string(c.state))
+}
+
+// capitalize capitalizes the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// validate rejects filter keys outside keys, like Docker.
+func validate(args filters.Args, keys ...string) error {
+	accepted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		accepted[k] = true
+	}
+	return args.Validate(accepted)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,189 @@
+package fakedocker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+func mustAdd(t *testing.T, e *fakedocker.Engine, spec fakedocker.ContainerSpec) string {
+	t.Helper()
+	id, err := e.AddContainer(spec)
+	if err != nil {
+		t.Fatalf("AddContainer(%s): %v", spec.Name, err)
+	}
+	return id
+}
+
+func names(cs []container.Summary) []string {
+	var out []string
+	for _, c := range cs {
+		out = append(out, c.Names[0])
+	}
+	return out
+}
+
+func TestContainerList_AllAndFilters(t *testing.T) {
+	ctx := context.Background()
+	e := fakedocker.New()
+	mustAdd(t, e, fakedocker.ContainerSpec{Name: "web", Running: true, Labels: map[string]string{"bosun.role": "web"}, Mounts: []string{"data:/data"}})
+	mustAdd(t, e, fakedocker.ContainerSpec{Name: "db", Running: true, Labels: map[string]string{"bosun.role": "db"}, Networks: []string{"backend"}})
+	mustAdd(t, e, fakedocker.ContainerSpec{Name: "job", Labels: map[string]string{"bosun.role": "job"}})
+
+	tests := []struct {
+		name string
+		opts container.ListOptions
+		want []string
+	}{
+		{"running only", container.ListOptions{}, []string{"/db", "/web"}},
+		{"all newest first", container.ListOptions{All: true}, []string{"/job", "/db", "/web"}},
+		{"label", container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("label", "bosun.role=job"))}, []string{"/job"}},
+		{"label key", container.ListOptions{Filters: filters.NewArgs(filters.Arg("label", "bosun.role"))}, []string{"/db", "/web"}},
+		{"status overrides all", container.ListOptions{Filters: filters.NewArgs(filters.Arg("status", "created"))}, []string{"/job"}},
+		{"name", container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("name", "web"))}, []string{"/web"}},
+		{"volume", container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("volume", "data"))}, []string{"/web"}},
+		{"network", container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("network", "backend"))}, []string{"/db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.ContainerList(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g := names(got); !equal(g, tt.want) {
+				t.Errorf("got %v, want %v", g, tt.want)
+			}
+		})
+	}
+
+	if _, err := e.ContainerList(ctx, container.ListOptions{Filters: filters.NewArgs(filters.Arg("bogus", "x"))}); err == nil {
+		t.Error("expected an error for an unknown filter")
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	e := fakedocker.New()
+	id := mustAdd(t, e, fakedocker.ContainerSpec{Name: "web", Running: true, Mounts: []string{"data:/data"}})
+
+	if err := e.ContainerRemove(ctx, id, container.RemoveOptions{}); !cerrdefs.IsConflict(err) {
+		t.Errorf("removing a running container: got %v, want a conflict", err)
+	}
+	if err := e.VolumeRemove(ctx, "data", false); !cerrdefs.IsConflict(err) {
+		t.Errorf("removing a volume in use: got %v, want a conflict", err)
+	}
+
+	if err := e.Exit(id, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ContainerStart(ctx, "web", container.StartOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	inspect, err := e.ContainerInspect(ctx, id[:12])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspect.RestartCount != 1 || !inspect.State.Running {
+		t.Errorf("after exit and start: restarts %d, running %v", inspect.RestartCount, inspect.State.Running)
+	}
+
+	if err := e.ContainerRemove(ctx, id, container.RemoveOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ContainerInspect(ctx, id); !client.IsErrNotFound(err) {
+		t.Errorf("inspect after remove: got %v, want not found", err)
+	}
+	if err := e.VolumeRemove(ctx, "data", false); err != nil {
+		t.Errorf("removing an unused volume: %v", err)
+	}
+}
+
+func TestSetLabels(t *testing.T) {
+	ctx := context.Background()
+	e := fakedocker.New()
+	mustAdd(t, e, fakedocker.ContainerSpec{Name: "web"})
+	if _, err := e.VolumeCreate(ctx, volume.CreateOptions{Name: "data"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.NetworkCreate(ctx, "backend", network.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"web", "data", "backend"} {
+		if err := e.SetLabels(ref, map[string]string{"bosun.x": ref}); err != nil {
+			t.Fatalf("SetLabels(%s): %v", ref, err)
+		}
+	}
+
+	f := filters.NewArgs(filters.Arg("label", "bosun.x"))
+	cs, _ := e.ContainerList(ctx, container.ListOptions{All: true, Filters: f})
+	vs, _ := e.VolumeList(ctx, volume.ListOptions{Filters: f})
+	ns, _ := e.NetworkList(ctx, network.ListOptions{Filters: f})
+	if len(cs) != 1 || len(vs.Volumes) != 1 || len(ns) != 1 {
+		t.Fatalf("relabeled entities: %d containers, %d volumes, %d networks", len(cs), len(vs.Volumes), len(ns))
+	}
+	if cs[0].Labels["bosun.x"] != "web" || vs.Volumes[0].Labels["bosun.x"] != "data" || ns[0].Labels["bosun.x"] != "backend" {
+		t.Errorf("unexpected labels: %v %v %v", cs[0].Labels, vs.Volumes[0].Labels, ns[0].Labels)
+	}
+	if err := e.SetLabels("missing", nil); !client.IsErrNotFound(err) {
+		t.Errorf("SetLabels(missing): got %v, want not found", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := fakedocker.New()
+	msgs, errs := e.Events(ctx, events.ListOptions{Filters: filters.NewArgs(
+		filters.Arg("type", "container"),
+		filters.Arg("label", "bosun.watch=1"),
+	)})
+
+	mustAdd(t, e, fakedocker.ContainerSpec{Name: "ignored", Running: true})
+	id := mustAdd(t, e, fakedocker.ContainerSpec{Name: "web", Running: true, Labels: map[string]string{"bosun.watch": "1"}})
+	if err := e.SetHealth(id, container.Healthy); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ContainerStop(ctx, id, container.StopOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []events.Action{"create", "start", "health_status: healthy", "kill", "die", "stop"}
+	for _, action := range want {
+		select {
+		case msg := <-msgs:
+			if msg.Action != action || msg.Actor.ID != id || msg.Actor.Attributes["name"] != "web" {
+				t.Fatalf("got %s %s (%s), want %s for web", msg.Action, msg.Actor.ID, msg.Actor.Attributes["name"], action)
+			}
+		case err := <-errs:
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", action)
+		}
+	}
+
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("after cancel: got %v, want context.Canceled", err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package fakedocker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// subscriber queues the events matching its filters, so emitting never
+// blocks the engine on a slow reader.
+type subscriber struct {
+	filters filters.Args
+
+	mu     sync.Mutex
+	queue  []events.Message
+	notify chan struct{}
+}
+
+func (e *Engine) subscribe(f filters.Args) *subscriber {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	sub := &subscriber{filters: f, notify: make(chan struct{}, 1)}
+	e.subs[sub] = struct{}{}
+	return sub
+}
+
+func (e *Engine) unsubscribe(sub *subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.subs, sub)
+}
+
+// emit queues an event for every matching subscriber. Callers hold e.mu.
+func (e *Engine) emit(typ events.Type, action events.Action, id string, attrs map[string]string) {
+	now := e.now()
+	msg := events.Message{
+		Type:     typ,
+		Action:   action,
+		Actor:    events.Actor{ID: id, Attributes: attrs},
+		Scope:    "local",
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+	for sub := range e.subs {
+		if !sub.matches(msg) {
+			continue
+		}
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, msg)
+		sub.mu.Unlock()
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// matches applies the event filters like Docker: each given filter must match.
+func (s *subscriber) matches(msg events.Message) bool {
+	f := s.filters
+	if f.Contains("type") && !f.ExactMatch("type", string(msg.Type)) {
+		return false
+	}
+	if f.Contains("event") {
+		action, _, _ := strings.Cut(string(msg.Action), ":")
+		if !f.ExactMatch("event", action) {
+			return false
+		}
+	}
+	if f.Contains("container") {
+		if msg.Type != events.ContainerEventType || !(f.ExactMatch("container", msg.Actor.ID) || f.ExactMatch("container", msg.Actor.Attributes["name"])) {
+			return false
+		}
+	}
+	return f.MatchKVList("label", msg.Actor.Attributes)
+}
+
+// run delivers queued events until ctx is done, then reports ctx.Err() and unsubscribes.
+func (s *subscriber) run(ctx context.Context, msgs chan<- events.Message, errs chan<- error, done func()) {
+	defer done()
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, msg := range queue {
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			errs <- ctx.Err()
+			return
+		}
+	}
+}
+
+// imageIDOf derives a stable image ID from an image reference. Image IDs
+// are returned as is.
+func imageIDOf(ref string) string {
+	if strings.HasPrefix(ref, "sha256:") && len(ref) == len("sha256:")+64 {
+		return ref
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// imageConfig returns an image config carrying labels.
+func imageConfig(labels map[string]string) *dockerspec.DockerOCIImageConfig {
+	return &dockerspec.DockerOCIImageConfig{ImageConfig: ocispec.ImageConfig{Labels: labels}}
+}
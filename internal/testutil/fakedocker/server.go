@@ -0,0 +1,279 @@
+package fakedocker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// Handler serves the Docker API subset backed by the engine's client methods,
+// with or without a /vX.Y version prefix: ping and version; container list,
+// inspect, create, start, stop, restart, rename and remove; volume and network
+// list, inspect, create and remove; network connect; image inspect; and the
+// event stream.
+func (e *Engine) Handler() http.Handler {
+	mux := http.NewServeMux()
+	ping := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", APIVersion)
+		w.Header().Set("Ostype", "linux")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("OK"))
+		}
+	}
+	mux.HandleFunc("GET /_ping", ping)
+	mux.HandleFunc("HEAD /_ping", ping)
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, e.Version)
+	})
+
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		args, ok := queryFilters(w, r)
+		if !ok {
+			return
+		}
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+		reply(w, http.StatusOK)(e.ContainerList(r.Context(), container.ListOptions{All: all, Filters: args}))
+	})
+	mux.HandleFunc("GET /containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK)(e.ContainerInspect(r.Context(), r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var req container.CreateRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		reply(w, http.StatusCreated)(e.ContainerCreate(r.Context(), req.Config, req.HostConfig, req.NetworkingConfig, nil, r.URL.Query().Get("name")))
+	})
+	mux.HandleFunc("POST /containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		noContent(w, e.ContainerStart(r.Context(), r.PathValue("id"), container.StartOptions{}))
+	})
+	mux.HandleFunc("POST /containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		noContent(w, e.ContainerStop(r.Context(), r.PathValue("id"), stopOptions(r)))
+	})
+	mux.HandleFunc("POST /containers/{id}/restart", func(w http.ResponseWriter, r *http.Request) {
+		noContent(w, e.ContainerRestart(r.Context(), r.PathValue("id"), stopOptions(r)))
+	})
+	mux.HandleFunc("POST /containers/{id}/rename", func(w http.ResponseWriter, r *http.Request) {
+		noContent(w, e.ContainerRename(r.Context(), r.PathValue("id"), r.URL.Query().Get("name")))
+	})
+	mux.HandleFunc("DELETE /containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+		noContent(w, e.ContainerRemove(r.Context(), r.PathValue("id"), container.RemoveOptions{Force: force}))
+	})
+
+	mux.HandleFunc("GET /volumes", func(w http.ResponseWriter, r *http.Request) {
+		args, ok := queryFilters(w, r)
+		if !ok {
+			return
+		}
+		reply(w, http.StatusOK)(e.VolumeList(r.Context(), volume.ListOptions{Filters: args}))
+	})
+	mux.HandleFunc("GET /volumes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK)(e.VolumeInspect(r.Context(), r.PathValue("name")))
+	})
+	mux.HandleFunc("POST /volumes/create", func(w http.ResponseWriter, r *http.Request) {
+		var opts volume.CreateOptions
+		if !decode(w, r, &opts) {
+			return
+		}
+		reply(w, http.StatusCreated)(e.VolumeCreate(r.Context(), opts))
+	})
+	mux.HandleFunc("DELETE /volumes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+		noContent(w, e.VolumeRemove(r.Context(), r.PathValue("name"), force))
+	})
+
+	mux.HandleFunc("GET /networks", func(w http.ResponseWriter, r *http.Request) {
+		args, ok := queryFilters(w, r)
+		if !ok {
+			return
+		}
+		reply(w, http.StatusOK)(e.NetworkList(r.Context(), network.ListOptions{Filters: args}))
+	})
+	mux.HandleFunc("GET /networks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK)(e.NetworkInspect(r.Context(), r.PathValue("id"), network.InspectOptions{}))
+	})
+	mux.HandleFunc("POST /networks/create", func(w http.ResponseWriter, r *http.Request) {
+		var req network.CreateRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		reply(w, http.StatusCreated)(e.NetworkCreate(r.Context(), req.Name, req.CreateOptions))
+	})
+	mux.HandleFunc("POST /networks/{id}/connect", func(w http.ResponseWriter, r *http.Request) {
+		var req network.ConnectOptions
+		if !decode(w, r, &req) {
+			return
+		}
+		noContent(w, e.NetworkConnect(r.Context(), r.PathValue("id"), req.Container, req.EndpointConfig))
+	})
+	mux.HandleFunc("DELETE /networks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		noContent(w, e.NetworkRemove(r.Context(), r.PathValue("id")))
+	})
+
+	// Image references may contain slashes
+	mux.HandleFunc("GET /images/{ref...}", func(w http.ResponseWriter, r *http.Request) {
+		ref, ok := strings.CutSuffix(r.PathValue("ref"), "/json")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		reply(w, http.StatusOK)(e.ImageInspect(r.Context(), ref))
+	})
+
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		args, ok := queryFilters(w, r)
+		if !ok {
+			return
+		}
+		e.serveEvents(w, r, args)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = versionPrefix.ReplaceAllString(r.URL.Path, "")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveEvents streams events as concatenated JSON objects until the client goes away.
+func (e *Engine) serveEvents(w http.ResponseWriter, r *http.Request, args filters.Args) {
+	msgs, errs := e.Events(r.Context(), events.ListOptions{Filters: args})
+	select {
+	case err := <-errs:
+		writeError(w, err)
+		return
+	default:
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case msg := <-msgs:
+			if enc.Encode(msg) != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-errs:
+			return
+		}
+	}
+}
+
+// ServeUnix serves the engine on a unix socket until the test ends and
+// returns its DOCKER_HOST value, e.g. "unix:///tmp/fakedocker123/docker.sock".
+func (e *Engine) ServeUnix(t testing.TB) string {
+	t.Helper()
+	// t.TempDir paths can exceed the unix socket path limit
+	dir, err := os.MkdirTemp("", "fakedocker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: e.Handler()}
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() {
+		// Event streams only end when their clients go away
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = srv.Shutdown(ctx)
+		_ = srv.Close()
+	})
+	return "unix://" + sock
+}
+
+// queryFilters decodes the filters query parameter, replying 400 when invalid.
+func queryFilters(w http.ResponseWriter, r *http.Request) (filters.Args, bool) {
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	if err != nil {
+		writeError(w, invalidError{err.Error()})
+		return args, false
+	}
+	return args, true
+}
+
+func stopOptions(r *http.Request) container.StopOptions {
+	var opts container.StopOptions
+	if t, err := strconv.Atoi(r.URL.Query().Get("t")); err == nil {
+		opts.Timeout = &t
+	}
+	return opts
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, invalidError{err.Error()})
+		return false
+	}
+	return true
+}
+
+// reply returns a function writing a client method's result with status, or its error.
+func reply(w http.ResponseWriter, status int) func(any, error) {
+	return func(v any, err error) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, status, v)
+	}
+}
+
+func noContent(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError replies with the status Docker uses for err and its message.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var nf interface{ NotFound() }
+	var cf interface{ Conflict() }
+	var ip interface{ InvalidParameter() }
+	switch {
+	case errors.As(err, &nf):
+		status = http.StatusNotFound
+	case errors.As(err, &cf):
+		status = http.StatusConflict
+	case errors.As(err, &ip):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, map[string]string{"message": err.Error()})
+}
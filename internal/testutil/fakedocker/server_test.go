@@ -0,0 +1,102 @@
+package fakedocker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/simone-viozzi/bosun/internal/testutil/fakedocker"
+)
+
+func TestServeUnix_ClientRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	e := fakedocker.New()
+	e.SetImageLabels("nginx:1", map[string]string{"bosun.image": "yes"})
+	cli, err := client.NewClientWithOpts(client.WithHost(e.ServeUnix(t)), client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v := cli.ClientVersion(); v != fakedocker.APIVersion {
+		t.Errorf("negotiated API version %s, want %s", v, fakedocker.APIVersion)
+	}
+
+	msgs, errs := cli.Events(ctx, events.ListOptions{Filters: filters.NewArgs(filters.Arg("type", "container"))})
+
+	if _, err := cli.VolumeCreate(ctx, volume.CreateOptions{Name: "data", Labels: map[string]string{"bosun.v": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.NetworkCreate(ctx, "backend", network.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := cli.ContainerCreate(ctx,
+		&container.Config{Image: "nginx:1", Labels: map[string]string{"bosun.role": "web"}},
+		&container.HostConfig{Binds: []string{"data:/data:ro"}, NetworkMode: "backend"},
+		nil, nil, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.ContainerCreate(ctx, &container.Config{Image: "nginx:1"}, nil, nil, nil, "web"); !cerrdefs.IsConflict(err) {
+		t.Errorf("duplicate name: got %v, want a conflict", err)
+	}
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := cli.ContainerList(ctx, container.ListOptions{Filters: filters.NewArgs(filters.Arg("label", "bosun.role=web"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != resp.ID || len(list[0].Mounts) != 1 || list[0].Mounts[0].Name != "data" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	if _, ok := list[0].NetworkSettings.Networks["backend"]; !ok {
+		t.Errorf("container not attached to backend: %v", list[0].NetworkSettings.Networks)
+	}
+
+	img, err := cli.ImageInspect(ctx, list[0].ImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Config == nil || img.Config.Labels["bosun.image"] != "yes" {
+		t.Errorf("image labels: %+v", img.Config)
+	}
+
+	if err := cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.ContainerInspect(ctx, resp.ID); !client.IsErrNotFound(err) {
+		t.Errorf("inspect after remove: got %v, want not found", err)
+	}
+	if _, err := cli.ContainerList(ctx, container.ListOptions{Filters: filters.NewArgs(filters.Arg("bogus", "x"))}); err == nil {
+		t.Error("expected an error for an unknown filter")
+	}
+
+	var got []events.Action
+	for len(got) < 5 {
+		select {
+		case msg := <-msgs:
+			got = append(got, msg.Action)
+		case err := <-errs:
+			t.Fatalf("events: %v (got %v)", err, got)
+		}
+	}
+	want := []events.Action{"create", "start", "kill", "die", "stop"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events %v, want prefix %v", got, want)
+		}
+	}
+}